@@ -9,26 +9,38 @@ package cmd
 
 import (
 	"fmt"
-
 	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
 )
 
 type DbBackupCmd struct {
-	cmd *cobra.Command
+	cmd    *cobra.Command
+	format string
 }
 
 func NewDbBackupCmd() *DbBackupCmd {
 	c := &DbBackupCmd{
-		&cobra.Command{
+		cmd: &cobra.Command{
 			Use:   "backup",
 			Short: "backups the database",
-			Long:  ``,
+			Long: `takes a logical backup of the database and writes it to the backup directory (i.e. Backup.Path)
+together with a metadata file recording the application and database versions at the time of the backup`,
+			Example: "dbman db backup --format directory",
 		},
 	}
 	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVar(&c.format, "format", "file", "the format of the backup - file or directory")
 	return c
 }
 
 func (c *DbBackupCmd) Run(cmd *cobra.Command, args []string) {
-	fmt.Println("backup called")
+	_, _, err, elapsed := DM.WithOutput(os.Stdout).Backup(c.format)
+	if err != nil {
+		fmt.Printf("!!! I cannot backup the database\n")
+		fmt.Printf("%v\n", err)
+		fmt.Printf("? the execution time was %v\n", elapsed)
+		os.Exit(1)
+	}
+	fmt.Printf("? I have backed up the database in %v\n", elapsed)
 }
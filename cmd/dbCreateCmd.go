@@ -15,7 +15,8 @@ import (
 )
 
 type DbCreateCmd struct {
-	cmd *cobra.Command
+	cmd    *cobra.Command
+	dryRun bool
 }
 
 func NewDbCreateCmd() *DbCreateCmd {
@@ -27,17 +28,21 @@ func NewDbCreateCmd() *DbCreateCmd {
 		},
 	}
 	c.cmd.Run = c.Run
+	c.cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "prints the merged scripts that would be executed, without running them")
 	return c
 }
 
 func (c *DbCreateCmd) Run(cmd *cobra.Command, args []string) {
-	output, err, elapsed := DM.Create()
-	fmt.Print(output.String())
+	_, err, elapsed := DM.WithOutput(os.Stdout).Create(c.dryRun)
 	if err != nil {
 		fmt.Printf("!!! I cannot create the database\n")
 		fmt.Printf("%v\n", err)
 		fmt.Printf("? the execution time was %v\n", elapsed)
 		os.Exit(1)
 	}
+	if c.dryRun {
+		fmt.Printf("? I have completed the dry run in %v\n", elapsed)
+		return
+	}
 	fmt.Printf("? I have created the database in %v\n", elapsed)
 }
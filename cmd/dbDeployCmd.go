@@ -15,7 +15,8 @@ import (
 )
 
 type DbDeployCmd struct {
-	cmd *cobra.Command
+	cmd    *cobra.Command
+	dryRun bool
 }
 
 func NewDbDeployCmd() *DbDeployCmd {
@@ -27,17 +28,21 @@ func NewDbDeployCmd() *DbDeployCmd {
 		},
 	}
 	c.cmd.Run = c.Run
+	c.cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "prints the merged scripts that would be executed, without running them")
 	return c
 }
 
 func (c *DbDeployCmd) Run(cmd *cobra.Command, args []string) {
-	output, err, elapsed := DM.Deploy()
-	fmt.Print(output.String())
+	_, err, elapsed := DM.WithOutput(os.Stdout).Deploy(c.dryRun)
 	if err != nil {
 		fmt.Printf("!!! I cannot deploy the database\n")
 		fmt.Printf("%v\n", err)
 		fmt.Printf("? the execution time was %v\n", elapsed)
 		os.Exit(1)
 	}
+	if c.dryRun {
+		fmt.Printf("? I have completed the dry run in %v\n", elapsed)
+		return
+	}
 	fmt.Printf("? I have deployed the database in %v\n", elapsed)
 }
@@ -0,0 +1,42 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+type DbDowngradeCmd struct {
+	cmd *cobra.Command
+}
+
+func NewDbDowngradeCmd() *DbDowngradeCmd {
+	c := &DbDowngradeCmd{
+		cmd: &cobra.Command{
+			Use:   "downgrade",
+			Short: "roll back an existing database to the current Application Version",
+			Long:  `runs the downgrade commands of each release in the release plan, from the current database version back to the current Application Version`,
+		},
+	}
+	c.cmd.Run = c.Run
+	return c
+}
+
+func (c *DbDowngradeCmd) Run(cmd *cobra.Command, args []string) {
+	_, err, elapsed := DM.WithOutput(os.Stdout).Downgrade()
+	if err != nil {
+		fmt.Printf("!!! I cannot downgrade the database\n")
+		fmt.Printf("%v\n", err)
+		fmt.Printf("? the execution time was %v\n", elapsed)
+		os.Exit(1)
+	}
+	fmt.Printf("? I have downgraded the database in %v\n", elapsed)
+}
@@ -0,0 +1,58 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+type DbHistoryCmd struct {
+	cmd        *cobra.Command
+	appVersion string
+	command    string
+	status     string
+	since      string
+	limit      int
+	format     string
+}
+
+func NewDbHistoryCmd() *DbHistoryCmd {
+	c := &DbHistoryCmd{
+		cmd: &cobra.Command{
+			Use:     "history",
+			Short:   "shows the execution history of the release commands run on the database",
+			Long:    `lists the commands executed on the database, most recent first, including the scripts run, timings, status and who ran them`,
+			Example: "dbman db history --status failure --since 24h",
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVar(&c.appVersion, "app-version", "", "only shows commands of the specified application version")
+	c.cmd.Flags().StringVar(&c.command, "command", "", "only shows executions of the specified command")
+	c.cmd.Flags().StringVar(&c.status, "status", "", "only shows executions with the specified status - success, failure")
+	c.cmd.Flags().StringVar(&c.since, "since", "", "only shows executions started after a duration ago (e.g. 24h) or a date (e.g. 2006-01-02)")
+	c.cmd.Flags().IntVar(&c.limit, "limit", 50, "the maximum number of executions to show, 0 for all")
+	c.cmd.Flags().StringVarP(&c.format, "output", "o", "json", "the format of the output - yaml, json, csv")
+	return c
+}
+
+func (c *DbHistoryCmd) Run(cmd *cobra.Command, args []string) {
+	filter, err := ParseHistoryFilter(c.appVersion, c.command, c.status, c.since, c.limit)
+	if err != nil {
+		fmt.Print(err.Error())
+		os.Exit(1)
+	}
+	table, err := DM.History(filter)
+	if err != nil {
+		fmt.Print(err.Error())
+		os.Exit(1)
+	}
+	table.Print(c.format)
+}
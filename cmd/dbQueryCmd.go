@@ -54,7 +54,7 @@ func (c *DbQueryCmd) Run(cmd *cobra.Command, args []string) {
 		if len(args) == 2 {
 			parts := strings.Split(args[1], ",")
 			for _, part := range parts {
-				subPart := strings.Split(part, "=")
+				subPart := strings.SplitN(part, "=", 2)
 				if len(subPart) != 2 {
 					fmt.Printf("!!! I cannot break down query parameter '%s': format should be 'key=value'\n", subPart)
 					return
@@ -9,26 +9,49 @@ package cmd
 
 import (
 	"fmt"
-
 	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+	"strings"
 )
 
 type DbRestoreCmd struct {
-	cmd *cobra.Command
+	cmd   *cobra.Command
+	force bool
 }
 
 func NewDbRestoreCmd() *DbRestoreCmd {
 	c := &DbRestoreCmd{
-		&cobra.Command{
+		cmd: &cobra.Command{
 			Use:   "restore [backup]",
 			Short: "restores a specific backup",
-			Long:  ``,
+			Long: `restores a backup taken by dbman db backup; the backup can be specified by name or by the path to its metadata file
+the restore is refused if the database has a version newer than the backup, unless --force is used`,
+			Example: "dbman db restore interlink-20230101120000",
 		},
 	}
 	c.cmd.Run = c.Run
+	c.cmd.Flags().BoolVar(&c.force, "force", false, "restores the backup even if the database has a newer version than the backup")
 	return c
 }
 
 func (c *DbRestoreCmd) Run(cmd *cobra.Command, args []string) {
-	fmt.Println("restore called")
+	if len(args) != 1 {
+		fmt.Printf("!!! You forgot to tell me the name of the backup you want to restore\n")
+		os.Exit(1)
+	}
+	dm := DM.WithOutput(os.Stdout)
+	restore := dm.Restore
+	// only the command line can restore a backup from the path to its metadata file
+	if strings.HasSuffix(args[0], ".json") {
+		restore = dm.RestoreFrom
+	}
+	_, err, elapsed := restore(args[0], c.force)
+	if err != nil {
+		fmt.Printf("!!! I cannot restore the database\n")
+		fmt.Printf("%v\n", err)
+		fmt.Printf("? the execution time was %v\n", elapsed)
+		os.Exit(1)
+	}
+	fmt.Printf("? I have restored the database in %v\n", elapsed)
 }
@@ -16,7 +16,8 @@ import (
 )
 
 type DbRunCmd struct {
-	cmd *cobra.Command
+	cmd    *cobra.Command
+	dryRun bool
 }
 
 func NewDbRunCmd() *DbRunCmd {
@@ -28,6 +29,7 @@ func NewDbRunCmd() *DbRunCmd {
 		},
 	}
 	c.cmd.Run = c.Run
+	c.cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "prints the merged scripts that would be executed, without running them")
 	return c
 }
 
@@ -37,13 +39,16 @@ func (c *DbRunCmd) Run(_ *cobra.Command, args []string) {
 		fmt.Printf("!!! You forgot to tell me the name of the command(s) you want to run\n")
 		return
 	}
-	output, err, elapsed := DM.Run(strings.Split(args[0], ","))
-	fmt.Print(output.String())
+	_, err, elapsed := DM.WithOutput(os.Stdout).Run(strings.Split(args[0], ","), c.dryRun)
 	if err != nil {
 		fmt.Printf("!!! I cannot execute the requested commands\n")
 		fmt.Printf("%v\n", err)
 		fmt.Printf("? the execution time was %v\n", elapsed)
 		os.Exit(1)
 	}
+	if c.dryRun {
+		fmt.Printf("? I have completed the dry run in %v\n", elapsed)
+		return
+	}
 	fmt.Printf("? I have executed the requested commands in %v\n", elapsed)
 }
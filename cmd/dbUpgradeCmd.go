@@ -10,11 +10,15 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"os"
 	. "southwinds.dev/dbman/core"
 )
 
 type DbUpgradeCmd struct {
-	cmd *cobra.Command
+	cmd         *cobra.Command
+	dryRun      bool
+	resume      bool
+	fromCommand string
 }
 
 func NewDbUpgradeCmd() *DbUpgradeCmd {
@@ -26,17 +30,23 @@ func NewDbUpgradeCmd() *DbUpgradeCmd {
 		},
 	}
 	c.cmd.Run = c.Run
+	c.cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "prints the merged scripts that would be executed, without running them")
+	c.cmd.Flags().BoolVar(&c.resume, "resume", false, "continues a failed upgrade from the command that failed")
+	c.cmd.Flags().StringVar(&c.fromCommand, "from-command", "", "continues a failed upgrade from the specified command, implies --resume")
 	return c
 }
 
 func (c *DbUpgradeCmd) Run(cmd *cobra.Command, args []string) {
-	output, err, elapsed := DM.Upgrade()
-	fmt.Print(output.String())
+	_, err, elapsed := DM.WithOutput(os.Stdout).Upgrade(c.dryRun, c.resume, c.fromCommand)
 	if err != nil {
 		fmt.Printf("!!! I cannot upgrade the database\n")
 		fmt.Printf("%v\n", err)
 		fmt.Printf("? the execution time was %v\n", elapsed)
 		return
 	}
+	if c.dryRun {
+		fmt.Printf("? I have completed the dry run in %v\n", elapsed)
+		return
+	}
 	fmt.Printf("? I have upgraded the database in %v\n", elapsed)
 }
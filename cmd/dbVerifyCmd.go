@@ -0,0 +1,50 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+type DbVerifyCmd struct {
+	cmd    *cobra.Command
+	format string
+}
+
+func NewDbVerifyCmd() *DbVerifyCmd {
+	c := &DbVerifyCmd{
+		cmd: &cobra.Command{
+			Use:   "verify",
+			Short: "checks that the scripts of applied releases have not changed since they were applied",
+			Long: `recomputes the checksums of the scripts executed for each applied release from the script repository and compares them with the checksums recorded in the database
+the command exits with a non-zero code if any script has been modified or cannot be found in the repository`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.format, "output", "o", "json", "the format of the output - yaml, json, csv")
+	return c
+}
+
+func (c *DbVerifyCmd) Run(cmd *cobra.Command, args []string) {
+	table, drifted, err, elapsed := DM.Verify()
+	if err != nil {
+		fmt.Printf("!!! I cannot verify the database scripts\n")
+		fmt.Printf("%v\n", err)
+		fmt.Printf("? the execution time was %v\n", elapsed)
+		os.Exit(1)
+	}
+	table.Print(c.format)
+	if drifted > 0 {
+		fmt.Printf("!!! I have found %d script(s) that changed after being applied\n", drifted)
+		os.Exit(1)
+	}
+	fmt.Printf("? I have verified %d script(s) in %v\n", len(table.Rows), elapsed)
+}
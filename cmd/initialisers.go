@@ -13,7 +13,8 @@ func InitialiseRootCmd() *RootCmd {
 	configCmd := InitialiseConfigCmd()
 	releaseCmd := InitialiseReleaseCmd()
 	dbCmd := InitialiseDbCmd()
-	rootCmd.Command.AddCommand(releaseCmd.cmd, dbCmd.cmd, configCmd.cmd, serveCmd.cmd)
+	pluginCmd := InitialisePluginCmd()
+	rootCmd.Command.AddCommand(releaseCmd.cmd, dbCmd.cmd, configCmd.cmd, serveCmd.cmd, pluginCmd.cmd)
 	return rootCmd
 }
 
@@ -21,7 +22,11 @@ func InitialiseReleaseCmd() *ReleaseCmd {
 	releaseCmd := NewReleaseCmd()
 	releaseInfoCmd := NewReleaseInfoCmd()
 	releasePlanCmd := NewReleasePlanCmd()
-	releaseCmd.cmd.AddCommand(releaseInfoCmd.cmd, releasePlanCmd.cmd)
+	releasePackCmd := NewReleasePackCmd()
+	releaseSignCmd := NewReleaseSignCmd()
+	releaseValidateCmd := NewReleaseValidateCmd()
+	releaseConvertCmd := NewReleaseConvertCmd()
+	releaseCmd.cmd.AddCommand(releaseInfoCmd.cmd, releasePlanCmd.cmd, releasePackCmd.cmd, releaseSignCmd.cmd, releaseValidateCmd.cmd, releaseConvertCmd.cmd)
 	return releaseCmd
 }
 
@@ -32,6 +37,7 @@ func InitialiseDbCmd() *DbCmd {
 	dbCreateCmd := NewDbCreateCmd()
 	dbDeployCmd := NewDbDeployCmd()
 	dbUpgradeCmd := NewDbUpgradeCmd()
+	dbDowngradeCmd := NewDbDowngradeCmd()
 	dbQueryCmd := NewDbQueryCmd()
 	dbQueriesCmd := NewDbQueriesCmd()
 	dbBackupCmd := NewDbBackupCmd()
@@ -39,6 +45,8 @@ func InitialiseDbCmd() *DbCmd {
 	dbInfoCmd := NewDbInfoCmd()
 	dbWaitCmd := NewWaitCmd()
 	dbRunCmd := NewDbRunCmd()
+	dbVerifyCmd := NewDbVerifyCmd()
+	dbHistoryCmd := NewDbHistoryCmd()
 	dbCmd.cmd.AddCommand(dbVersionCmd.cmd,
 		dbDiffCmd.cmd,
 		dbDeployCmd.cmd,
@@ -46,12 +54,15 @@ func InitialiseDbCmd() *DbCmd {
 		dbDeployCmd.cmd,
 		dbRunCmd.cmd,
 		dbUpgradeCmd.cmd,
+		dbDowngradeCmd.cmd,
 		dbQueryCmd.cmd,
 		dbQueriesCmd.cmd,
 		dbBackupCmd.cmd,
 		dbRestoreCmd.cmd,
 		dbInfoCmd.cmd,
-		dbWaitCmd.cmd)
+		dbWaitCmd.cmd,
+		dbVerifyCmd.cmd,
+		dbHistoryCmd.cmd)
 	return dbCmd
 }
 
@@ -66,3 +77,11 @@ func InitialiseConfigCmd() *ConfigCmd {
 	cfgCmd.cmd.AddCommand(cfgSetCmd.cmd, cfgShowCmd.cmd, cfgUseCmd.cmd, cfgListCmd.cmd, cfgRmCmd.cmd, checkCmd.cmd)
 	return cfgCmd
 }
+
+func InitialisePluginCmd() *PluginCmd {
+	pluginCmd := NewPluginCmd()
+	pluginListCmd := NewPluginListCmd()
+	pluginVerifyCmd := NewPluginVerifyCmd()
+	pluginCmd.cmd.AddCommand(pluginListCmd.cmd, pluginVerifyCmd.cmd)
+	return pluginCmd
+}
@@ -0,0 +1,27 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import "github.com/spf13/cobra"
+
+type PluginCmd struct {
+	cmd *cobra.Command
+}
+
+func NewPluginCmd() *PluginCmd {
+	c := &PluginCmd{
+		cmd: &cobra.Command{
+			Use:   "plugin",
+			Short: "manages database provider plugins",
+			Long: `lists and verifies the database provider plugins (dbman-db-<name>) found in the directories in Plugins, 
+the working directory and DbMan's directory`,
+			// the plugin commands only need the configuration, not a connection to the database provider
+			PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		}}
+	return c
+}
@@ -0,0 +1,50 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"southwinds.dev/dbman/core"
+	"strings"
+)
+
+// decorator for the plugin list cobra command
+type PluginListCmd struct {
+	cmd    *cobra.Command
+	format string
+}
+
+func NewPluginListCmd() *PluginListCmd {
+	c := &PluginListCmd{
+		cmd: &cobra.Command{
+			Use:   "list",
+			Short: "lists the database providers available to DbMan",
+			Long: `lists the native database providers and launches each plugin found in the plugin search path
+to report its name, version, protocol and capabilities`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.format, "output", "o", "json", "the format of the output - yaml, json, csv")
+	return c
+}
+
+func (c *PluginListCmd) Run(cmd *cobra.Command, args []string) {
+	report, err := core.ListPlugins(core.NewConfig("", ""))
+	if err != nil {
+		fmt.Printf("!!! I cannot list the plugins\n")
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	report.Table().Print(c.format)
+	fmt.Printf("? I have searched for plugins in %s\n", strings.Join(report.SearchPath, ", "))
+	if !report.Valid() {
+		fmt.Printf("! some plugins cannot be loaded, see their status above\n")
+	}
+}
@@ -0,0 +1,54 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"southwinds.dev/dbman/core"
+)
+
+// decorator for the plugin verify cobra command
+type PluginVerifyCmd struct {
+	cmd    *cobra.Command
+	format string
+}
+
+func NewPluginVerifyCmd() *PluginVerifyCmd {
+	c := &PluginVerifyCmd{
+		cmd: &cobra.Command{
+			Use:   "verify",
+			Short: "checks the plugin checksums against the plugin allow-list",
+			Long: `computes the SHA-256 checksum of each plugin found in the plugin search path and checks it against the allow-list in PluginsAllowList
+if there is no allow-list, the checksums are reported so that they can be used to create one
+the command exits with a non-zero code if any plugin is not in the allow-list or does not match its checksum`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.format, "output", "o", "json", "the format of the output - yaml, json, csv")
+	return c
+}
+
+func (c *PluginVerifyCmd) Run(cmd *cobra.Command, args []string) {
+	report, err := core.VerifyPlugins(core.NewConfig("", ""))
+	if err != nil {
+		fmt.Printf("!!! I cannot verify the plugins\n")
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	report.Table().Print(c.format)
+	if len(report.AllowList) == 0 {
+		fmt.Printf("! there is no plugin allow-list, set PluginsAllowList to only allow plugins with known checksums\n")
+	}
+	if !report.Valid() {
+		fmt.Printf("!!! I have found plugins that are not allowed to run\n")
+		os.Exit(1)
+	}
+	fmt.Printf("? I have verified %d plugin(s)\n", len(report.Plugins))
+}
@@ -0,0 +1,55 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+// decorator for the release convert cobra command
+type ReleaseConvertCmd struct {
+	cmd    *cobra.Command
+	format string
+	keep   bool
+}
+
+func NewReleaseConvertCmd() *ReleaseConvertCmd {
+	c := &ReleaseConvertCmd{
+		cmd: &cobra.Command{
+			Use:   "convert [files]",
+			Short: "converts release plans and manifests between the JSON and YAML formats",
+			Long: `converts each file to the specified format, writing the converted file next to the original with a .json or .yaml extension
+files whose name starts with plan are converted as a release plan, the others as a release manifest
+as plan.json and manifest.json take precedence over their YAML equivalents, the original file is removed unless --keep is used`,
+			Example: `dbman release convert plan.json v1/manifest.json v2/manifest.json --to yaml`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.format, "to", "t", "", "the format to convert to - json or yaml, if not specified the format the file is not in")
+	c.cmd.Flags().BoolVar(&c.keep, "keep", false, "keeps the original files")
+	return c
+}
+
+func (c *ReleaseConvertCmd) Run(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Printf("!!! I need at least one file to convert, see help: dbman release convert --help\n")
+		os.Exit(1)
+	}
+	for _, file := range args {
+		converted, err := ConvertRelease(file, c.format, c.keep)
+		if err != nil {
+			fmt.Printf("!!! I cannot convert %s\n", file)
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("? I have converted %s into %s\n", file, converted)
+	}
+}
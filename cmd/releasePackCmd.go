@@ -0,0 +1,47 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+// decorator for the release pack cobra command
+type ReleasePackCmd struct {
+	cmd      *cobra.Command
+	filename string
+}
+
+func NewReleasePackCmd() *ReleasePackCmd {
+	c := &ReleasePackCmd{
+		cmd: &cobra.Command{
+			Use:   "pack",
+			Short: "packs all the releases in the scripts repository into a single release bundle",
+			Long: `writes the release plan, the release manifests and all the command, query and theme files they reference into a tar.gz release bundle 
+including an index with the checksum of each file
+the bundle can be used as the Repo.URI (e.g. file:///path/to/dbman-bundle-0.0.4.tar.gz) where the scripts repository cannot be reached`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.filename, "filename", "f", "", `the bundle file name, if not specified dbman-bundle-[latest app version].tar.gz`)
+	return c
+}
+
+func (c *ReleasePackCmd) Run(cmd *cobra.Command, args []string) {
+	index, file, err, elapsed := DM.Pack(c.filename)
+	if err != nil {
+		fmt.Printf("!!! I cannot pack the release bundle\n")
+		fmt.Printf("%v\n", err)
+		fmt.Printf("? the execution time was %v\n", elapsed)
+		os.Exit(1)
+	}
+	fmt.Printf("? I have packed %d release(s) and %d file(s) into %s in %v\n", len(index.Releases), len(index.Files), file, elapsed)
+}
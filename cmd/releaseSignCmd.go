@@ -0,0 +1,63 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+// decorator for the release sign cobra command
+type ReleaseSignCmd struct {
+	cmd    *cobra.Command
+	key    string
+	dir    string
+	newKey bool
+}
+
+func NewReleaseSignCmd() *ReleaseSignCmd {
+	c := &ReleaseSignCmd{
+		cmd: &cobra.Command{
+			Use:   "sign",
+			Short: "signs the releases in a local scripts repository",
+			Long: `writes an ed25519 detached signature (.sig file) next to the release plan, each release manifest and every script and theme file they reference
+when the Repo.PublicKey is set, DbMan rejects any unsigned or tampered content before executing anything against the database
+use --new-key to create the key pair first: the private key is written to the --key file and the public key to the --key file with a .pub extension`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.key, "key", "k", "", "the file containing the base64 encoded ed25519 private key")
+	c.cmd.Flags().StringVarP(&c.dir, "dir", "d", ".", "the root directory of the scripts repository")
+	c.cmd.Flags().BoolVar(&c.newKey, "new-key", false, "creates a new key pair before signing")
+	_ = c.cmd.MarkFlagRequired("key")
+	return c
+}
+
+func (c *ReleaseSignCmd) Run(cmd *cobra.Command, args []string) {
+	if c.newKey {
+		publicKey, err := GenerateSigningKey(c.key)
+		if err != nil {
+			fmt.Printf("!!! I cannot create the signing key pair\n")
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("? I have created a new key pair, set the Repo.PublicKey to %s\n", publicKey)
+	}
+	signed, err := SignRelease(c.dir, c.key)
+	if err != nil {
+		fmt.Printf("!!! I cannot sign the releases\n")
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	for _, file := range signed {
+		fmt.Printf("? I have signed %s\n", file)
+	}
+	fmt.Printf("? I have signed %d file(s)\n", len(signed))
+}
@@ -0,0 +1,52 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	. "southwinds.dev/dbman/core"
+)
+
+// decorator for the release validate cobra command
+type ReleaseValidateCmd struct {
+	cmd    *cobra.Command
+	format string
+}
+
+func NewReleaseValidateCmd() *ReleaseValidateCmd {
+	c := &ReleaseValidateCmd{
+		cmd: &cobra.Command{
+			Use:   "validate",
+			Short: "checks the release plan and manifests for errors",
+			Long: `checks every release in the plan for unresolved command references, duplicate names, missing files, unresolved or unused merge variables, 
+unknown database providers and db versions going backwards
+the command exits with a non-zero code if any error is found, so that it can be used in CI pipelines`,
+		},
+	}
+	c.cmd.Run = c.Run
+	c.cmd.Flags().StringVarP(&c.format, "output", "o", "json", "the format of the output - yaml, json, csv")
+	return c
+}
+
+func (c *ReleaseValidateCmd) Run(cmd *cobra.Command, args []string) {
+	report, err, elapsed := DM.Validate()
+	if err != nil {
+		fmt.Printf("!!! I cannot validate the releases\n")
+		fmt.Printf("%v\n", err)
+		fmt.Printf("? the execution time was %v\n", elapsed)
+		os.Exit(1)
+	}
+	report.Table().Print(c.format)
+	if !report.Valid() {
+		fmt.Printf("!!! I have found %d error(s) and %d warning(s) in %d release(s)\n", report.Errors, report.Warnings, report.Releases)
+		os.Exit(1)
+	}
+	fmt.Printf("? I have validated %d release(s) in %v, with %d warning(s)\n", report.Releases, elapsed, report.Warnings)
+}
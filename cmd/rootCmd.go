@@ -16,13 +16,14 @@ import (
 
 type RootCmd struct {
 	*cobra.Command
+	refresh bool
 }
 
 // https://textkool.com/en/ascii-art-generator?hl=default&vl=default&font=Broadway%20KB&text=dbman%0A
 
 func NewRootCmd() *RootCmd {
 	c := &RootCmd{
-		&cobra.Command{
+		Command: &cobra.Command{
 			Use:   "dbman",
 			Short: "database manager",
 			Long: `
@@ -36,7 +37,11 @@ dbman is a CLI tool to manage database schema versions and upgrades.
 dbman can also be run from a container (when in http mode) to manage the data / schema life cycle of databases from a container platform.`,
 		},
 	}
-	cobra.OnInitialize(c.initConfig)
+	c.PersistentFlags().BoolVar(&c.refresh, "refresh", false, "ignores the script repository content cached on disk and downloads it again")
+	// the nearest PersistentPreRun is executed, so commands not needing a database (e.g. plugin) can skip loading the provider
+	c.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		c.initConfig()
+	}
 	return c
 }
 
@@ -47,5 +52,8 @@ func (c *RootCmd) initConfig() {
 		fmt.Println(err.Error())
 		os.Exit(-1)
 	}
+	if c.refresh {
+		dm.RefreshScripts()
+	}
 	core.DM = dm
 }
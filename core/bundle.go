@@ -0,0 +1,207 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"time"
+)
+
+// BundleFormat the format of the release bundles written by this version of DbMan
+const BundleFormat = "dbman-bundle/1"
+
+// BundleIndex the index of a release bundle, stored as index.json at the root of the bundle archive
+type BundleIndex struct {
+	// the format of the bundle
+	Format string `json:"format"`
+	// the time the bundle was created
+	Created time.Time `json:"created"`
+	// the script repository the bundle was packed from
+	Source string `json:"source"`
+	// the revision of the script repository (e.g. the git commit SHA), if any
+	Revision string `json:"revision,omitempty"`
+	// the releases in the bundle
+	Releases []Info `json:"releases"`
+	// the files in the bundle
+	Files []BundleFile `json:"files"`
+}
+
+// BundleFile a file in a release bundle
+type BundleFile struct {
+	// the path of the file relative to the root of the bundle
+	Path string `json:"path"`
+	// the size of the file in bytes
+	Size int `json:"size"`
+	// the SHA-256 checksum of the file content
+	Checksum string `json:"checksum"`
+}
+
+// collects the files required by all the releases in the release plan, including their signatures if any
+func (s *ScriptManager) bundleFiles(plan *Plan) (map[string][]byte, error) {
+	source, err := s.getSource()
+	if err != nil {
+		return nil, err
+	}
+	files, err := releaseFiles(source, plan, s.get(ThemeName))
+	if err != nil {
+		return nil, err
+	}
+	signatures := make(map[string][]byte)
+	for p := range files {
+		if signature, err := source.ReadFile(p + signatureExt); err == nil {
+			signatures[p+signatureExt] = signature
+		}
+	}
+	for p, signature := range signatures {
+		files[p] = signature
+	}
+	return files, nil
+}
+
+// collects the files required by all the releases in the release plan: the plan, the release manifests,
+// the command and query scripts and the theme files
+// theme: the theme to collect if the source cannot list the available themes
+func releaseFiles(source ScriptSource, plan *Plan, theme string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	read := func(p string) error {
+		p = cleanPath(p)
+		if _, found := files[p]; found {
+			return nil
+		}
+		content, err := source.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[p] = content
+		return nil
+	}
+	planPath, content, err := findFile(source.ReadFile, ".", planFiles)
+	if err != nil {
+		return nil, err
+	}
+	files[planPath] = content
+	for _, release := range plan.Releases {
+		manifestPath, content, err := findFile(source.ReadFile, release.Path, manifestFiles)
+		if err != nil {
+			return nil, err
+		}
+		files[manifestPath] = content
+		manifest, err := new(Manifest).Decode(content)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("!!! I cannot decode the manifest of release %s: %v\n", release.AppVersion, err))
+		}
+		for _, command := range manifest.Commands {
+			for _, script := range command.Scripts {
+				if err = read(path.Join(release.Path, manifest.CommandsPath, script.File)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, query := range manifest.Queries {
+			if err = read(path.Join(release.Path, manifest.QueriesPath, query.File)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// include all the themes if the source can list them, otherwise the specified theme only
+	if themes, err := source.List("theme"); err == nil {
+		for _, p := range themes {
+			if strings.HasSuffix(p, signatureExt) {
+				continue
+			}
+			if err = read(p); err != nil {
+				return nil, err
+			}
+		}
+	} else if len(theme) > 0 {
+		for _, file := range []string{"style.css", "header.html", "footer.html"} {
+			// theme files are optional
+			_ = read(path.Join("theme", theme, file))
+		}
+	}
+	return files, nil
+}
+
+// writes the files and their index into a tar.gz release bundle
+func writeBundle(w io.Writer, index *BundleIndex, files map[string][]byte) error {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	index.Files = make([]BundleFile, 0, len(paths))
+	for _, p := range paths {
+		index.Files = append(index.Files, BundleFile{Path: p, Size: len(files[p]), Checksum: Checksum(string(files[p]))})
+	}
+	indexBytes, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	write := func(name string, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: index.Created,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	if err = write("index.json", indexBytes); err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if err = write(p, files[p]); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// verifies the files extracted from a release bundle against the bundle index
+// archives without an index (i.e. not created by release pack) are not verified
+func verifyBundle(files map[string][]byte) error {
+	content, found := files["index.json"]
+	if !found {
+		return nil
+	}
+	index := new(BundleIndex)
+	if err := json.NewDecoder(bytes.NewReader(content)).Decode(index); err != nil {
+		return errors.New(fmt.Sprintf("invalid bundle index: %v", err))
+	}
+	if index.Format != BundleFormat {
+		return errors.New(fmt.Sprintf("unsupported bundle format '%s', expected '%s'", index.Format, BundleFormat))
+	}
+	for _, f := range index.Files {
+		data, found := files[f.Path]
+		if !found {
+			return errors.New(fmt.Sprintf("file %s in the bundle index is missing", f.Path))
+		}
+		if Checksum(string(data)) != f.Checksum {
+			return errors.New(fmt.Sprintf("the checksum of file %s does not match the bundle index", f.Path))
+		}
+	}
+	return nil
+}
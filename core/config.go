@@ -21,6 +21,7 @@ const (
 	AppVersion       = "AppVersion"
 	ThemeName        = "Theme"
 	Plugins          = "Plugins"
+	PluginsAllowList = "PluginsAllowList"
 	HttpMetrics      = "Http.Metrics"
 	HttpAuthMode     = "Http.AuthMode"
 	HttpUsername     = "Http.Username"
@@ -29,6 +30,8 @@ const (
 	RepoURI          = "Repo.URI"
 	RepoUsername     = "Repo.Username"
 	RepoPassword     = "Repo.Password"
+	RepoRef          = "Repo.Ref"
+	RepoPublicKey    = "Repo.PublicKey"
 	DbProvider       = "Db.Provider"
 	DbHost           = "Db.Host"
 	DbPort           = "Db.Port"
@@ -38,6 +41,12 @@ const (
 	DbAdminUser      = "Db.AdminUsername"
 	DbAdminPwd       = "Db.AdminPassword"
 	DbObjectsPattern = "Db.ObjectsPattern"
+	DbLockTimeout    = "Db.LockTimeout"
+	DbPath           = "Db.Path"
+	DbDriver         = "Db.Driver"
+	DbDSN            = "Db.DSN"
+	DbAdminDSN       = "Db.AdminDSN"
+	BackupPath       = "Backup.Path"
 )
 
 // dbman configuration management struct
@@ -111,6 +120,8 @@ func (c *Config) Load(path string, name string) error {
 
 	_ = c.cfg.BindEnv("AppVersion")
 	_ = c.cfg.BindEnv("Theme")
+	_ = c.cfg.BindEnv("Plugins")
+	_ = c.cfg.BindEnv("PluginsAllowList")
 	_ = c.cfg.BindEnv("Http.Port")
 	_ = c.cfg.BindEnv("Http.AuthMode")
 	_ = c.cfg.BindEnv("Http.Username")
@@ -124,9 +135,17 @@ func (c *Config) Load(path string, name string) error {
 	_ = c.cfg.BindEnv("Db.Password")
 	_ = c.cfg.BindEnv("Db.AdminUsername")
 	_ = c.cfg.BindEnv("Db.AdminPassword")
+	_ = c.cfg.BindEnv("Db.LockTimeout")
+	_ = c.cfg.BindEnv("Db.Path")
+	_ = c.cfg.BindEnv("Db.Driver")
+	_ = c.cfg.BindEnv("Db.DSN")
+	_ = c.cfg.BindEnv("Db.AdminDSN")
 	_ = c.cfg.BindEnv("Repo.URI")
 	_ = c.cfg.BindEnv("Repo.Username")
 	_ = c.cfg.BindEnv("Repo.Password")
+	_ = c.cfg.BindEnv("Repo.Ref")
+	_ = c.cfg.BindEnv("Repo.PublicKey")
+	_ = c.cfg.BindEnv("Backup.Path")
 
 	return nil
 }
@@ -236,6 +255,8 @@ func (c *Config) GetString(key string) string {
 // default config file content
 const cfgFile = `AppVersion = "1.0.0"
 Theme = ""
+Plugins = ""
+PluginsAllowList = ""
 [Http]
 	Metrics  = "true"
 	AuthMode = "basic"
@@ -251,8 +272,17 @@ Theme = ""
     Password      = "1nt3rlink"
     AdminUsername = "postgres"
     AdminPassword = "p0stg3s"
+    LockTimeout   = "60"
+    Path          = ""
+    Driver        = ""
+    DSN           = ""
+    AdminDSN      = ""
 [Repo]
-    URI      = "https://raw.githubusercontent.com/southwinds-io/interlink-db/master"
-    Username = ""
-    Password = ""
+    URI       = "https://raw.githubusercontent.com/southwinds-io/interlink-db/master"
+    Username  = ""
+    Password  = ""
+    Ref       = ""
+    PublicKey = ""
+[Backup]
+    Path = ""
 `
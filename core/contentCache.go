@@ -0,0 +1,87 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// contentCache an on-disk cache of the files downloaded from http(s) script repositories
+// the cached files are revalidated with the server using their ETag, so that unchanged files are not downloaded again
+type contentCache struct {
+	// the directory where the files are cached
+	dir string
+	// if true, the cached files are ignored and downloaded again
+	refresh bool
+}
+
+// creates a cache of the downloaded files in the specified directory
+func newContentCache(dir string, refresh bool) *contentCache {
+	return &contentCache{dir: dir, refresh: refresh}
+}
+
+// fetch gets the content of the file at the specified uri, using the cached copy if the server reports it has not changed
+// if the cache is nil, the file is always downloaded
+func (c *contentCache) fetch(uri string) ([]byte, error) {
+	if c == nil {
+		content, _, _, err := httpGet(uri, "")
+		return content, err
+	}
+	etag, cached, found := c.read(uri)
+	if !found || c.refresh {
+		etag = ""
+	}
+	content, newEtag, notModified, err := httpGet(uri, etag)
+	if err != nil {
+		return nil, err
+	}
+	if notModified {
+		return cached, nil
+	}
+	// files without an ETag cannot be revalidated so are not cached
+	if len(newEtag) > 0 {
+		c.write(uri, newEtag, content)
+	}
+	return content, nil
+}
+
+// reads the ETag and content of a cached file
+func (c *contentCache) read(uri string) (etag string, content []byte, found bool) {
+	name := c.filename(uri)
+	etagBytes, err := os.ReadFile(name + ".etag")
+	if err != nil {
+		return "", nil, false
+	}
+	content, err = os.ReadFile(name)
+	if err != nil {
+		return "", nil, false
+	}
+	return strings.TrimSpace(string(etagBytes)), content, true
+}
+
+// caches the content of a file, failing to cache a file is not an error as it can be downloaded again
+func (c *contentCache) write(uri string, etag string, content []byte) {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return
+	}
+	name := c.filename(uri)
+	// the content is written before its ETag so that a partially written entry is never used
+	if os.WriteFile(name, content, 0600) == nil {
+		_ = os.WriteFile(name+".etag", []byte(etag), 0600)
+	}
+}
+
+// the cached files are named after the SHA-256 of their uri without credentials
+func (c *contentCache) filename(uri string) string {
+	sum := sha256.Sum256([]byte(redactUrl(uri)))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,42 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentCache(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Write([]byte(testRepo["plan.json"]))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		content, err := newContentCache(dir, false).fetch(server.URL + "/plan.json")
+		if err != nil || string(content) != testRepo["plan.json"] {
+			t.Fatalf("unexpected content %q: %v", content, err)
+		}
+	}
+	if downloads != 1 {
+		t.Fatalf("expected the file to be downloaded once, downloaded %d times", downloads)
+	}
+	// refresh ignores the cached copy
+	if _, err := newContentCache(dir, true).fetch(server.URL + "/plan.json"); err != nil || downloads != 2 {
+		t.Fatalf("expected the file to be downloaded again: %v", err)
+	}
+}
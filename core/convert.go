@@ -0,0 +1,89 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+)
+
+// ConvertRelease translates a release plan or a release manifest file between the JSON and YAML formats
+// file: the file to convert, it is taken as a release plan if its name starts with plan, otherwise as a manifest
+// format: the format to convert to, either json or yaml; if empty, the format the file is not in
+// keep: if true the original file is kept, otherwise it is removed after the converted file is written
+// returns the name of the converted file
+func ConvertRelease(file string, format string, keep bool) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("!!! I cannot read %s: %v\n", file, err))
+	}
+	format = strings.ToLower(format)
+	if len(format) == 0 {
+		format = "json"
+		if IsJSON(content) {
+			format = "yaml"
+		}
+	}
+	if format == "yml" {
+		format = "yaml"
+	}
+	if format != "json" && format != "yaml" {
+		return "", errors.New(fmt.Sprintf("!!! I cannot convert to format '%s', valid formats are json and yaml\n", format))
+	}
+	// decodes into the release types so that the converted file only contains valid attributes
+	var value interface{}
+	if strings.HasPrefix(strings.ToLower(filepath.Base(file)), "plan") {
+		value, err = new(Plan).decode(content)
+	} else {
+		value, err = new(Manifest).Decode(content)
+	}
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("!!! I cannot decode %s: %v\n", file, err))
+	}
+	var buf bytes.Buffer
+	if format == "json" {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		// scripts and descriptions often contain < > and &
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(value)
+	} else {
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		err = encoder.Encode(value)
+		if err == nil {
+			err = encoder.Close()
+		}
+	}
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("!!! I cannot convert %s to %s: %v\n", file, format, err))
+	}
+	target := strings.TrimSuffix(file, filepath.Ext(file)) + "." + format
+	if target == file {
+		return "", errors.New(fmt.Sprintf("!!! %s is already in %s format\n", file, format))
+	}
+	if _, err = os.Stat(target); err == nil {
+		return "", errors.New(fmt.Sprintf("!!! %s already exists, I will not overwrite it\n", target))
+	}
+	if err = os.WriteFile(target, buf.Bytes(), 0644); err != nil {
+		return "", errors.New(fmt.Sprintf("!!! I cannot write %s: %v\n", target, err))
+	}
+	if !keep {
+		if err = os.Remove(file); err != nil {
+			return target, errors.New(fmt.Sprintf("!!! I have written %s but cannot remove %s: %v\n", target, file, err))
+		}
+	}
+	return target, nil
+}
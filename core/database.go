@@ -13,26 +13,34 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
 	"os"
-	"os/exec"
 	. "southwinds.dev/dbman/plugin"
 	"strings"
 )
 
+// the names of the database providers built into DbMan
+var nativeProviders = []string{"_pgsql", "_sqlite", "_sql"}
+
 func NewDatabase(cfg *Config) (*DatabaseProviderManager, error) {
 	provider, client, err := getDbProvider(cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &DatabaseProviderManager{
+	db := &DatabaseProviderManager{
 		provider: provider,
 		client:   client,
-	}, nil
+	}
+	if client != nil {
+		db.protocol = client.NegotiatedVersion()
+	}
+	return db, nil
 }
 
 // manages the lifecycle of a DatabaseProvider
 type DatabaseProviderManager struct {
 	provider DatabaseProvider
 	client   *plugin.Client
+	// the protocol version negotiated with the plugin, 0 for native providers
+	protocol int
 }
 
 // safely terminate the rpc client
@@ -44,6 +52,13 @@ func (db *DatabaseProviderManager) Provider() DatabaseProvider {
 	return db.provider
 }
 
+// Legacy returns true if the provider is a plugin built for an older DbMan version, using the legacy net/rpc protocol
+// these plugins can only get and set the version, run commands and queries and get the database information, so they
+// cannot lock the database, record the command history and upgrade progress, bind query parameters or take backups
+func (db *DatabaseProviderManager) Legacy() bool {
+	return db.protocol == LegacyProtocolVersion
+}
+
 // load a database provider plugin
 func getDbProvider(cfg *Config) (DatabaseProvider, *plugin.Client, error) {
 	// declare the database provider instance
@@ -58,6 +73,14 @@ func getDbProvider(cfg *Config) (DatabaseProvider, *plugin.Client, error) {
 		case "_pgsql":
 			// create a plugin instance
 			provider = &PgSQLProvider{}
+		// the SQLite native db provider
+		case "_sqlite":
+			// create a plugin instance
+			provider = &SQLiteProvider{}
+		// the generic provider using a registered database/sql driver
+		case "_sql":
+			// create a plugin instance
+			provider = &SQLProvider{}
 		default:
 			// there is not any native provider implemented for the required name
 			return nil, nil, errors.New(fmt.Sprintf("!!! I do not support a native database provider called '%s'", dbProvider))
@@ -80,37 +103,22 @@ func getDbProvider(cfg *Config) (DatabaseProvider, *plugin.Client, error) {
 		Level:  hclog.Error,
 	})
 
-	// start by launching the plugin process
-	client := plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: plugin.HandshakeConfig{
-			ProtocolVersion:  1,
-			MagicCookieKey:   "dbman-db-provider",
-			MagicCookieValue: fmt.Sprintf("dbman-db-%s", dbProvider),
-		},
-		Plugins: map[string]plugin.Plugin{
-			dbProvider: &DatabaseProviderPlugin{},
-		},
-		Cmd:    exec.Command(fmt.Sprintf("./dbman-db-%s", dbProvider)),
-		Logger: logger,
-	})
-	// defer client.Kill()
-
-	// connect to the db plugin via RPC
-	rpcClient, err := client.Client()
+	// find the plugin executable in the plugin search path
+	path, err := findPlugin(pluginSearchPath(cfg), dbProvider)
 	if err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("I cannot load db provider %s (%s)\n", dbProvider, err))
+		return nil, nil, err
 	}
-
-	// request the plugin
-	raw, err := rpcClient.Dispense(dbProvider)
+	// if there is an allow-list, only plugins in the list with a matching checksum are launched
+	allowList, err := loadAllowList(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// launch the plugin process and connect to it via RPC
+	// the DatabaseProvider returned feels like a normal interface implementation but is in fact over an RPC connection
+	db, client, err := launchPlugin(dbProvider, path, allowList, logger)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	// We should have a DatabaseProvider now! This feels like a normal interface
-	// implementation but is in fact over an RPC connection.
-	db := raw.(DatabaseProvider)
-
 	// return the provider
 	return db, client, nil
 }
@@ -12,8 +12,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	"io"
 	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
 	. "southwinds.dev/dbman/plugin"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -29,6 +35,8 @@ type DbMan struct {
 	db *DatabaseProviderManager
 	// is it ready?
 	ready bool
+	// if not nil, the log of the operations is also written to it as it is produced
+	output io.Writer
 }
 
 func NewDbMan() (*DbMan, error) {
@@ -46,7 +54,7 @@ func NewDbMan() (*DbMan, error) {
 		log.WriteString(fmt.Sprintf("OOPS!!! %s - ", err))
 		log.WriteString("try one of the following solutions: ")
 		log.WriteString("(1) if using a native provider, check that the provider name is correct in DbMan's config file ")
-		log.WriteString("(2) if using a plugin, check that the plugin file exist in DbMan's directory and that the name is correct in DbMan's config file ")
+		log.WriteString("(2) if using a plugin, check that the plugin file exists in one of the directories in Plugins, the working directory or DbMan's directory, and that the name is correct in DbMan's config file ")
 		return nil, errors.New(log.String())
 	}
 	// pass in DbMan's configuration to the database provider
@@ -74,6 +82,15 @@ func (dm *DbMan) GetReleaseInfo(appVersion string) (*Info, *Manifest, error) {
 	return dm.script.fetchManifest(appVersion)
 }
 
+// the application version to deploy, resolving aliases such as latest or 1.2.x against the release plan
+func (dm *DbMan) appVersion() (string, error) {
+	plan, err := dm.script.fetchPlan()
+	if err != nil {
+		return "", err
+	}
+	return plan.resolve(dm.get(AppVersion))
+}
+
 func (dm *DbMan) SaveConfig() {
 	dm.Cfg.Save()
 }
@@ -170,12 +187,27 @@ func (dm *DbMan) WaitForConnection(attempts, interval int) error {
 	return nil
 }
 
-func (dm *DbMan) Create() (log bytes.Buffer, err error, elapsed time.Duration) {
+// Create runs the commands required to create the database in the first place
+// dryRun: if true, prints the scripts that would be executed without running them
+func (dm *DbMan) Create(dryRun bool) (log bytes.Buffer, err error, elapsed time.Duration) {
 	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
 	log = bytes.Buffer{}
-	appVer := dm.get(AppVersion)
+	out := dm.newLog(&log)
+	// prevent other DbMan instances from changing the database at the same time
+	if !dryRun {
+		if err = dm.lock(out); err != nil {
+			return log, err, time.Since(start)
+		}
+		defer dm.unlock()
+	}
+	appVer, err := dm.appVersion()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
 	// get database release version
-	log.WriteString(fmt.Sprintf("? I am checking that the database '%s' does not already exist\n", dm.get(DbName)))
+	out.WriteString(fmt.Sprintf("? I am checking that the database '%s' does not already exist\n", dm.get(DbName)))
 	r := dm.DbPlugin().GetVersion()
 	result := NewParameterFromJSON(r)
 	// if no error then
@@ -190,7 +222,7 @@ func (dm *DbMan) Create() (log bytes.Buffer, err error, elapsed time.Duration) {
 		}
 	}
 	// fetch the release manifest for appVersion
-	log.WriteString(fmt.Sprintf("? I am retrieving the release manifest for application version '%v'\n", dm.get(AppVersion)))
+	out.WriteString(fmt.Sprintf("? I am retrieving the release manifest for application version '%v'\n", appVer))
 	_, manifest, err := dm.script.fetchManifest(appVer)
 	if err != nil {
 		return log, err, time.Since(start)
@@ -198,24 +230,38 @@ func (dm *DbMan) Create() (log bytes.Buffer, err error, elapsed time.Duration) {
 	// get the commands for the create action
 	cmds := manifest.GetCommands(manifest.Create.Commands)
 	// run the commands on the database
-	output, err := dm.runCommands(cmds, manifest)
-	log.WriteString(output.String())
+	_, err = dm.runCommands(out, appVer, cmds, manifest, dryRun)
 	// return
 	return log, err, time.Since(start)
 }
 
-func (dm *DbMan) Deploy() (log bytes.Buffer, err error, elapsed time.Duration) {
+// Deploy runs the commands required to deploy the database schema and objects on an empty database
+// dryRun: if true, prints the scripts that would be executed without running them
+func (dm *DbMan) Deploy(dryRun bool) (log bytes.Buffer, err error, elapsed time.Duration) {
 	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
 	log = bytes.Buffer{}
-	appVer := dm.get(AppVersion)
+	out := dm.newLog(&log)
+	// prevent other DbMan instances from changing the database at the same time
+	if !dryRun {
+		if err = dm.lock(out); err != nil {
+			return log, err, time.Since(start)
+		}
+		defer dm.unlock()
+	}
+	appVer, err := dm.appVersion()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
 	// get database release version
 	r := dm.DbPlugin().GetVersion()
 	result := NewParameterFromJSON(r)
-	if !result.HasError() {
+	if v := result.GetVersion(); !result.HasError() && v != nil {
 		// there is already a database with a pre-existing deployment so cannot continue
 		return log, errors.New(fmt.Sprintf("!!! I have found an existing database version %v, which is for application version %v",
-			result.GetString("dbVersion"),
-			result.GetString("appVersion"))), time.Since(start)
+			v.DbVersion,
+			v.AppVersion)), time.Since(start)
 	}
 	// fetch the release manifest for appVersion
 	info, manifest, err := dm.script.fetchManifest(appVer)
@@ -225,29 +271,47 @@ func (dm *DbMan) Deploy() (log bytes.Buffer, err error, elapsed time.Duration) {
 	// get the commands for the deploy action
 	cmds := manifest.GetCommands(manifest.Deploy.Commands)
 	// run the commands on the database
-	output, err := dm.runCommands(cmds, manifest)
-	log.WriteString(output.String())
+	scripts, err := dm.runCommands(out, appVer, cmds, manifest, dryRun)
 	if err != nil {
 		return log, err, time.Since(start)
 	}
+	if dryRun {
+		out.WriteString(dm.dryRunVersion(appVer, manifest.DbVersion))
+		return log, nil, time.Since(start)
+	}
 	// update release version history
-	err = dm.setDbVersion(appVer, manifest.DbVersion, fmt.Sprintf("Created database version %s", manifest.DbVersion), info.Path)
+	err = dm.setDbVersion(appVer, manifest.DbVersion, fmt.Sprintf("Created database version %s", manifest.DbVersion), info.Path, scripts)
 	if err != nil {
-		log.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
+		out.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
 	}
 	return log, err, time.Since(start)
 }
 
-func (dm *DbMan) Run(cmdNames []string) (log bytes.Buffer, err error, elapsed time.Duration) {
+// Run runs one or more commands defined in the release manifest of the current application version
+// dryRun: if true, prints the scripts that would be executed without running them
+func (dm *DbMan) Run(cmdNames []string, dryRun bool) (log bytes.Buffer, err error, elapsed time.Duration) {
 	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
 	log = bytes.Buffer{}
-	_, manifest, err := dm.script.fetchManifest(dm.get(AppVersion))
+	out := dm.newLog(&log)
+	// prevent other DbMan instances from changing the database at the same time
+	if !dryRun {
+		if err = dm.lock(out); err != nil {
+			return log, err, time.Since(start)
+		}
+		defer dm.unlock()
+	}
+	appVer, err := dm.appVersion()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	_, manifest, err := dm.script.fetchManifest(appVer)
 	if err != nil {
 		return log, err, time.Since(start)
 	}
 	cmds := manifest.GetCommands(cmdNames)
-	output, err := dm.runCommands(cmds, manifest)
-	log.WriteString(output.String())
+	_, err = dm.runCommands(out, appVer, cmds, manifest, dryRun)
 	if err != nil {
 		return log, err, time.Since(start)
 	}
@@ -255,13 +319,15 @@ func (dm *DbMan) Run(cmdNames []string) (log bytes.Buffer, err error, elapsed ti
 }
 
 // add a new entry in the database version history
-func (dm *DbMan) setDbVersion(appVer string, dbVersion string, description string, path string) error {
+// scripts: the checksums of the scripts executed since the last entry was added
+func (dm *DbMan) setDbVersion(appVer string, dbVersion string, description string, path string, scripts []ScriptChecksum) error {
 	var err error = nil
 	input := &Version{
 		AppVersion:  appVer,
 		DbVersion:   dbVersion,
 		Description: description,
-		Source:      fmt.Sprintf("%s/%s", dm.get(RepoURI), path),
+		Source:      dm.source(path),
+		Scripts:     scripts,
 	}
 	setVerResult := NewParameterFromJSON(dm.DbPlugin().SetVersion(input.ToString()))
 	if setVerResult.HasError() {
@@ -270,12 +336,37 @@ func (dm *DbMan) setDbVersion(appVer string, dbVersion string, description strin
 	return err
 }
 
-func (dm *DbMan) Upgrade() (log bytes.Buffer, err error, elapsed time.Duration) {
+// describes the location of a release in the script repository, including the repository revision if any
+// (e.g. the git commit SHA) so that the exact scripts applied can be traced back
+func (dm *DbMan) source(path string) string {
+	if revision := dm.script.revision(); len(revision) > 0 {
+		return fmt.Sprintf("%s/%s@%s", dm.get(RepoURI), path, revision)
+	}
+	return fmt.Sprintf("%s/%s", dm.get(RepoURI), path)
+}
+
+// Upgrade runs the commands required to upgrade an existing database to the current application version
+// dryRun: if true, prints the scripts that would be executed without running them
+// resume: if true, continues a failed upgrade from the command that failed
+// fromCommand: if not empty, continues a failed upgrade from the named command (implies resume)
+func (dm *DbMan) Upgrade(dryRun bool, resume bool, fromCommand string) (log bytes.Buffer, err error, elapsed time.Duration) {
 	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
 	log = bytes.Buffer{}
+	out := dm.newLog(&log)
+	// prevent other DbMan instances from changing the database at the same time
+	if !dryRun {
+		if err = dm.lock(out); err != nil {
+			return log, err, time.Since(start)
+		}
+		defer dm.unlock()
+	}
 	// gets the target app version
-	targetAppVer := dm.get(AppVersion)
-
+	targetAppVer, err := dm.appVersion()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
 	// gets the current app version
 	version, err := dm.getVersion()
 	if err != nil {
@@ -289,91 +380,677 @@ func (dm *DbMan) Upgrade() (log bytes.Buffer, err error, elapsed time.Duration)
 	if err != nil {
 		return log, err, time.Since(start)
 	}
-	// decide the upgrade path i.e. compare current vs target version
-	// if the target version matches the current installed version
-	if targetAppVer == version.AppVersion {
+	// the app version the upgrade path starts from
+	originAppVer := version.AppVersion
+	// the progress of the failed upgrade to resume
+	var progress *Progress
+	if resume || len(fromCommand) > 0 {
+		progress, err = dm.getResumableProgress(targetAppVer)
+		if err != nil {
+			return log, err, time.Since(start)
+		}
+		// the upgrade path is the one of the failed upgrade, as the version table may have moved on
+		originAppVer = progress.From
+	} else if targetAppVer == version.AppVersion {
+		// if the target version matches the current installed version
 		// nothing to do!
-		log.WriteString(fmt.Sprintf("? I have nothing to do: the current version (i.e. %s) matches the version deployed\nIf you need to upgrade to a different version change the value of the 'AppVersion' configuration variable\n", version.AppVersion))
+		out.WriteString(fmt.Sprintf("? I have nothing to do: the current version (i.e. %s) matches the version deployed\nIf you need to upgrade to a different version change the value of the 'AppVersion' configuration variable\n", version.AppVersion))
 		return log, nil, time.Since(start)
 	}
 	// check if an upgrade is possible
-	currentIx, targetIx := plan.getUpgradeWindow(version.AppVersion, targetAppVer)
-	if targetIx <= currentIx {
+	if compareVersions(targetAppVer, originAppVer) <= 0 {
 		// cannot upgrade so returns
-		return log, errors.New(fmt.Sprintf("!!! I cannot upgrade as target version %s is not past the current version %s", targetAppVer, version.AppVersion)), time.Since(start)
+		return log, errors.New(fmt.Sprintf("!!! I cannot upgrade as target version %s is not past the current version %s\nIf you need to roll back the database use the downgrade command instead", targetAppVer, originAppVer)), time.Since(start)
+	}
+	// work out the releases in the upgrade path
+	path, err := plan.upgradePath(originAppVer, targetAppVer)
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	// work out the steps of the upgrade path
+	steps, err := dm.upgradeSteps(path)
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	// the step to start from
+	first := 0
+	if progress != nil {
+		first, err = dm.resumeStep(steps, progress, fromCommand)
+		if err != nil {
+			return log, err, time.Since(start)
+		}
+		out.WriteString(fmt.Sprintf("? I am resuming the upgrade from application version %s to %s at release %s, %s\n", progress.From, progress.To, steps[first].info.AppVersion, steps[first]))
+	}
+	// if releases are signed, verifies the scripts of the whole upgrade path before changing the database
+	if len(dm.get(RepoPublicKey)) > 0 {
+		for _, step := range steps[first:] {
+			if step.cmd == nil {
+				continue
+			}
+			if _, err = dm.script.fetchCommandContent(step.info.AppVersion, step.manifest.CommandsPath, *step.cmd); err != nil {
+				return log, err, time.Since(start)
+			}
+		}
+	}
+	// the checksums of the scripts executed since the version history was last updated
+	var scripts []ScriptChecksum
+	if progress != nil {
+		// the steps of the failed upgrade completed since the version history was last updated are recorded with the next update
+		scripts, err = dm.completedScripts(steps, first)
+		if err != nil {
+			return log, err, time.Since(start)
+		}
 	}
 	// execute upgrade
-	// loop through releases
-	for i := currentIx; i <= targetIx; i++ {
+	// loop through the steps
+	for i := first; i < len(steps); i++ {
+		step := steps[i]
+		// if the step is the first one of a release
+		if i == first || steps[i-1].ix != step.ix {
+			out.WriteString(fmt.Sprintf("? I am applying manifest for application version %s, db version %s\n", step.info.AppVersion, step.info.DbVersion))
+			if step.ix != 0 && len(step.manifest.Upgrade.Alter) == 0 {
+				out.WriteString(fmt.Sprintf("? I did not find an Alter command in the manifest, so I am not applying any changes to the schema\n"))
+			}
+		}
+		if !dryRun {
+			out.WriteString(dm.setProgress(step.progress(originAppVer, targetAppVer, i, "running", nil)))
+		}
+		err = dm.runUpgradeStep(step, out, &scripts, path[0], len(path)-1, dryRun)
+		if err != nil {
+			if !dryRun {
+				out.WriteString(dm.setProgress(step.progress(originAppVer, targetAppVer, i, "failed", err)))
+				out.WriteString(fmt.Sprintf("? once the cause of the failure has been fixed, the upgrade can be resumed from this step using the resume option\n"))
+			}
+			return log, err, time.Since(start)
+		}
+	}
+	if !dryRun {
+		last := steps[len(steps)-1]
+		out.WriteString(dm.setProgress(last.progress(originAppVer, targetAppVer, len(steps), "completed", nil)))
+	}
+	return log, nil, time.Since(start)
+}
+
+// upgradeStep a step in the upgrade path, either running a command or updating the version history
+type upgradeStep struct {
+	// the position of the release in the upgrade path, starting with the release being upgraded at 0
+	ix int
+	// the release information
+	info *Info
+	// the release manifest
+	manifest *Manifest
+	// the stage of the release: prepare, alter, deploy or version
+	stage string
+	// the command to run, nil for the version stage
+	cmd *Command
+}
+
+func (s upgradeStep) String() string {
+	if s.cmd == nil {
+		return "version history update"
+	}
+	return fmt.Sprintf("%s command '%s'", s.stage, s.cmd.Name)
+}
+
+// progress creates the progress record of the step
+func (s upgradeStep) progress(from string, to string, index int, status string, err error) *Progress {
+	p := &Progress{
+		From:    from,
+		To:      to,
+		Release: s.info.AppVersion,
+		Stage:   s.stage,
+		Step:    index,
+		Status:  status,
+		Time:    time.Now().UTC(),
+	}
+	if s.cmd != nil {
+		p.Command = s.cmd.Name
+	}
+	if err != nil {
+		p.Error = err.Error()
+	}
+	return p
+}
+
+// works out the steps of the upgrade path from the current release to the target release:
+// the prepare commands of the current release, the alter commands of each following release,
+// the deploy commands of the target release and a version history update after each following release
+func (dm *DbMan) upgradeSteps(path []Info) ([]upgradeStep, error) {
+	var steps []upgradeStep
+	targetIx := len(path) - 1
+	for i := range path {
+		info := path[i]
+		// gets the manifest for the release
+		_, manifest, err := dm.script.fetchManifest(info.AppVersion)
+		if err != nil {
+			return nil, err
+		}
+		addCommands := func(stage string, name string) {
+			for _, c := range manifest.GetCommands([]string{name}) {
+				cmd := c
+				steps = append(steps, upgradeStep{ix: i, info: &info, manifest: manifest, stage: stage, cmd: &cmd})
+			}
+		}
+		// run the prepare to upgrade scripts only on the release being upgraded
+		if i == 0 {
+			addCommands("prepare", manifest.Upgrade.Prepare)
+			continue
+		}
+		// if the release is not the one being upgraded and there is an alter command defined in the manifest
+		if len(manifest.Upgrade.Alter) > 0 {
+			addCommands("alter", manifest.Upgrade.Alter)
+		}
+		// run the deploy objects commands only on the target release
+		if i == targetIx {
+			addCommands("deploy", manifest.Upgrade.Deploy)
+		}
+		steps = append(steps, upgradeStep{ix: i, info: &info, manifest: manifest, stage: "version"})
+	}
+	return steps, nil
+}
+
+// runs a step of the upgrade path
+// scripts: the checksums of the scripts executed since the version history was last updated
+// origin: the release the upgrade started from
+func (dm *DbMan) runUpgradeStep(step upgradeStep, out *opLog, scripts *[]ScriptChecksum, origin Info, targetIx int, dryRun bool) error {
+	// run the command
+	if step.cmd != nil {
+		executed, err := dm.runCommands(out, step.info.AppVersion, []Command{*step.cmd}, step.manifest, dryRun)
+		*scripts = append(*scripts, executed...)
+		return err
+	}
+	// otherwise, update the release version history
+	appVer, description := step.info.AppVersion, fmt.Sprintf("Updated database schema only to version %s", step.manifest.DbVersion)
+	if step.ix == targetIx {
+		description = fmt.Sprintf("Upgraded database from version %s to %s", origin.DbVersion, step.manifest.DbVersion)
+	}
+	if dryRun {
+		out.WriteString(dm.dryRunVersion(appVer, step.manifest.DbVersion))
+		return nil
+	}
+	err := dm.setDbVersion(appVer, step.manifest.DbVersion, description, step.info.Path, *scripts)
+	if err != nil {
+		return err
+	}
+	out.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
+	*scripts = nil
+	return nil
+}
+
+// works out the checksums of the scripts of the commands run before the step to resume from,
+// since the version history was last updated
+func (dm *DbMan) completedScripts(steps []upgradeStep, first int) ([]ScriptChecksum, error) {
+	from := first
+	for from > 0 && steps[from-1].cmd != nil {
+		from--
+	}
+	var scripts []ScriptChecksum
+	for _, step := range steps[from:first] {
+		cmd, err := dm.script.fetchCommandContent(step.info.AppVersion, step.manifest.CommandsPath, *step.cmd)
+		if err != nil {
+			return nil, err
+		}
+		for _, script := range cmd.Scripts {
+			scripts = append(scripts, NewScriptChecksum(step.info.AppVersion, step.manifest.DbVersion, cmd.Name, script))
+		}
+	}
+	return scripts, nil
+}
+
+// gets the progress of the last upgrade and checks that it can be resumed to the target app version
+func (dm *DbMan) getResumableProgress(targetAppVer string) (*Progress, error) {
+	if dm.legacyPlugin() {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot resume the upgrade as the database plugin '%s' was built for an older version of DbMan "+
+			"and does not record the progress of upgrades\n", dm.get(DbProvider)))
+	}
+	result := NewParameterFromJSON(dm.DbPlugin().GetProgress())
+	if result.HasError() {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot retrieve the progress of the last upgrade: %s\n", result.Error()))
+	}
+	progress := result.GetProgress()
+	if progress == nil {
+		return nil, errors.New("!!! I cannot resume the upgrade as there is no record of a previous upgrade\n")
+	}
+	if progress.Status == "completed" {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot resume the upgrade as the last upgrade to version %s has completed\n", progress.To))
+	}
+	if progress.To != targetAppVer {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot resume the upgrade to version %s as the last upgrade was to version %s\n", targetAppVer, progress.To))
+	}
+	return progress, nil
+}
+
+// finds the index of the step to resume the upgrade from
+// if fromCommand is empty, the upgrade resumes from the step that failed, otherwise from the named command,
+// looking first in the release that failed and then in the whole upgrade path
+func (dm *DbMan) resumeStep(steps []upgradeStep, progress *Progress, fromCommand string) (int, error) {
+	if len(fromCommand) == 0 {
+		if progress.Step < len(steps) {
+			s := steps[progress.Step]
+			if s.info.AppVersion == progress.Release && s.stage == progress.Stage && (s.cmd == nil || s.cmd.Name == progress.Command) {
+				return progress.Step, nil
+			}
+		}
+		return 0, errors.New(fmt.Sprintf("!!! I cannot find the %s step of release %s in the upgrade path, the release plan might have changed\n"+
+			"Use the from-command option to specify the command to resume from\n", progress.Stage, progress.Release))
+	}
+	found := -1
+	for i, s := range steps {
+		if s.cmd != nil && s.cmd.Name == fromCommand {
+			if s.info.AppVersion == progress.Release {
+				return i, nil
+			}
+			if found == -1 {
+				found = i
+			}
+		}
+	}
+	if found == -1 {
+		return 0, errors.New(fmt.Sprintf("!!! I cannot find the command '%s' in the upgrade path from version %s to %s\n", fromCommand, progress.From, progress.To))
+	}
+	return found, nil
+}
+
+// records the progress of an upgrade
+// a failure to record the progress does not fail the upgrade, so it is returned as a warning to add to the log
+func (dm *DbMan) setProgress(progress *Progress) string {
+	// plugins using the legacy protocol cannot record the progress, as reported when locking the database
+	if dm.legacyPlugin() {
+		return ""
+	}
+	result := NewParameterFromJSON(dm.DbPlugin().SetProgress(progress.ToString()))
+	if result.HasError() {
+		return fmt.Sprintf("! I cannot record the progress of the upgrade: %s\n", strings.TrimRight(result.Error().Error(), "\n"))
+	}
+	return ""
+}
+
+// Backup takes a logical backup of the managed database using the database provider
+// the database dump and a metadata file describing it are written to the backup directory
+// format: the format of the database dump, either file or directory
+func (dm *DbMan) Backup(format string) (log bytes.Buffer, backup *Backup, err error, elapsed time.Duration) {
+	start := time.Now()
+	log = bytes.Buffer{}
+	out := dm.newLog(&log)
+	// ensure the backup directory exists
+	dir, err := dm.getBackupDir()
+	if err != nil {
+		return log, nil, err, time.Since(start)
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return log, nil, errors.New(fmt.Sprintf("!!! I cannot create the backup directory '%s': %v\n", dir, err)), time.Since(start)
+	}
+	// the backup name is unique for the database and time the backup was taken
+	name := fmt.Sprintf("%s-%s", dm.get(DbName), start.UTC().Format("20060102150405"))
+	input := &Backup{
+		Name:     name,
+		Path:     filepath.Join(dir, name),
+		Format:   format,
+		Database: dm.get(DbName),
+		Provider: dm.get(DbProvider),
+		Time:     start.UTC(),
+	}
+	out.WriteString(fmt.Sprintf("? I am backing up the database '%s'\n", dm.get(DbName)))
+	result := NewParameterFromJSON(dm.DbPlugin().Backup(input.ToString()))
+	out.WriteString(result.GetLog())
+	if result.HasError() {
+		return log, nil, result.Error(), time.Since(start)
+	}
+	backup = result.GetBackup()
+	if backup == nil {
+		return log, nil, errors.New("!!! The database plugin did not return the backup information\n"), time.Since(start)
+	}
+	// write the backup metadata next to the database dump
+	err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.json", name)), []byte(backup.ToString()), 0644)
+	if err != nil {
+		return log, backup, errors.New(fmt.Sprintf("!!! I cannot write the backup metadata: %v\n", err)), time.Since(start)
+	}
+	out.WriteString(fmt.Sprintf("? I have written backup '%s' for application version '%s' to '%s'\n", backup.Name, backup.AppVersion, backup.Path))
+	return log, backup, nil, time.Since(start)
+}
+
+// Restore restores the managed database from a backup taken by DbMan
+// the application version recorded in the backup is checked against the release plan and the current database version
+// name: the name of the backup in the backup directory
+// force: if true, restores the backup even if the database has a newer version than the backup
+func (dm *DbMan) Restore(name string, force bool) (log bytes.Buffer, err error, elapsed time.Duration) {
+	return dm.restore(func() (*Backup, error) { return dm.getBackup(name) }, force)
+}
+
+// RestoreFrom restores the managed database from the backup described by the metadata file at the passed-in path
+// as the path is not restricted to the backup directory, it must only be used by the command line
+// force: if true, restores the backup even if the database has a newer version than the backup
+func (dm *DbMan) RestoreFrom(path string, force bool) (log bytes.Buffer, err error, elapsed time.Duration) {
+	return dm.restore(func() (*Backup, error) { return readBackup(path, path) }, force)
+}
+
+// restores the backup whose metadata is read by the passed-in function
+func (dm *DbMan) restore(getBackup func() (*Backup, error), force bool) (log bytes.Buffer, err error, elapsed time.Duration) {
+	start := time.Now()
+	log = bytes.Buffer{}
+	out := dm.newLog(&log)
+	// prevent other DbMan instances from changing the database at the same time
+	if err = dm.lock(out); err != nil {
+		return log, err, time.Since(start)
+	}
+	defer dm.unlock()
+	// read the backup metadata
+	backup, err := getBackup()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	out.WriteString(fmt.Sprintf("? I have found backup '%s' taken on %v for application version '%s' and database version '%s'\n", backup.Name, backup.Time, backup.AppVersion, backup.DbVersion))
+	if backup.Provider != dm.get(DbProvider) {
+		out.WriteString(fmt.Sprintf("! the backup was taken by database provider '%s' but I am using '%s'\n", backup.Provider, dm.get(DbProvider)))
+	}
+	// check the backup version is part of the release plan
+	plan, err := dm.GetReleasePlan()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	backupInfo, _ := plan.info(backup.AppVersion)
+	if backupInfo == nil {
+		out.WriteString(fmt.Sprintf("! application version '%s' recorded in the backup is not in the release plan\n", backup.AppVersion))
+	}
+	// check the database does not have a newer version than the backup
+	version, _ := dm.getVersion()
+	if version != nil {
+		currentInfo, _ := plan.info(version.AppVersion)
+		newer := backupInfo == nil || currentInfo == nil || compareVersions(version.AppVersion, backup.AppVersion) > 0
+		if newer && version.AppVersion != backup.AppVersion {
+			if !force {
+				return log, errors.New(fmt.Sprintf("!!! I cannot restore the backup as the database has version '%s' which is newer than or cannot be compared to backup version '%s'\n"+
+					"If you meant to roll back the database, run the restore again forcing it\n", version.AppVersion, backup.AppVersion)), time.Since(start)
+			}
+			out.WriteString(fmt.Sprintf("! I am forcing the restore of version '%s' on top of database version '%s'\n", backup.AppVersion, version.AppVersion))
+		}
+	}
+	// restore the backup
+	result := NewParameterFromJSON(dm.DbPlugin().Restore(backup.ToString()))
+	out.WriteString(result.GetLog())
+	if result.HasError() {
+		return log, result.Error(), time.Since(start)
+	}
+	out.WriteString(fmt.Sprintf("? I have restored backup '%s'\n", backup.Name))
+	return log, nil, time.Since(start)
+}
+
+// Downgrade rolls back an existing database to the release defined by the AppVersion configuration value
+// walking the release plan backwards from the current release and running the downgrade commands of each release
+func (dm *DbMan) Downgrade() (log bytes.Buffer, err error, elapsed time.Duration) {
+	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
+	log = bytes.Buffer{}
+	out := dm.newLog(&log)
+	// prevent other DbMan instances from changing the database at the same time
+	if err = dm.lock(out); err != nil {
+		return log, err, time.Since(start)
+	}
+	defer dm.unlock()
+	// gets the target app version
+	targetAppVer, err := dm.appVersion()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	// gets the current app version
+	version, err := dm.getVersion()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	if version == nil {
+		return log, errors.New("!!! the database does not exist\n"), time.Since(start)
+	}
+	// gets the release plan to understand available release path
+	plan, err := dm.GetReleasePlan()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	// if the target version matches the current installed version
+	if targetAppVer == version.AppVersion {
+		// nothing to do!
+		out.WriteString(fmt.Sprintf("? I have nothing to do: the current version (i.e. %s) matches the version deployed\nIf you need to downgrade to a different version change the value of the 'AppVersion' configuration variable\n", version.AppVersion))
+		return log, nil, time.Since(start)
+	}
+	// check if a downgrade is possible
+	if compareVersions(targetAppVer, version.AppVersion) >= 0 {
+		// cannot downgrade so returns
+		return log, errors.New(fmt.Sprintf("!!! I cannot downgrade as target version %s is not before the current version %s in the release plan", targetAppVer, version.AppVersion)), time.Since(start)
+	}
+	// the downgrade path is the path the database was upgraded along, as recorded in its command history, walked backwards
+	applied, err := dm.appliedReleases()
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	path, err := plan.downgradePath(targetAppVer, version.AppVersion, applied)
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	currentIx := len(path) - 1
+	var (
+		executed []ScriptChecksum
+		// the checksums of the scripts executed since the version history was last updated
+		scripts []ScriptChecksum
+	)
+	// loop backwards through the releases to roll back
+	for i := currentIx; i > 0; i-- {
 		// gets the specific release information
-		info := plan.Releases[i-1]
-		log.WriteString(fmt.Sprintf("? I am applying manifest for application version %s, db version %s\n", info.AppVersion, info.DbVersion))
+		info := path[i]
+		out.WriteString(fmt.Sprintf("? I am rolling back manifest for application version %s, db version %s\n", info.AppVersion, info.DbVersion))
 		// gets the manifest for the release
 		_, manifest, err := dm.script.fetchManifest(info.AppVersion)
 		if err != nil {
 			return log, err, time.Since(start)
 		}
-		var (
-			cmd    []Command
-			output bytes.Buffer
-		)
-		// run the prepare to upgrade scripts only on the release being upgraded
-		if i == currentIx {
-			// get the prepare to upgrade commands
-			cmd = manifest.GetCommands([]string{manifest.Upgrade.Prepare})
-			// prepare the database for upgrade (e.g. drop database objects)
-			output, err = dm.runCommands(cmd, manifest)
-			log.WriteString(output.String())
+		// run the prepare to downgrade scripts only on the release currently deployed
+		if i == currentIx && len(manifest.Downgrade.Prepare) > 0 {
+			executed, err = dm.runCommands(out, info.AppVersion, manifest.GetCommands([]string{manifest.Downgrade.Prepare}), manifest, false)
+			scripts = append(scripts, executed...)
 			if err != nil {
 				return log, err, time.Since(start)
 			}
-		} else {
-			// if the release is not the one being upgraded
-			// and there is an upgrade command defined in the manifest
-			if len(manifest.Upgrade.Alter) > 0 {
-				// run the schema alter scripts
-				cmd = manifest.GetCommands([]string{manifest.Upgrade.Alter})
-				// alter the database schema
-				output, err = dm.runCommands(cmd, manifest)
-				log.WriteString(output.String())
-				if err != nil {
-					return log, err, time.Since(start)
-				}
-			} else {
-				log.WriteString(fmt.Sprintf("? I did not find an Alter command in the manifest, so I am not applying any changes to the schema\n"))
+		}
+		// revert the schema changes introduced by the release
+		if len(manifest.Downgrade.Revert) > 0 {
+			executed, err = dm.runCommands(out, info.AppVersion, manifest.GetCommands([]string{manifest.Downgrade.Revert}), manifest, false)
+			scripts = append(scripts, executed...)
+			if err != nil {
+				return log, err, time.Since(start)
 			}
-			// run the deploy objects commands only on the target release
-			if i == targetIx {
-				cmd = manifest.GetCommands([]string{manifest.Upgrade.Deploy})
-				// deploy the database objects
-				output, err = dm.runCommands(cmd, manifest)
-				log.WriteString(output.String())
-				if err != nil {
-					return log, err, time.Since(start)
-				}
-				// now can update the release version history
-				err = dm.setDbVersion(targetAppVer, manifest.DbVersion, fmt.Sprintf("Upgraded database from version %s to %s", version.DbVersion, manifest.DbVersion), info.Path)
-				if err != nil {
-					return log, err, time.Since(start)
-				} else {
-					log.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
-				}
-			} else {
-				// now can update the release version history
-				err = dm.setDbVersion(info.AppVersion, manifest.DbVersion, fmt.Sprintf("Updated database schema only to version %s", manifest.DbVersion), info.Path)
-				if err != nil {
-					return log, err, time.Since(start)
-				} else {
-					log.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
-				}
+		} else {
+			out.WriteString(fmt.Sprintf("? I did not find a Revert command in the manifest, so I am not reverting any changes to the schema\n"))
+		}
+		// if the previous release is not the target, record the schema only roll back
+		if i-1 > 0 {
+			previous := path[i-1]
+			err = dm.setDbVersion(previous.AppVersion, previous.DbVersion, fmt.Sprintf("Downgraded database schema only to version %s", previous.DbVersion), previous.Path, scripts)
+			if err != nil {
+				return log, err, time.Since(start)
 			}
+			out.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
+			scripts = nil
 		}
 	}
+	// deploy the database objects of the target release
+	info := path[0]
+	_, manifest, err := dm.script.fetchManifest(info.AppVersion)
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	deploy := manifest.Downgrade.Deploy
+	if len(deploy) == 0 {
+		deploy = manifest.Upgrade.Deploy
+	}
+	executed, err = dm.runCommands(out, info.AppVersion, manifest.GetCommands([]string{deploy}), manifest, false)
+	scripts = append(scripts, executed...)
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	// now can update the release version history
+	err = dm.setDbVersion(targetAppVer, manifest.DbVersion, fmt.Sprintf("Downgraded database from version %s to %s", version.DbVersion, manifest.DbVersion), info.Path, scripts)
+	if err != nil {
+		return log, err, time.Since(start)
+	}
+	out.WriteString(fmt.Sprintf("? I am updating the release version history\n"))
 	return log, nil, time.Since(start)
 }
 
+// Pack writes the plan, manifests, scripts and themes of all the releases in the script repository into a
+// single release bundle that can be used as the Repo.URI where the script repository cannot be reached
+// filename: the bundle file name, if empty dbman-bundle-[latest app version].tar.gz in the current directory
+// returns the bundle index and the name of the bundle file written
+func (dm *DbMan) Pack(filename string) (index *BundleIndex, file string, err error, elapsed time.Duration) {
+	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
+	plan, err := dm.script.fetchPlan()
+	if err != nil {
+		return nil, "", err, time.Since(start)
+	}
+	if len(plan.Releases) == 0 {
+		return nil, "", errors.New("!!! the release plan does not contain any releases\n"), time.Since(start)
+	}
+	files, err := dm.script.bundleFiles(plan)
+	if err != nil {
+		return nil, "", err, time.Since(start)
+	}
+	if len(filename) == 0 {
+		latest, err := plan.resolve("latest")
+		if err != nil {
+			latest = plan.Releases[len(plan.Releases)-1].AppVersion
+		}
+		filename = fmt.Sprintf("dbman-bundle-%s.tar.gz", latest)
+	}
+	index = &BundleIndex{
+		Format:   BundleFormat,
+		Created:  time.Now().UTC(),
+		Source:   dm.get(RepoURI),
+		Revision: dm.script.revision(),
+		Releases: plan.Releases,
+	}
+	// writes to a temporary file first so that an existing bundle is not left truncated if packing fails
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, "", errors.New(fmt.Sprintf("!!! I cannot create the release bundle file: %v\n", err)), time.Since(start)
+	}
+	err = writeBundle(f, index, files)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, "", errors.New(fmt.Sprintf("!!! I cannot write the release bundle %s: %v\n", filename, err)), time.Since(start)
+	}
+	return index, filename, nil, time.Since(start)
+}
+
+// Validate checks the release plan and the manifests of all its releases for unresolved command references,
+// duplicate names, missing files, unresolved or unused merge variables, unknown database providers and
+// db versions going backwards, without connecting to the database
+func (dm *DbMan) Validate() (report *ValidationReport, err error, elapsed time.Duration) {
+	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
+	report, err = dm.script.validate()
+	return report, err, time.Since(start)
+}
+
+// Verify recomputes the checksums of the scripts executed for each applied release using the content in the
+// script repository and compares them with the checksums recorded in the database when the scripts were applied
+// returns a table with the status of the file and of the merged content of each script, and the number of scripts
+// whose file has drifted
+func (dm *DbMan) Verify() (table *Table, drifted int, err error, elapsed time.Duration) {
+	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
+	result := NewParameterFromJSON(dm.DbPlugin().GetChecksums())
+	if result.HasError() {
+		return nil, 0, errors.New(fmt.Sprintf("!!! I cannot retrieve the recorded script checksums: %s\n", result.Error())), time.Since(start)
+	}
+	table = &Table{Header: Row{"appVersion", "dbVersion", "command", "script", "file", "applied", "fileStatus", "mergedStatus"}}
+	// the release manifests already fetched, by application version
+	manifests := make(map[string]*Manifest)
+	for _, c := range result.GetChecksums() {
+		file, merged := dm.verifyScript(c, manifests)
+		// a script has drifted if its file has changed, the merged script can change with the configuration or context
+		// without its file changing, so it is only used when the checksum of the file was not recorded
+		if file != "OK" && (file != "UNKNOWN" || merged != "OK") {
+			drifted++
+		}
+		table.Rows = append(table.Rows, Row{c.AppVersion, c.DbVersion, c.Command, c.Script, c.File, c.Time.Format(time.RFC3339), file, merged})
+	}
+	return table, drifted, nil, time.Since(start)
+}
+
+// History returns the command execution history entries matching the filter as a table, most recent first
+func (dm *DbMan) History(filter *HistoryFilter) (*Table, error) {
+	result := NewParameterFromJSON(dm.DbPlugin().GetHistory(filter.ToString()))
+	if result.HasError() {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot retrieve the command history: %s\n", result.Error()))
+	}
+	table := &Table{Header: Row{"id", "appVersion", "dbVersion", "command", "scripts", "start", "end", "duration", "status", "error", "user", "host"}}
+	for _, e := range result.GetHistory() {
+		status := "success"
+		if !e.Success {
+			status = "failure"
+		}
+		table.Rows = append(table.Rows, Row{
+			strconv.FormatInt(e.Id, 10),
+			e.AppVersion,
+			e.DbVersion,
+			e.Command,
+			strings.Join(e.Scripts, ","),
+			e.Start.Format(time.RFC3339),
+			e.End.Format(time.RFC3339),
+			(time.Duration(e.Duration) * time.Millisecond).String(),
+			status,
+			e.Error,
+			e.User,
+			e.Host,
+		})
+	}
+	return table, nil
+}
+
+// ParseHistoryFilter creates a command history filter from user input
+// status: either success or failure, empty for any
+// since: either a duration before now (e.g. 24h) or a date (e.g. 2006-01-02 or 2006-01-02T15:04:05Z), empty for any
+// limit: the maximum number of entries to return, zero for all
+func ParseHistoryFilter(appVersion string, command string, status string, since string, limit int) (*HistoryFilter, error) {
+	filter := &HistoryFilter{
+		AppVersion: appVersion,
+		Command:    command,
+		Status:     strings.ToLower(status),
+		Limit:      limit,
+	}
+	if len(filter.Status) > 0 && filter.Status != "success" && filter.Status != "failure" {
+		return nil, errors.New(fmt.Sprintf("!!! invalid status '%s', valid values are success or failure\n", status))
+	}
+	if limit < 0 {
+		return nil, errors.New(fmt.Sprintf("!!! invalid limit %d, it must not be negative\n", limit))
+	}
+	if len(since) > 0 {
+		if d, err := time.ParseDuration(since); err == nil {
+			filter.Since = time.Now().Add(-d).UTC()
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else if t, err := time.Parse("2006-01-02", since); err == nil {
+			filter.Since = t
+		} else {
+			return nil, errors.New(fmt.Sprintf("!!! invalid since value '%s', use a duration (e.g. 24h) or a date (e.g. 2006-01-02)\n", since))
+		}
+	}
+	return filter, nil
+}
+
 func (dm *DbMan) Query(name string, params map[string]string) (*Table, *Query, time.Duration, error) {
 	start := time.Now()
+	// reads the script repository once for the whole operation
+	dm = dm.snapshot()
 	// get the release manifest for the current application version
 	_, manifest, err := dm.GetReleaseInfo(dm.Cfg.GetString(AppVersion))
 	if err != nil {
@@ -384,28 +1061,36 @@ func (dm *DbMan) Query(name string, params map[string]string) (*Table, *Query, t
 	if query == nil {
 		return nil, nil, time.Since(start), errors.New(fmt.Sprintf("!!! I cannot find query: %v\n", name))
 	}
-	// check the arguments passed in match the query definition
-	expectedParams := len(query.Vars)
-	providedParams := len(params)
-	if expectedParams != providedParams {
-		return nil, nil, time.Since(start), errors.New(fmt.Sprintf("!!! The query expected '%v' parameters but '%v' were provided\n", dm.varsToString(query.Vars), dm.paramsToString(params)))
-	}
 	// check validity of passed-in params
+	inputs := make(map[string]bool)
 	// for each parameter in the query definition
 	for _, v := range query.Vars {
 		// if the parameter is expected from the input (CLI or HTTP request)
 		if len(v.FromInput) > 0 {
+			inputs[v.FromInput] = true
 			// check the parameter has been provided
 			_, exist := params[v.FromInput]
-			// if the value is not in the input map
-			if !exist {
+			// if the value is not in the input map and there is no default value to use instead
+			if !exist && len(v.Default) == 0 {
 				// return parameter required error
 				return nil, nil, time.Since(start), errors.New(fmt.Sprintf("!!! The required query parameter '%v' has not been provided\n", v.FromInput))
 			}
 		}
 	}
+	// check the passed-in params are all expected by the query definition
+	var unknown []string
+	for key := range params {
+		if !inputs[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, nil, time.Since(start), errors.New(fmt.Sprintf("!!! The query '%s' does not expect the parameters '%v', the expected parameters are '%v'\n", name, strings.Join(unknown, ","), dm.varsToString(query.Vars)))
+	}
 	// fetch the query content
-	q, err := dm.script.fetchQueryContent(dm.get(AppVersion), manifest.QueriesPath, *query, params)
+	// plugins using the legacy protocol cannot bind query parameters, so the input values are merged with the query instead
+	q, err := dm.script.fetchQueryContent(dm.get(AppVersion), manifest.QueriesPath, *query, params, !dm.legacyPlugin())
 	if err != nil {
 		return nil, nil, time.Since(start), err
 	}
@@ -441,41 +1126,195 @@ func (dm *DbMan) Serve() {
 		router.HandleFunc("/db/info/server", s.dbServerHandler).Methods("GET")
 		router.HandleFunc("/db/info/queries", s.queriesHandler).Methods("GET")
 		router.HandleFunc("/db/query/{name}", s.queryHandler).Methods("GET")
+		router.HandleFunc("/db/history", s.historyHandler).Methods("GET")
+		router.HandleFunc("/release/validate", s.validateHandler).Methods("GET")
 		router.HandleFunc("/db/create", s.createHandler).Methods("POST")
 		router.HandleFunc("/db/deploy", s.deployHandler).Methods("POST")
 		router.HandleFunc("/db/upgrade", s.upgradeHandler).Methods("POST")
+		router.HandleFunc("/db/downgrade", s.downgradeHandler).Methods("POST")
+		router.HandleFunc("/db/backup", s.backupHandler).Methods("POST")
+		router.HandleFunc("/db/restore/{name}", s.restoreHandler).Methods("POST")
+		router.HandleFunc("/jobs", s.jobsHandler).Methods("GET")
+		router.HandleFunc("/jobs/{id}", s.jobHandler).Methods("GET")
+		router.HandleFunc("/jobs/{id}/stream", s.jobStreamHandler).Methods("GET")
+		router.HandleFunc("/db/upgrade/stream", s.upgradeStreamHandler).Methods("GET")
 	}
 	s.Serve()
 }
 
+// WithOutput returns a copy of DbMan that writes the log of its operations to the passed-in writer as it is produced
+// in addition to returning it when the operation completes
+func (dm *DbMan) WithOutput(w io.Writer) *DbMan {
+	d := *dm
+	d.output = w
+	return &d
+}
+
+// returns a copy of DbMan reading the script repository through a snapshot for the duration of an operation
+func (dm *DbMan) snapshot() *DbMan {
+	op := *dm
+	op.script = dm.script.withSnapshot()
+	return &op
+}
+
+// RefreshScripts ignores the script repository content cached on disk and downloads it again
+func (dm *DbMan) RefreshScripts() {
+	dm.script.setRefresh()
+}
+
+// creates the log of an operation, written to the returned buffer and to the DbMan output if any
+func (dm *DbMan) newLog(log *bytes.Buffer) *opLog {
+	return &opLog{log: log, output: dm.output}
+}
+
 func (dm *DbMan) getTheme(name string) *Theme {
 	return NewTheme(name, dm.script)
 }
 
-func (dm *DbMan) runCommands(cmds []Command, manifest *Manifest) (log bytes.Buffer, err error) {
-	log = bytes.Buffer{}
+// runCommands fetches the scripts of the passed-in commands from the release and runs them on the database
+// out: the operation log to write the execution progress to
+// appVersion: the application version of the release the manifest belongs to
+// dryRun: if true, writes the merged scripts to the log instead of running them
+// returns the checksums of the scripts of the commands that have been executed successfully
+func (dm *DbMan) runCommands(out *opLog, appVersion string, cmds []Command, manifest *Manifest, dryRun bool) (scripts []ScriptChecksum, err error) {
 	// fetch the scripts for the commands
 	var commands []*Command
 	for _, cmd := range cmds {
-		cmd, err := dm.script.fetchCommandContent(dm.get(AppVersion), manifest.CommandsPath, cmd)
+		cmd, err := dm.script.fetchCommandContent(appVersion, manifest.CommandsPath, cmd)
 		if err != nil {
-			return log, err
+			return nil, err
 		}
 		commands = append(commands, cmd)
 	}
+	// in dry run mode, write the merged scripts to the log without executing them
+	if dryRun {
+		for _, c := range commands {
+			out.WriteString(fmt.Sprintf("? [dry run] release %s, command '%s' would run on a connection that is %s\n", appVersion, c.Name, dm.connectionMode(c)))
+			for _, script := range c.Scripts {
+				out.WriteString(fmt.Sprintf("-- script '%s' (%s)\n%s\n", script.Name, script.File, strings.TrimRight(script.Content, "\n")))
+			}
+		}
+		return nil, nil
+	}
 	// execute the commands
 	for _, c := range commands {
-		log.WriteString(fmt.Sprintf("? I have started execution of the command '%s'\n", c.Name))
+		out.WriteString(fmt.Sprintf("? I have started execution of the command '%s'\n", c.Name))
+		started := time.Now()
 		r := dm.DbPlugin().RunCommand(c.ToString())
 		result := NewParameterFromJSON(r)
+		// record the execution in the command history
+		out.WriteString(dm.setHistory(appVersion, manifest.DbVersion, c, started, result.Error()))
 		if result.HasError() {
-			log.WriteString(fmt.Sprintf("!!! the execution of the command '%s' has failed: %s\n", c.Name, result.Error()))
-			return log, result.Error()
+			out.WriteString(fmt.Sprintf("!!! the execution of the command '%s' has failed: %s\n", c.Name, result.Error()))
+			return scripts, result.Error()
+		}
+		out.WriteString(result.GetLog())
+		out.WriteString(fmt.Sprintf("? the execution of the command '%s' has succeeded\n", c.Name))
+		for _, script := range c.Scripts {
+			scripts = append(scripts, NewScriptChecksum(appVersion, manifest.DbVersion, c.Name, script))
+		}
+	}
+	return scripts, err
+}
+
+// recomputes the checksums of an applied script from the script repository and compares them with the recorded ones
+// file: the status of the script file content, OK if unchanged, MODIFIED if changed, MISSING if the script cannot be
+// found or UNKNOWN if its checksum was not recorded
+// merged: the status of the merged script, which can also change with the values of the variables merged from the
+// configuration or the run context, OK, MODIFIED or MISSING
+func (dm *DbMan) verifyScript(c ScriptChecksum, manifests map[string]*Manifest) (file string, merged string) {
+	manifest, found := manifests[c.AppVersion]
+	if !found {
+		_, m, err := dm.script.fetchManifest(c.AppVersion)
+		if err != nil {
+			return "MISSING", "MISSING"
+		}
+		manifests[c.AppVersion] = m
+		manifest = m
+	}
+	cmds := manifest.GetCommands([]string{c.Command})
+	if len(cmds) == 0 {
+		return "MISSING", "MISSING"
+	}
+	cmd, err := dm.script.fetchCommandContent(c.AppVersion, manifest.CommandsPath, cmds[0])
+	if err != nil {
+		return "MISSING", "MISSING"
+	}
+	for _, script := range cmd.Scripts {
+		if script.Name == c.Script {
+			return checksumStatus(c.FileChecksum, script.FileChecksum), checksumStatus(c.Checksum, Checksum(script.Content))
+		}
+	}
+	return "MISSING", "MISSING"
+}
+
+// compares a recorded checksum with the current one
+func checksumStatus(recorded string, current string) string {
+	switch {
+	case len(recorded) == 0:
+		return "UNKNOWN"
+	case recorded != current:
+		return "MODIFIED"
+	default:
+		return "OK"
+	}
+}
+
+// records the execution of a command in the command history
+// a failure to record the execution does not fail the command, so it is returned as a warning to add to the log
+func (dm *DbMan) setHistory(appVersion string, dbVersion string, c *Command, started time.Time, cmdErr error) string {
+	// plugins using the legacy protocol cannot record the command history, as reported when locking the database
+	if dm.legacyPlugin() {
+		return ""
+	}
+	end := time.Now()
+	host, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	entry := &HistoryEntry{
+		AppVersion: appVersion,
+		DbVersion:  dbVersion,
+		Command:    c.Name,
+		Scripts:    make([]string, 0),
+		Start:      started.UTC(),
+		End:        end.UTC(),
+		Duration:   end.Sub(started).Milliseconds(),
+		Success:    cmdErr == nil,
+		User:       username,
+		Host:       host,
+	}
+	for _, script := range c.Scripts {
+		entry.Scripts = append(entry.Scripts, script.Name)
+	}
+	if cmdErr != nil {
+		entry.Error = cmdErr.Error()
+	}
+	result := NewParameterFromJSON(dm.DbPlugin().SetHistory(entry.ToString()))
+	if result.HasError() {
+		return fmt.Sprintf("! I cannot record the execution of the command '%s' in the command history: %s\n", c.Name, strings.TrimRight(result.Error().Error(), "\n"))
+	}
+	return ""
+}
+
+// describes the connection a command runs on
+func (dm *DbMan) connectionMode(c *Command) string {
+	mode := func(textTrue string, textFalse string, use bool) string {
+		if use {
+			return textTrue
 		}
-		log.WriteString(result.GetLog())
-		log.WriteString(fmt.Sprintf("? the execution of the command '%s' has succeeded\n", c.Name))
+		return textFalse
 	}
-	return log, err
+	return fmt.Sprintf("%s, %s and %s",
+		mode("transactional", "non-transactional", c.Transactional),
+		mode("as an admin", "as a user", c.AsAdmin),
+		mode("to the db", "to the server", c.UseDb))
+}
+
+// describes the version history entry that would be added if not running in dry run mode
+func (dm *DbMan) dryRunVersion(appVer string, dbVersion string) string {
+	return fmt.Sprintf("? [dry run] I would update the release version history to application version %s, database version %s\n", appVer, dbVersion)
 }
 
 func (dm *DbMan) get(key string) string {
@@ -486,6 +1325,11 @@ func (dm *DbMan) DbPlugin() DatabaseProvider {
 	return dm.db.Provider()
 }
 
+// returns true if the database provider is a plugin built for an older DbMan version, using the legacy protocol
+func (dm *DbMan) legacyPlugin() bool {
+	return dm.db != nil && dm.db.Legacy()
+}
+
 func (dm *DbMan) GetDbInfo() (*DbInfo, error) {
 	// query the plugin for serialised information
 	infoString := dm.DbPlugin().GetInfo()
@@ -511,6 +1355,80 @@ func (dm *DbMan) GetDbInfo() (*DbInfo, error) {
 	return nil, errors.New("!!! The database plugin did not return a result of the correct type (i.e. map[string]interface{})\n")
 }
 
+// lock acquires the cluster wide lock on the managed database
+// if another DbMan instance holds the lock, it waits up to the number of seconds in Db.LockTimeout
+// plugins using the legacy protocol cannot lock the database, so a warning is written to the operation log instead
+func (dm *DbMan) lock(out *opLog) error {
+	if dm.legacyPlugin() {
+		out.WriteString(fmt.Sprintf("! the database plugin '%s' was built for an older version of DbMan: the database is not locked and "+
+			"the command history and upgrade progress are not recorded, make sure no other DbMan instance changes the database at the same time\n", dm.get(DbProvider)))
+		return nil
+	}
+	host, _ := os.Hostname()
+	timeout, err := strconv.Atoi(dm.get(DbLockTimeout))
+	if err != nil || timeout < 0 {
+		timeout = 60
+	}
+	lock := &Lock{
+		Name:    dm.get(DbName),
+		Owner:   fmt.Sprintf("dbman@%s:%d", host, os.Getpid()),
+		Timeout: timeout,
+	}
+	return NewParameterFromJSON(dm.DbPlugin().Lock(lock.ToString())).Error()
+}
+
+// unlock releases the cluster wide lock on the managed database
+func (dm *DbMan) unlock() {
+	if dm.legacyPlugin() {
+		return
+	}
+	lock := &Lock{Name: dm.get(DbName)}
+	if err := NewParameterFromJSON(dm.DbPlugin().Unlock(lock.ToString())).Error(); err != nil {
+		fmt.Print(err.Error())
+	}
+}
+
+// getBackupDir returns the absolute path to the directory where backups are kept
+// if Backup.Path is not set, backups are kept in the configuration directory
+func (dm *DbMan) getBackupDir() (string, error) {
+	dir := dm.get(BackupPath)
+	if len(dir) == 0 {
+		dir = filepath.Join(dm.GetConfigSetDir(), ".dbman_backups")
+	}
+	return filepath.Abs(dir)
+}
+
+// getBackup reads the metadata of a backup in the backup directory
+// name: the name of the backup, which cannot contain a path so that only backups in the backup directory can be read
+func (dm *DbMan) getBackup(name string) (*Backup, error) {
+	if len(name) == 0 || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, errors.New(fmt.Sprintf("!!! '%s' is not a valid backup name, the name cannot contain a path\n", name))
+	}
+	dir, err := dm.getBackupDir()
+	if err != nil {
+		return nil, err
+	}
+	return readBackup(name, filepath.Join(dir, fmt.Sprintf("%s.json", name)))
+}
+
+// reads the metadata of a backup from the passed-in path
+func readBackup(name string, path string) (*Backup, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot read the metadata of backup '%s': %v\n", name, err))
+	}
+	return NewBackup(string(content))
+}
+
+// returns the application versions applied to the database, in the order they were applied
+func (dm *DbMan) appliedReleases() ([]string, error) {
+	result := NewParameterFromJSON(dm.DbPlugin().GetHistory((&HistoryFilter{Status: "success"}).ToString()))
+	if result.HasError() {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot retrieve the command history: %s\n", result.Error()))
+	}
+	return releaseHistory(result.GetHistory()), nil
+}
+
 func (dm *DbMan) getVersion() (*Version, error) {
 	// gets the current app version
 	v := dm.DbPlugin().GetVersion()
@@ -518,27 +1436,16 @@ func (dm *DbMan) getVersion() (*Version, error) {
 	return result.GetVersion(), result.Error()
 }
 
+// returns the names of the input parameters of the passed-in query variables, or none
 func (dm *DbMan) varsToString(vars []Var) string {
-	buffer := bytes.Buffer{}
-	for i, v := range vars {
-		buffer.WriteString(v.FromInput)
-		if i < len(vars)-1 {
-			buffer.WriteString(",")
+	var names []string
+	for _, v := range vars {
+		if len(v.FromInput) > 0 {
+			names = append(names, v.FromInput)
 		}
 	}
-	return buffer.String()
-}
-
-func (dm *DbMan) paramsToString(params map[string]string) string {
-	buffer := bytes.Buffer{}
-	for key, _ := range params {
-		buffer.WriteString(key)
-		buffer.WriteString(",")
-	}
-	result := buffer.String()
-	if len(result) == 0 {
+	if len(names) == 0 {
 		return "none"
-	} else {
-		return result[:len(result)-1]
 	}
+	return strings.Join(names, ",")
 }
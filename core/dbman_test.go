@@ -10,8 +10,14 @@ package core
 import (
 	"bytes"
 	"fmt"
+	"github.com/spf13/viper"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"southwinds.dev/dbman/plugin"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,13 +86,13 @@ func TestDbMan_Serve(t *testing.T) {
 }
 
 func TestDbMan_Create_Deploy(t *testing.T) {
-	output, err, _ := DM.Create()
+	output, err, _ := DM.Create(false)
 	fmt.Print(output.String())
 	if err != nil {
 		t.Error(err)
 		t.Fail()
 	}
-	output, err, _ = DM.Deploy()
+	output, err, _ = DM.Deploy(false)
 	fmt.Print(output.String())
 	if err != nil {
 		t.Error(err)
@@ -97,20 +103,20 @@ func TestDbMan_Create_Deploy(t *testing.T) {
 func TestDbMan_Upgrade(t *testing.T) {
 	newDb()
 	DM.Cfg.Set("AppVersion", "0.0.1")
-	_, err, _ := DM.Create()
+	_, err, _ := DM.Create(false)
 	if err != nil {
 		t.Error(err)
 		t.Fail()
 		return
 	}
-	_, err, _ = DM.Deploy()
+	_, err, _ = DM.Deploy(false)
 	if err != nil {
 		t.Error(err)
 		t.Fail()
 		return
 	}
 	DM.Cfg.Set("AppVersion", "0.0.4")
-	output, err, _ := DM.Upgrade()
+	output, err, _ := DM.Upgrade(false, false, "")
 	fmt.Print(output.String())
 	if err != nil {
 		t.Error(err)
@@ -148,3 +154,420 @@ func newDb() {
 	exec.Command("docker", "run", "--name", "ilinkdb", "-itd", "-p", "5432:5432", "-e", "POSTGRESQL_ADMIN_PASSWORD=interlink", "centos/postgresql-12-centos7").Run()
 	time.Sleep(2 * time.Second)
 }
+
+// the status of the script file is reported separately from the merged script, which changes with the configuration
+func TestDbMan_VerifyScript(t *testing.T) {
+	cfg := &Config{Cache: NewCache(), cfg: viper.New()}
+	cfg.cfg.Set("Db.Name", "interlink")
+	files := map[string][]byte{
+		"plan.json":        []byte(`{"releases":[{"appVersion":"0.0.1","dbVersion":"0.0.1","path":"v1"}]}`),
+		"v1/manifest.json": []byte(`{"dbVersion":"0.0.1","commands":[{"name":"create-db","scripts":[{"name":"db","file":"db.sql","vars":[{"name":"db","fromConf":"Db.Name"}]}]}]}`),
+		"v1/db.sql":        []byte("CREATE DATABASE {{db}};"),
+	}
+	dm := &DbMan{Cfg: cfg, script: &ScriptManager{cfg: cfg, files: files}}
+	recorded := plugin.ScriptChecksum{
+		AppVersion:   "0.0.1",
+		Command:      "create-db",
+		Script:       "db",
+		Checksum:     plugin.Checksum("CREATE DATABASE interlink;"),
+		FileChecksum: plugin.Checksum("CREATE DATABASE {{db}};"),
+	}
+	cases := []struct {
+		name           string
+		dbName, script string
+		recorded       plugin.ScriptChecksum
+		file, merged   string
+	}{
+		{"unchanged", "interlink", "CREATE DATABASE {{db}};", recorded, "OK", "OK"},
+		{"configuration changed", "onix", "CREATE DATABASE {{db}};", recorded, "OK", "MODIFIED"},
+		{"file changed", "interlink", "CREATE DATABASE {{db}} ;", recorded, "MODIFIED", "MODIFIED"},
+		{"file checksum not recorded", "interlink", "CREATE DATABASE {{db}};", plugin.ScriptChecksum{AppVersion: "0.0.1", Command: "create-db", Script: "db", Checksum: recorded.Checksum}, "UNKNOWN", "OK"},
+		{"script removed", "interlink", "CREATE DATABASE {{db}};", plugin.ScriptChecksum{AppVersion: "0.0.1", Command: "create-db", Script: "other"}, "MISSING", "MISSING"},
+	}
+	for _, c := range cases {
+		cfg.cfg.Set("Db.Name", c.dbName)
+		files["v1/db.sql"] = []byte(c.script)
+		file, merged := dm.verifyScript(c.recorded, make(map[string]*plugin.Manifest))
+		if file != c.file || merged != c.merged {
+			t.Errorf("%s: got %s/%s, want %s/%s", c.name, file, merged, c.file, c.merged)
+		}
+	}
+}
+
+// writes a scripts repository with three releases: 0.0.1 deploys table t1, 0.0.2 adds table t2 and 0.0.3 adds table t3
+// and view v3, the script of the alter command of 0.0.3 is passed-in so that it can fail
+func writeTestReleases(t *testing.T, dir string, alter3 string) {
+	files := map[string]string{
+		"plan.json": `{"releases":[
+			{"appVersion":"0.0.1","dbVersion":"1","path":"v1"},
+			{"appVersion":"0.0.2","dbVersion":"2","path":"v2"},
+			{"appVersion":"0.0.3","dbVersion":"3","path":"v3"}]}`,
+		"v1/manifest.json": `{"dbVersion":"1","commands":[
+			{"name":"create-tables","useDb":true,"scripts":[{"name":"t1","file":"t1.sql"}]},
+			{"name":"prepare-1","useDb":true,"scripts":[{"name":"p1","file":"p1.sql"}]}],
+			"deploy":{"commands":["create-tables"]},"upgrade":{"prepare":"prepare-1"}}`,
+		"v1/t1.sql": "CREATE TABLE t1(id INTEGER)",
+		"v1/p1.sql": "SELECT 1",
+		"v2/manifest.json": `{"dbVersion":"2","commands":[
+			{"name":"alter-2","useDb":true,"scripts":[{"name":"a2","file":"a2.sql"}]}],
+			"upgrade":{"alter":"alter-2"}}`,
+		"v2/a2.sql": "CREATE TABLE t2(id INTEGER)",
+		"v3/manifest.json": `{"dbVersion":"3","commands":[
+			{"name":"alter-3","useDb":true,"scripts":[{"name":"a3","file":"a3.sql"}]},
+			{"name":"deploy-3","useDb":true,"scripts":[{"name":"d3","file":"d3.sql"}]}],
+			"upgrade":{"alter":"alter-3","deploy":"deploy-3"},
+			"queries":[{"name":"total","file":"q3.sql","vars":[
+				{"name":"id","fromInput":"id","type":"int"},
+				{"name":"offset","fromInput":"offset","type":"int","default":"1"},
+				{"name":"label","fromValue":"sum"}]}]}`,
+		"v3/a3.sql": alter3,
+		"v3/d3.sql": "CREATE VIEW v3 AS SELECT * FROM t3",
+		"v3/q3.sql": "SELECT {{id}} + {{offset}} AS '{{label}}'",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// creates a DbMan reading the test repository and managing a SQLite database, both in temporary directories
+func newTestDbMan(t *testing.T, appVersion string) (*DbMan, string) {
+	repo := t.TempDir()
+	writeTestReleases(t, repo, "CREATE TABLE t3(id INTEGER)")
+	cfg := &Config{Cache: NewCache(), cfg: viper.New()}
+	for key, value := range map[string]string{
+		AppVersion:    appVersion,
+		RepoURI:       repo,
+		DbProvider:    "_sqlite",
+		DbName:        "test",
+		DbPath:        filepath.Join(t.TempDir(), "test.db"),
+		DbLockTimeout: "1",
+		BackupPath:    t.TempDir(),
+	} {
+		cfg.cfg.Set(key, value)
+	}
+	conf, err := plugin.NewConf(cfg.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &SQLiteProvider{}
+	if err = provider.Setup(conf); err != nil {
+		t.Fatal(err)
+	}
+	sm, _ := NewScriptManager(cfg)
+	dm := &DbMan{Cfg: cfg, script: sm, db: &DatabaseProviderManager{provider: &plugin.DatabasePluginDecorator{Plugin: provider}}, ready: true}
+	return dm, repo
+}
+
+// returns a function checking the result of an operation, which fails the test if the operation returned an error
+// and otherwise returns its log
+func mustRun(t *testing.T, op string) func(log bytes.Buffer, err error, elapsed time.Duration) string {
+	return func(log bytes.Buffer, err error, _ time.Duration) string {
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", op, err, log.String())
+		}
+		return log.String()
+	}
+}
+
+// the application version recorded as the latest version of the test database
+func dbVersion(t *testing.T, dm *DbMan) string {
+	version, err := dm.getVersion()
+	if err != nil || version == nil {
+		t.Fatalf("cannot get the database version: %v", err)
+	}
+	return version.AppVersion
+}
+
+func TestDbMan_UpgradeSteps(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.3")
+	plan, err := dm.GetReleasePlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := plan.upgradePath("0.0.1", "0.0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := dm.upgradeSteps(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, step := range steps {
+		got = append(got, fmt.Sprintf("%s %s", step.info.AppVersion, step))
+	}
+	// the prepare command of the release being upgraded, the alter commands of the following releases and the
+	// deploy command of the target release, updating the version history after each following release
+	want := []string{
+		"0.0.1 prepare command 'prepare-1'",
+		"0.0.2 alter command 'alter-2'",
+		"0.0.2 version history update",
+		"0.0.3 alter command 'alter-3'",
+		"0.0.3 deploy command 'deploy-3'",
+		"0.0.3 version history update",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected steps\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestDbMan_ResumeStep(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.3")
+	plan, _ := dm.GetReleasePlan()
+	path, _ := plan.upgradePath("0.0.1", "0.0.3")
+	steps, err := dm.upgradeSteps(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := &plugin.Progress{From: "0.0.1", To: "0.0.3", Release: "0.0.3", Stage: "alter", Command: "alter-3", Step: 3, Status: "failed"}
+	cases := []struct {
+		name        string
+		progress    plugin.Progress
+		fromCommand string
+		step        int
+		err         string
+	}{
+		{"failed step", *failed, "", 3, ""},
+		{"failed version update", plugin.Progress{Release: "0.0.2", Stage: "version", Step: 2}, "", 2, ""},
+		{"from command", *failed, "alter-2", 1, ""},
+		{"from command in the failed release", *failed, "deploy-3", 4, ""},
+		{"plan changed", plugin.Progress{Release: "0.0.3", Stage: "alter", Command: "alter-4", Step: 3}, "", 0, "from-command option"},
+		{"step out of range", plugin.Progress{Release: "0.0.3", Stage: "version", Step: 6}, "", 0, "the release plan might have changed"},
+		{"unknown command", *failed, "alter-4", 0, "cannot find the command 'alter-4' in the upgrade path from version 0.0.1 to 0.0.3"},
+	}
+	for _, c := range cases {
+		step, err := dm.resumeStep(steps, &c.progress, c.fromCommand)
+		if len(c.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", c.name, c.err, err)
+			}
+			continue
+		}
+		if err != nil || step != c.step {
+			t.Errorf("%s: got step %d, %v; want step %d", c.name, step, err, c.step)
+		}
+	}
+}
+
+func TestDbMan_GetResumableProgress(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.3")
+	if _, err := dm.getResumableProgress("0.0.3"); err == nil || !strings.Contains(err.Error(), "no record of a previous upgrade") {
+		t.Fatalf("expected an error as no upgrade has been recorded, got %v", err)
+	}
+	cases := []struct {
+		status, to, target string
+		err                string
+	}{
+		{"failed", "0.0.3", "0.0.3", ""},
+		{"running", "0.0.3", "0.0.3", ""},
+		{"completed", "0.0.3", "0.0.3", "has completed"},
+		{"failed", "0.0.2", "0.0.3", "the last upgrade was to version 0.0.2"},
+	}
+	for _, c := range cases {
+		if msg := dm.setProgress(&plugin.Progress{From: "0.0.1", To: c.to, Release: c.to, Stage: "alter", Status: c.status}); len(msg) > 0 {
+			t.Fatal(msg)
+		}
+		progress, err := dm.getResumableProgress(c.target)
+		if len(c.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s upgrade to %s: expected an error containing %q, got %v", c.status, c.to, c.err, err)
+			}
+			continue
+		}
+		if err != nil || progress.To != c.to {
+			t.Errorf("%s upgrade to %s: got %v, %v", c.status, c.to, progress, err)
+		}
+	}
+}
+
+// an upgrade failing in a release resumes from the failed command, without running the commands that succeeded
+func TestDbMan_ResumeUpgrade(t *testing.T) {
+	dm, repo := newTestDbMan(t, "0.0.1")
+	mustRun(t, "deploy")(dm.Deploy(false))
+	writeTestReleases(t, repo, "INSERT INTO missing VALUES(1)")
+	dm.Cfg.cfg.Set(AppVersion, "0.0.3")
+	log, err, _ := dm.Upgrade(false, false, "")
+	if err == nil {
+		t.Fatalf("expected the upgrade to fail\n%s", log.String())
+	}
+	// the releases before the failed one have been applied
+	if v := dbVersion(t, dm); v != "0.0.2" {
+		t.Fatalf("expected version 0.0.2 after the failure, got %s", v)
+	}
+	progress, err := dm.getResumableProgress("0.0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Status != "failed" || progress.Step != 3 || progress.Command != "alter-3" {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+	// the from command must be in the upgrade path
+	if _, err, _ = dm.Upgrade(false, false, "alter-4"); err == nil {
+		t.Fatal("expected an error for a command not in the upgrade path")
+	}
+	writeTestReleases(t, repo, "CREATE TABLE t3(id INTEGER)")
+	out := mustRun(t, "resume")(dm.Upgrade(false, true, ""))
+	if !strings.Contains(out, "resuming the upgrade from application version 0.0.1 to 0.0.3 at release 0.0.3, alter command 'alter-3'") {
+		t.Fatalf("the upgrade did not resume from the failed command\n%s", out)
+	}
+	if strings.Contains(out, "'prepare-1'") || strings.Contains(out, "'alter-2'") {
+		t.Fatalf("the commands that succeeded were run again\n%s", out)
+	}
+	if v := dbVersion(t, dm); v != "0.0.3" {
+		t.Fatalf("expected version 0.0.3 after resuming, got %s", v)
+	}
+	// a completed upgrade cannot be resumed
+	if _, err, _ = dm.Upgrade(false, true, ""); err == nil || !strings.Contains(err.Error(), "has completed") {
+		t.Fatalf("expected an error resuming a completed upgrade, got %v", err)
+	}
+}
+
+// the scripts of the commands that succeeded before the failure are recorded when the upgrade is resumed
+func TestDbMan_ResumeUpgradeChecksums(t *testing.T) {
+	dm, repo := newTestDbMan(t, "0.0.1")
+	mustRun(t, "deploy")(dm.Deploy(false))
+	// the deploy command of 0.0.3 fails after its alter command has succeeded
+	writeTestReleases(t, repo, "CREATE TABLE t4(id INTEGER)")
+	deploy := filepath.Join(repo, "v3", "d3.sql")
+	if err := os.WriteFile(deploy, []byte("INSERT INTO t3 VALUES(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dm.Cfg.cfg.Set(AppVersion, "0.0.3")
+	if log, err, _ := dm.Upgrade(false, false, ""); err == nil {
+		t.Fatalf("expected the upgrade to fail\n%s", log.String())
+	}
+	if err := os.WriteFile(deploy, []byte("CREATE VIEW v3 AS SELECT * FROM t4"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, "resume")(dm.Upgrade(false, true, ""))
+	result := plugin.NewParameterFromJSON(dm.DbPlugin().GetChecksums())
+	if result.HasError() {
+		t.Fatal(result.Error())
+	}
+	var got []string
+	for _, c := range result.GetChecksums() {
+		got = append(got, fmt.Sprintf("%s %s", c.AppVersion, c.Command))
+	}
+	sort.Strings(got)
+	want := []string{"0.0.1 create-tables", "0.0.1 prepare-1", "0.0.2 alter-2", "0.0.3 alter-3", "0.0.3 deploy-3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected checksums\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+// a dry run writes the merged scripts and version updates it would apply, without changing the database
+func TestDbMan_UpgradeDryRun(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.1")
+	mustRun(t, "deploy")(dm.Deploy(false))
+	dm.Cfg.cfg.Set(AppVersion, "0.0.3")
+	out := mustRun(t, "dry run")(dm.Upgrade(true, false, ""))
+	for _, want := range []string{
+		"? [dry run] release 0.0.1, command 'prepare-1' would run on a connection that is non-transactional, as a user and to the db\n-- script 'p1' (p1.sql)\nSELECT 1\n",
+		"? [dry run] release 0.0.2, command 'alter-2' would run on a connection that is non-transactional, as a user and to the db\n-- script 'a2' (a2.sql)\nCREATE TABLE t2(id INTEGER)\n",
+		"? [dry run] I would update the release version history to application version 0.0.2, database version 2\n",
+		"-- script 'd3' (d3.sql)\nCREATE VIEW v3 AS SELECT * FROM t3\n",
+		"? [dry run] I would update the release version history to application version 0.0.3, database version 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the dry run output does not contain %q\n%s", want, out)
+		}
+	}
+	if v := dbVersion(t, dm); v != "0.0.1" {
+		t.Fatalf("the dry run changed the database version to %s", v)
+	}
+	if progress := plugin.NewParameterFromJSON(dm.DbPlugin().GetProgress()).GetProgress(); progress != nil {
+		t.Fatalf("the dry run recorded the progress %+v", progress)
+	}
+}
+
+// a backup can only be restored over a newer database version if forced
+func TestDbMan_RestoreVersion(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.1")
+	mustRun(t, "deploy")(dm.Deploy(false))
+	log, v1, err, _ := dm.Backup("file")
+	mustRun(t, "backup")(log, err, 0)
+	dm.Cfg.cfg.Set(AppVersion, "0.0.2")
+	mustRun(t, "upgrade")(dm.Upgrade(false, false, ""))
+	// the backup names are unique to the second
+	time.Sleep(time.Second)
+	log, v2, err, _ := dm.Backup("file")
+	mustRun(t, "backup")(log, err, 0)
+	if v1.AppVersion != "0.0.1" || v2.AppVersion != "0.0.2" {
+		t.Fatalf("unexpected backup versions %s and %s", v1.AppVersion, v2.AppVersion)
+	}
+	// the backup of the same version
+	mustRun(t, "restore")(dm.Restore(v2.Name, false))
+	// the backup of an older version
+	if log, err, _ = dm.Restore(v1.Name, false); err == nil || !strings.Contains(err.Error(), "'0.0.2' which is newer than") {
+		t.Fatalf("expected an error restoring an older version, got %v\n%s", err, log.String())
+	}
+	if v := dbVersion(t, dm); v != "0.0.2" {
+		t.Fatalf("the database was restored to %s", v)
+	}
+	out := mustRun(t, "forced restore")(dm.Restore(v1.Name, true))
+	if !strings.Contains(out, "! I am forcing the restore of version '0.0.1' on top of database version '0.0.2'") {
+		t.Fatalf("the forced restore was not reported\n%s", out)
+	}
+	if v := dbVersion(t, dm); v != "0.0.1" {
+		t.Fatalf("expected version 0.0.1 after the forced restore, got %s", v)
+	}
+	// the backup of a newer version
+	mustRun(t, "restore")(dm.Restore(v2.Name, false))
+	// the backup of a version not in the release plan cannot be compared
+	meta := filepath.Join(dm.get(BackupPath), fmt.Sprintf("%s.json", v1.Name))
+	v1.AppVersion = "9.9.9"
+	if err = os.WriteFile(meta, []byte(v1.ToString()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err, _ = dm.Restore(v1.Name, false); err == nil || !strings.Contains(err.Error(), "cannot be compared") {
+		t.Fatalf("expected an error restoring a version not in the release plan, got %v", err)
+	}
+	if _, err, _ = dm.Restore("missing", false); err == nil {
+		t.Fatal("expected an error restoring a missing backup")
+	}
+	// only backups in the backup directory can be restored by name
+	for _, name := range []string{meta, "../" + v2.Name, v2.Name + "/..", "backups/" + v2.Name, `backups\` + v2.Name, ".."} {
+		if _, err, _ = dm.Restore(name, true); err == nil || !strings.Contains(err.Error(), "is not a valid backup name") {
+			t.Errorf("expected an error restoring '%s', got %v", name, err)
+		}
+	}
+	// the path to the metadata file can be used instead
+	mustRun(t, "restore from metadata")(dm.RestoreFrom(filepath.Join(dm.get(BackupPath), fmt.Sprintf("%s.json", v2.Name)), false))
+}
+
+func TestDbMan_QueryParams(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.3")
+	cases := []struct {
+		params map[string]string
+		want   string
+		err    string
+	}{
+		// the input parameters with a default value are optional and the other variables are not parameters
+		{map[string]string{"id": "2"}, "3", ""},
+		{map[string]string{"id": "2", "offset": "5"}, "7", ""},
+		{map[string]string{"offset": "5"}, "", "'id' has not been provided"},
+		{map[string]string{"id": "2", "label": "x", "limit": "1"}, "", "does not expect the parameters 'label,limit', the expected parameters are 'id,offset'"},
+	}
+	for _, c := range cases {
+		table, _, _, err := dm.Query("total", c.params)
+		if len(c.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("query %v: expected error %q, got %v", c.params, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("query %v: %v", c.params, err)
+			continue
+		}
+		if len(table.Rows) != 1 || table.Rows[0][0] != c.want || table.Header[0] != "sum" {
+			t.Errorf("query %v: got %v %v; want %s", c.params, table.Header, table.Rows, c.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitSource a script source read from a git repository at a specific commit
+// the repository is cloned (bare) into a local cache directory and fetched again when the source is created,
+// the git command line must be installed
+type gitSource struct {
+	// the repository url without credentials, used in messages
+	url string
+	// the path to the local bare clone
+	dir string
+	// the resolved commit SHA
+	commit string
+	// the git configuration entries, as key and value pairs, passed to each git command through the environment so that credentials are never
+	// part of the remote url or stored in the configuration of the clone
+	config []string
+}
+
+// true if the uri points to a git repository:
+//   - git+https://, git+http://, git+ssh:// or git+file:// urls
+//   - ssh:// urls or scp like addresses (e.g. git@github.com:org/repo.git)
+//   - urls or paths ending in .git (e.g. a local bare repository)
+func isGitUri(uri string) bool {
+	return strings.HasPrefix(uri, "git+") ||
+		strings.HasPrefix(uri, "ssh://") ||
+		strings.HasPrefix(uri, "git@") ||
+		strings.HasSuffix(uri, ".git")
+}
+
+// creates a source for a git repository
+// uri: the repository uri
+// ref: the branch, tag or commit to read the scripts from, the remote HEAD if empty
+// creds: the credentials to access the repository over http(s) in the format username:password, if any
+// cacheDir: the directory where the repositories are cloned
+// refresh: if true, the repository is cloned again instead of fetching the existing clone
+func newGitSource(uri string, ref string, creds string, cacheDir string, refresh bool) (ScriptSource, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("!!! I cannot find the git command, which is required to read scripts from a git repository\n")
+	}
+	remote := strings.TrimPrefix(uri, "git+")
+	// each repository is cloned in its own directory
+	sum := sha1.Sum([]byte(remote))
+	s := &gitSource{url: uri, dir: filepath.Join(cacheDir, hex.EncodeToString(sum[:]))}
+	// send the credentials to http(s) urls in an authorization header scoped to the repository url
+	if len(creds) > 0 && strings.HasPrefix(remote, "http") {
+		if _, err := url.Parse(remote); err != nil {
+			return nil, errors.New(fmt.Sprintf("!!! invalid git repository url %s: %v\n", uri, err))
+		}
+		auth := base64.StdEncoding.EncodeToString([]byte(creds))
+		s.config = append(s.config, fmt.Sprintf("http.%s.extraHeader", remote), fmt.Sprintf("Authorization: Basic %s", auth))
+	}
+	if refresh {
+		if err := os.RemoveAll(s.dir); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(s.dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(cacheDir, 0755); err != nil {
+			return nil, err
+		}
+		if _, err = s.git("clone", "--bare", "--quiet", remote, s.dir); err != nil {
+			os.RemoveAll(s.dir)
+			return nil, err
+		}
+	} else {
+		// the remote url is set again to remove the credentials stored in the url of clones made by earlier versions
+		if _, err = s.git("-C", s.dir, "remote", "set-url", "origin", remote); err != nil {
+			return nil, err
+		}
+		if _, err = s.git("-C", s.dir, "fetch", "--quiet", "--prune", "--tags", "--force", "origin", "+refs/heads/*:refs/heads/*"); err != nil {
+			return nil, err
+		}
+	}
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+	commit, err := s.git("-C", s.dir, "rev-parse", "--verify", "--quiet", fmt.Sprintf("%s^{commit}", ref))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot find the reference '%s' in git repository %s\n", ref, uri))
+	}
+	s.commit = strings.TrimSpace(string(commit))
+	return s, nil
+}
+
+func (s *gitSource) ReadPlan() ([]byte, error) {
+	return readPlan(s.ReadFile)
+}
+
+func (s *gitSource) ReadManifest(releasePath string) ([]byte, error) {
+	return readManifest(s.ReadFile, releasePath)
+}
+
+func (s *gitSource) ReadFile(p string) ([]byte, error) {
+	content, err := s.git("-C", s.dir, "cat-file", "blob", fmt.Sprintf("%s:%s", s.commit, cleanPath(p)))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot find %s in git repository %s at commit %s\n", p, s.url, s.commit))
+	}
+	return content, nil
+}
+
+func (s *gitSource) List(dir string) ([]string, error) {
+	args := []string{"-C", s.dir, "ls-tree", "-r", "--name-only", s.commit}
+	if p := cleanPath(dir); p != "." {
+		args = append(args, "--", p)
+	}
+	out, err := s.git(args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// Revision returns the commit SHA the scripts are read from
+func (s *gitSource) Revision() string {
+	return s.commit
+}
+
+// runs a git command and returns its standard output
+func (s *gitSource) git(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// never prompt for credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	// git reads configuration entries from GIT_CONFIG_COUNT, GIT_CONFIG_KEY_n and GIT_CONFIG_VALUE_n,
+	// which keeps them out of the command line
+	if len(s.config) > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(s.config)/2))
+		for i := 0; i < len(s.config); i += 2 {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i/2, s.config[i]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i/2, s.config[i+1]))
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		// the name of the git sub command, after the -C option if any
+		name := args[0]
+		if name == "-C" {
+			name = args[2]
+		}
+		// the error output might contain the url with credentials
+		return nil, errors.New(fmt.Sprintf("!!! git %s failed for repository %s: %s\n", name, s.url, redactUrl(strings.TrimSpace(stderr.String()))))
+	}
+	return stdout.Bytes(), nil
+}
+
+// removes credentials from the urls in a message
+func redactUrl(msg string) string {
+	words := strings.Fields(msg)
+	for i, w := range words {
+		if u, err := url.Parse(strings.Trim(w, "'\"")); err == nil && u.User != nil {
+			u.User = nil
+			words[i] = u.String()
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -17,24 +17,31 @@ package core
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
+	"io"
 	"net/http"
 	_ "southwinds.dev/dbman/docs" // documentation needed for swagger
 	"southwinds.dev/dbman/plugin"
 	h "southwinds.dev/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Server struct {
 	*h.Server
-	cfg *Config
+	cfg  *Config
+	jobs *Jobs
 }
 
 func NewServer(cfg *Config) *Server {
 	s := &Server{}
 	s.Server = h.New("dbman", "")
 	s.cfg = cfg
+	s.jobs = NewJobs(50)
 	return s
 }
 
@@ -123,7 +130,7 @@ func (s *Server) queryHandler(writer http.ResponseWriter, request *http.Request)
 	if len(queryParams) > 0 {
 		parts := strings.Split(queryParams[0], ",")
 		for _, part := range parts {
-			subPart := strings.Split(part, "=")
+			subPart := strings.SplitN(part, "=", 2)
 			if len(subPart) != 2 {
 				fmt.Printf("I cannot break down query parameter '%s': format should be 'key=value'\n", subPart)
 				return
@@ -171,70 +178,315 @@ func (s *Server) queryHandler(writer http.ResponseWriter, request *http.Request)
 	}
 }
 
-// @Summary Creates a new database
-// @Description When the database does not already exists, this operation executes the manifest commands required to create the new database.
+// @Summary Gets the command execution history.
+// @Description Lists the release commands executed on the database, most recent first, including the scripts run, timings, status and who ran them.
 // @Tags Database
-// @Produce  plain
-// @Success 200 {string} execution logs
+// @Produce  application/json, application/yaml, application/xml, text/csv
+// @Param appVersion query string false "only returns commands of the specified application version"
+// @Param command query string false "only returns executions of the specified command"
+// @Param status query string false "only returns executions with the specified status, either success or failure"
+// @Param since query string false "only returns executions started after a duration ago (e.g. 24h) or a date (e.g. 2006-01-02)"
+// @Param limit query int false "the maximum number of executions to return, defaults to 50, 0 for all"
+// @Success 200 {Table} a generic table
+// @Failure 400 {string} error message
 // @Failure 500 {string} error message
-// @Router /db/create [post]
-func (s *Server) createHandler(w http.ResponseWriter, r *http.Request) {
-	// deploy the schema and functions
-	output, err, elapsed := DM.Create()
-	w.Write([]byte(output.String()))
-	// return an error if failed
+// @Router /db/history [get]
+func (s *Server) historyHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 50
+	if len(q.Get("limit")) > 0 {
+		l, err := strconv.Atoi(q.Get("limit"))
+		if err != nil {
+			h.Err(w, http.StatusBadRequest, fmt.Sprintf("!!! invalid limit '%s': %v\n", q.Get("limit"), err))
+			return
+		}
+		limit = l
+	}
+	filter, err := ParseHistoryFilter(q.Get("appVersion"), q.Get("command"), q.Get("status"), q.Get("since"), limit)
+	if err != nil {
+		h.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	table, err := DM.History(filter)
 	if err != nil {
 		h.Err(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.Write(w, r, *table)
+}
+
+// @Summary Validates the release plan and manifests.
+// @Description Checks every release in the plan for unresolved command references, duplicate names, missing files, unresolved or unused merge variables, unknown database providers and db versions going backwards.
+// @Tags Release
+// @Produce  application/json, application/yaml
+// @Success 200 {object} ValidationReport "the validation report, the releases are valid if it has no errors"
+// @Failure 500 {string} error message
+// @Router /release/validate [get]
+func (s *Server) validateHandler(w http.ResponseWriter, r *http.Request) {
+	report, err, _ := DM.Validate()
+	if err != nil {
+		h.Err(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.Write(w, r, report)
+}
+
+// @Summary Gets the recent jobs.
+// @Description Lists the jobs started by the http service, most recent first, including their status and timings.
+// @Tags Jobs
+// @Produce  application/json, application/yaml
+// @Success 200 {array} Job
+// @Router /jobs [get]
+func (s *Server) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Write(w, r, s.jobs.List())
+}
+
+// @Summary Gets a job.
+// @Description Gets the status, timings and log of a job started by the http service.
+// @Tags Jobs
+// @Produce  application/json, application/yaml
+// @Param id path string true "the job identifier"
+// @Success 200 {object} Job
+// @Failure 404 {string} error message
+// @Router /jobs/{id} [get]
+func (s *Server) jobHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	job := s.jobs.Get(id)
+	if job == nil {
+		h.Err(w, http.StatusNotFound, fmt.Sprintf("!!! I cannot find job '%s'\n", id))
+		return
+	}
+	h.Write(w, r, job)
+}
+
+// @Summary Streams the log of a job.
+// @Description Streams the log of a job as server-sent events while it is written: a 'log' event for each chunk of the log and an 'end' event with the job status when the job completes.
+// @Tags Jobs
+// @Produce  text/event-stream
+// @Param id path string true "the job identifier"
+// @Success 200 {string} server-sent events
+// @Failure 404 {string} error message
+// @Router /jobs/{id}/stream [get]
+func (s *Server) jobStreamHandler(w http.ResponseWriter, r *http.Request) {
+	s.streamJob(w, r, mux.Vars(r)["id"])
+}
+
+// @Summary Streams the log of the last upgrade.
+// @Description Streams the log of the running or last upgrade job as server-sent events while it is written: a 'log' event for each chunk of the log and an 'end' event with the job status when the job completes.
+// @Tags Database
+// @Produce  text/event-stream
+// @Success 200 {string} server-sent events
+// @Failure 404 {string} error message
+// @Router /db/upgrade/stream [get]
+func (s *Server) upgradeStreamHandler(w http.ResponseWriter, r *http.Request) {
+	job := s.jobs.Latest("upgrade")
+	if job == nil {
+		h.Err(w, http.StatusNotFound, "!!! I cannot find any upgrade job\n")
+		return
+	}
+	s.streamJob(w, r, job.Id)
+}
+
+// streams the log of a job as server-sent events until the job completes or the client disconnects
+func (s *Server) streamJob(w http.ResponseWriter, r *http.Request, id string) {
+	if s.jobs.Get(id) == nil {
+		h.Err(w, http.StatusNotFound, fmt.Sprintf("!!! I cannot find job '%s'\n", id))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.Err(w, http.StatusInternalServerError, "!!! I cannot stream the job log as the connection does not support it\n")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// prevents reverse proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	_, err := s.jobs.Follow(r.Context(), id, func(chunk string) error {
+		event := bytes.Buffer{}
+		event.WriteString("event: log\n")
+		for _, line := range strings.Split(strings.TrimSuffix(chunk, "\n"), "\n") {
+			event.WriteString(fmt.Sprintf("data: %s\n", line))
+		}
+		event.WriteString("\n")
+		if _, err := w.Write(event.Bytes()); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	// the client has gone away
+	if err != nil {
+		return
+	}
+	// sends the job outcome without the log already streamed
+	job := s.jobs.Get(id)
+	job.Log = ""
+	outcome, _ := json.Marshal(job)
+	w.Write([]byte(fmt.Sprintf("event: end\ndata: %s\n\n", outcome)))
+	flusher.Flush()
+}
+
+// runs an operation as a job
+// by default the job runs in the background and the response is the started job with a 202 status
+// if the wait query parameter is true, the response is the operation log once the job has completed
+func (s *Server) runJob(w http.ResponseWriter, r *http.Request, action string, mutating bool, operation JobOperation) {
+	wait, _ := strconv.ParseBool(r.URL.Query().Get("wait"))
+	var (
+		job *Job
+		err error
+	)
+	if wait {
+		job, err = s.jobs.Run(action, mutating, operation)
 	} else {
-		_, err = w.Write([]byte(fmt.Sprintf("? I have completed the action in %v\n", elapsed)))
+		job, err = s.jobs.Start(action, mutating, operation)
+	}
+	if err != nil {
+		h.Err(w, http.StatusConflict, fmt.Sprintf("!!! I cannot %s the database: %v\n", action, err))
+		return
+	}
+	if !wait {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%s", job.Id))
+		w.WriteHeader(http.StatusAccepted)
+		err = json.NewEncoder(w).Encode(job)
+		if err != nil {
+			fmt.Printf("!!! I failed to write job to response: %v", err)
+		}
+		return
+	}
+	w.Write([]byte(job.Log))
+	// return an error if failed
+	if len(job.Error) > 0 {
+		h.Err(w, http.StatusInternalServerError, job.Error)
+	} else {
+		_, err = w.Write([]byte(fmt.Sprintf("? I have completed the action in %v\n", job.Elapsed)))
 		if err != nil {
 			fmt.Printf("!!! I failed to write error to response: %v", err)
 		}
 	}
 }
 
+// @Summary Creates a new database
+// @Description When the database does not already exists, this operation executes the manifest commands required to create the new database.
+// @Tags Database
+// @Produce  application/json, text/plain
+// @Param dryRun query bool false "if true, returns the merged scripts that would be executed without running them"
+// @Param wait query bool false "if true, waits for the operation to complete and returns its execution logs"
+// @Success 202 {object} Job "the started job"
+// @Success 200 {string} execution logs
+// @Failure 409 {string} error message
+// @Failure 500 {string} error message
+// @Router /db/create [post]
+func (s *Server) createHandler(w http.ResponseWriter, r *http.Request) {
+	// create the database
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	s.runJob(w, r, "create", !dryRun, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		return DM.WithOutput(out).Create(dryRun)
+	})
+}
+
 // @Summary Deploys the schema and objects in an empty database.
 // @Description When the database is empty, this operation executes the manifest commands required to deploy the  database schema and objects.
 // @Tags Database
-// @Produce  plain
+// @Produce  application/json, text/plain
+// @Param dryRun query bool false "if true, returns the merged scripts that would be executed without running them"
+// @Param wait query bool false "if true, waits for the operation to complete and returns its execution logs"
+// @Success 202 {object} Job "the started job"
 // @Success 200 {string} execution logs
+// @Failure 409 {string} error message
 // @Failure 500 {string} error message
 // @Router /db/deploy [post]
 func (s *Server) deployHandler(w http.ResponseWriter, r *http.Request) {
 	// deploy the schema and functions
-	output, err, elapsed := DM.Deploy()
-	w.Write([]byte(output.String()))
-	// return an error if failed
-	if err != nil {
-		h.Err(w, http.StatusInternalServerError, err.Error())
-	} else {
-		_, err = w.Write([]byte(fmt.Sprintf("? I have completed the action in %v\n", elapsed)))
-		if err != nil {
-			fmt.Printf("!!! I failed to write error to response: %v", err)
-		}
-	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	s.runJob(w, r, "deploy", !dryRun, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		return DM.WithOutput(out).Deploy(dryRun)
+	})
 }
 
 // @Summary Upgrade a database to a specific version.
 // @Description This operation executes the manifest commands required to upgrade an existing database schema and objects to a new version. The target version is defined by DbMan's configuration value "AppVersion". This operation support rolling upgrades.
 // @Tags Database
-// @Produce  plain
+// @Produce  application/json, text/plain
+// @Param dryRun query bool false "if true, returns the merged scripts that would be executed without running them"
+// @Param resume query bool false "if true, continues a failed upgrade from the command that failed"
+// @Param fromCommand query string false "continues a failed upgrade from the specified command"
+// @Param wait query bool false "if true, waits for the operation to complete and returns its execution logs"
+// @Success 202 {object} Job "the started job"
 // @Success 200 {string} execution logs
+// @Failure 409 {string} error message
 // @Failure 500 {string} error message
 // @Router /db/upgrade [post]
 func (s *Server) upgradeHandler(w http.ResponseWriter, r *http.Request) {
-	// deploy the schema and functions
-	output, err, elapsed := DM.Upgrade()
-	w.Write([]byte(output.String()))
-	// return an error if failed
-	if err != nil {
-		h.Err(w, http.StatusInternalServerError, err.Error())
-	} else {
-		_, err = w.Write([]byte(fmt.Sprintf("? I have completed the action in %v\n", elapsed)))
-		if err != nil {
-			fmt.Printf("!!! I failed to write error to response: %v", err)
-		}
-	}
+	// upgrade the schema and functions
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	resume, _ := strconv.ParseBool(r.URL.Query().Get("resume"))
+	fromCommand := r.URL.Query().Get("fromCommand")
+	s.runJob(w, r, "upgrade", !dryRun, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		return DM.WithOutput(out).Upgrade(dryRun, resume, fromCommand)
+	})
+}
+
+// @Summary Downgrade a database to a previous version.
+// @Description This operation executes the manifest commands required to roll back an existing database schema and objects to a previous version. The target version is defined by DbMan's configuration value "AppVersion".
+// @Tags Database
+// @Produce  application/json, text/plain
+// @Param wait query bool false "if true, waits for the operation to complete and returns its execution logs"
+// @Success 202 {object} Job "the started job"
+// @Success 200 {string} execution logs
+// @Failure 409 {string} error message
+// @Failure 500 {string} error message
+// @Router /db/downgrade [post]
+func (s *Server) downgradeHandler(w http.ResponseWriter, r *http.Request) {
+	// roll back the schema and functions
+	s.runJob(w, r, "downgrade", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		return DM.WithOutput(out).Downgrade()
+	})
+}
+
+// @Summary Takes a backup of the database.
+// @Description Takes a logical backup of the database and writes it with its metadata (including the application and database versions) to DbMan's backup directory.
+// @Tags Database
+// @Produce  application/json, text/plain
+// @Param format query string false "the format of the backup, either file (default) or directory"
+// @Param wait query bool false "if true, waits for the operation to complete and returns its execution logs"
+// @Success 202 {object} Job "the started job"
+// @Success 200 {string} execution logs
+// @Failure 409 {string} error message
+// @Failure 500 {string} error message
+// @Router /db/backup [post]
+func (s *Server) backupHandler(w http.ResponseWriter, r *http.Request) {
+	// take the backup
+	format := r.URL.Query().Get("format")
+	s.runJob(w, r, "backup", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		log, _, err, elapsed := DM.WithOutput(out).Backup(format)
+		return log, err, elapsed
+	})
+}
+
+// @Summary Restores the database from a backup.
+// @Description Restores a backup taken by DbMan. The restore is refused if the database has a version newer than the backup, unless it is forced.
+// @Tags Database
+// @Produce  application/json, text/plain
+// @Param name path string true "the name of the backup to restore, in the backup directory"
+// @Param force query bool false "restores the backup even if the database has a newer version"
+// @Param wait query bool false "if true, waits for the operation to complete and returns its execution logs"
+// @Success 202 {object} Job "the started job"
+// @Success 200 {string} execution logs
+// @Failure 409 {string} error message
+// @Failure 500 {string} error message
+// @Router /db/restore/{name} [post]
+func (s *Server) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	// restore the backup
+	s.runJob(w, r, "restore", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		return DM.WithOutput(out).Restore(name, force)
+	})
 }
 
 // @Summary Validates the current DbMan's configuration.
@@ -0,0 +1,217 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrJobRunning is returned when a mutating job is requested while another one is running
+var ErrJobRunning = errors.New("a job changing the database is already running")
+
+// Job an operation run asynchronously on behalf of an http request
+type Job struct {
+	// the unique identifier of the job
+	Id string `json:"id"`
+	// the name of the operation run by the job (e.g. upgrade)
+	Action string `json:"action"`
+	// true if the operation changes the database
+	Mutating bool `json:"mutating"`
+	// the status of the job: running, succeeded or failed
+	Status string `json:"status"`
+	// the time the job started
+	Started time.Time `json:"started"`
+	// the time the job ended
+	Ended *time.Time `json:"ended,omitempty"`
+	// the execution time of the operation
+	Elapsed string `json:"elapsed,omitempty"`
+	// the operation log, written so far if the job is running
+	Log string `json:"log"`
+	// the error returned by the operation if it failed
+	Error string `json:"error,omitempty"`
+	// the operation log as it is written
+	sink *LogSink
+}
+
+// JobOperation an operation run by a job
+// out: the writer the operation should write its log to as it is produced
+type JobOperation func(out io.Writer) (log bytes.Buffer, err error, elapsed time.Duration)
+
+// Jobs keeps track of the jobs started by the http server
+// only the most recent jobs are kept in memory
+type Jobs struct {
+	lock sync.RWMutex
+	// the jobs by id
+	jobs map[string]*Job
+	// the job ids in the order they were started
+	order []string
+	// the id of the mutating job running, if any
+	running string
+	// the maximum number of jobs to keep
+	max int
+}
+
+// NewJobs creates a job tracker keeping up to max jobs
+func NewJobs(max int) *Jobs {
+	return &Jobs{
+		jobs: make(map[string]*Job),
+		max:  max,
+	}
+}
+
+// Start runs the operation in the background and returns the started job
+// returns ErrJobRunning if the job is mutating and another mutating job is running
+func (j *Jobs) Start(action string, mutating bool, operation JobOperation) (*Job, error) {
+	job, err := j.add(action, mutating)
+	if err != nil {
+		return nil, err
+	}
+	go j.run(job.Id, operation)
+	return job, nil
+}
+
+// Run runs the operation and waits for it to finish, returning the completed job
+// returns ErrJobRunning if the job is mutating and another mutating job is running
+func (j *Jobs) Run(action string, mutating bool, operation JobOperation) (*Job, error) {
+	job, err := j.add(action, mutating)
+	if err != nil {
+		return nil, err
+	}
+	j.run(job.Id, operation)
+	return j.Get(job.Id), nil
+}
+
+// Get returns a copy of the job with the specified id or nil if the job does not exist
+func (j *Jobs) Get(id string) *Job {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	job, found := j.jobs[id]
+	if !found {
+		return nil
+	}
+	return job.copy()
+}
+
+// Latest returns a copy of the most recent job for the specified action or nil if there is none
+func (j *Jobs) Latest(action string) *Job {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	for i := len(j.order) - 1; i >= 0; i-- {
+		if job := j.jobs[j.order[i]]; job.Action == action {
+			return job.copy()
+		}
+	}
+	return nil
+}
+
+// Follow calls fn with the log of the job with the specified id as it is written until the job completes
+// returns false if the job does not exist
+func (j *Jobs) Follow(ctx context.Context, id string, fn func(chunk string) error) (bool, error) {
+	j.lock.RLock()
+	job, found := j.jobs[id]
+	j.lock.RUnlock()
+	if !found {
+		return false, nil
+	}
+	return true, job.sink.Follow(ctx, fn)
+}
+
+// List returns a copy of the jobs kept, most recent first
+func (j *Jobs) List() []Job {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	list := make([]Job, 0, len(j.order))
+	for i := len(j.order) - 1; i >= 0; i-- {
+		list = append(list, *j.jobs[j.order[i]].copy())
+	}
+	return list
+}
+
+// registers a new running job
+func (j *Jobs) add(action string, mutating bool) (*Job, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if mutating && len(j.running) > 0 {
+		return nil, fmt.Errorf("%w: job %s (%s)", ErrJobRunning, j.running, j.jobs[j.running].Action)
+	}
+	job := &Job{
+		Id:       newJobId(),
+		Action:   action,
+		Mutating: mutating,
+		Status:   "running",
+		Started:  time.Now().UTC(),
+		sink:     NewLogSink(),
+	}
+	j.jobs[job.Id] = job
+	j.order = append(j.order, job.Id)
+	if mutating {
+		j.running = job.Id
+	}
+	// discard the oldest completed jobs
+	for i := 0; len(j.order) > j.max && i < len(j.order); {
+		if j.jobs[j.order[i]].Status == "running" {
+			i++
+			continue
+		}
+		delete(j.jobs, j.order[i])
+		j.order = append(j.order[:i], j.order[i+1:]...)
+	}
+	return job.copy(), nil
+}
+
+// runs the operation of a job and records its outcome
+func (j *Jobs) run(id string, operation JobOperation) {
+	j.lock.RLock()
+	sink := j.jobs[id].sink
+	j.lock.RUnlock()
+	log, err, elapsed := operation(sink)
+	j.lock.Lock()
+	job := j.jobs[id]
+	ended := time.Now().UTC()
+	job.Ended = &ended
+	job.Elapsed = elapsed.String()
+	job.Log = log.String()
+	job.Status = "succeeded"
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+	}
+	if j.running == id {
+		j.running = ""
+	}
+	j.lock.Unlock()
+	// the job outcome is recorded before the followers are released
+	sink.Close()
+}
+
+// returns a copy of the job including the log written so far, must be called holding the lock
+func (job *Job) copy() *Job {
+	copied := *job
+	if copied.Status == "running" {
+		copied.Log = job.sink.String()
+	}
+	return &copied
+}
+
+// creates a random job identifier
+func newJobId() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
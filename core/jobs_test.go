@@ -0,0 +1,102 @@
+/*
+DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+Contributors to this project, hereby assign copyright in this code to the project,
+to be licensed under the same terms as the rest of the code.
+*/
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestJobs_OneMutatingJobAtATime(t *testing.T) {
+	jobs := NewJobs(10)
+	release := make(chan bool)
+	job, err := jobs.Start("upgrade", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		<-release
+		log := bytes.Buffer{}
+		log.WriteString("? done\n")
+		return log, nil, time.Second
+	})
+	if err != nil {
+		t.Fatalf("cannot start job: %v", err)
+	}
+	// a second mutating job is rejected while the first one runs
+	_, err = jobs.Start("deploy", true, nil)
+	if !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("expected ErrJobRunning, got %v", err)
+	}
+	// a non mutating job can run at the same time
+	dryRun, err := jobs.Run("deploy", false, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		return bytes.Buffer{}, errors.New("failed"), 0
+	})
+	if err != nil || dryRun.Status != "failed" || dryRun.Error != "failed" {
+		t.Fatalf("unexpected non mutating job outcome: %+v, %v", dryRun, err)
+	}
+	close(release)
+	for i := 0; i < 100 && jobs.Get(job.Id).Status == "running"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	completed := jobs.Get(job.Id)
+	if completed.Status != "succeeded" || completed.Log != "? done\n" || completed.Ended == nil {
+		t.Fatalf("unexpected job outcome: %+v", completed)
+	}
+	// once completed, another mutating job can start
+	if _, err = jobs.Run("deploy", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) { return bytes.Buffer{}, nil, 0 }); err != nil {
+		t.Fatalf("cannot start job after the previous one completed: %v", err)
+	}
+	list := jobs.List()
+	if len(list) != 3 || list[0].Action != "deploy" || list[2].Id != job.Id {
+		t.Fatalf("unexpected job list: %+v", list)
+	}
+}
+
+func TestJobs_KeepsMostRecent(t *testing.T) {
+	jobs := NewJobs(2)
+	var last *Job
+	for i := 0; i < 5; i++ {
+		last, _ = jobs.Run("create", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) { return bytes.Buffer{}, nil, 0 })
+	}
+	list := jobs.List()
+	if len(list) != 2 || list[0].Id != last.Id {
+		t.Fatalf("expected the two most recent jobs, got %+v", list)
+	}
+}
+
+func TestJobs_FollowLog(t *testing.T) {
+	jobs := NewJobs(10)
+	release := make(chan bool)
+	job, _ := jobs.Start("upgrade", true, func(out io.Writer) (bytes.Buffer, error, time.Duration) {
+		log := bytes.Buffer{}
+		dm := (&DbMan{}).WithOutput(out)
+		l := dm.newLog(&log)
+		l.WriteString("? first\n")
+		<-release
+		l.WriteString("? second\n")
+		return log, nil, 0
+	})
+	followed := bytes.Buffer{}
+	found, err := jobs.Follow(context.Background(), job.Id, func(chunk string) error {
+		followed.WriteString(chunk)
+		// the log written so far is available while the job runs
+		if chunk == "? first\n" {
+			if running := jobs.Get(job.Id); running.Log != "? first\n" {
+				t.Errorf("unexpected log so far: %q", running.Log)
+			}
+			close(release)
+		}
+		return nil
+	})
+	if !found || err != nil {
+		t.Fatalf("cannot follow job: %v", err)
+	}
+	if followed.String() != "? first\n? second\n" || jobs.Get(job.Id).Status != "succeeded" {
+		t.Fatalf("unexpected followed log %q", followed.String())
+	}
+}
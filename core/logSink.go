@@ -0,0 +1,100 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// the log of an operation, written to the buffer returned by the operation and to an optional output as it is produced
+type opLog struct {
+	log    *bytes.Buffer
+	output io.Writer
+}
+
+func (l *opLog) WriteString(s string) (int, error) {
+	if l.output != nil {
+		io.WriteString(l.output, s)
+	}
+	return l.log.WriteString(s)
+}
+
+// LogSink an operation log that can be followed by any number of readers while it is being written
+type LogSink struct {
+	lock sync.Mutex
+	log  bytes.Buffer
+	// closed and replaced every time the log changes, to wake up the followers
+	changed chan struct{}
+	closed  bool
+}
+
+// NewLogSink creates an empty log sink
+func NewLogSink() *LogSink {
+	return &LogSink{changed: make(chan struct{})}
+}
+
+// Write appends to the log and notifies the followers
+func (s *LogSink) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, err := s.log.Write(p)
+	s.notify()
+	return n, err
+}
+
+// String returns the log written so far
+func (s *LogSink) String() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.log.String()
+}
+
+// Close marks the end of the log, the followers return once they have read it all
+func (s *LogSink) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.notify()
+	}
+}
+
+// Follow calls fn with the log written so far and then with every new chunk as it is written
+// it returns when the sink is closed, the context is done or fn returns an error
+func (s *LogSink) Follow(ctx context.Context, fn func(chunk string) error) error {
+	offset := 0
+	for {
+		s.lock.Lock()
+		chunk := string(s.log.Bytes()[offset:])
+		offset += len(chunk)
+		changed, closed := s.changed, s.closed
+		s.lock.Unlock()
+		if len(chunk) > 0 {
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		}
+		if closed {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// wakes up the followers, must be called holding the lock
+func (s *LogSink) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,347 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// scripts are merged using Go templates (https://pkg.go.dev/text/template)
+// each merge variable is available as a function with the variable name, so that {{name}} is replaced by its value
+// and can be used in conditions and loops, e.g.:
+//
+//	{{if eq env "prod"}}ALTER SYSTEM SET log_statement = 'ddl';{{end}}
+//	{{range split roles ","}}GRANT SELECT ON ALL TABLES IN SCHEMA public TO {{ident .}};{{end}}
+//
+// the variables are also available as fields of the template data, i.e. {{.name}} or {{index . "name"}}
+//
+// scripts containing double braces that are not placeholders (e.g. PostgreSQL array literals such as '{{1,2},{3,4}}')
+// cannot be parsed as templates, so the manifest, a script or a query can set its merge mode to replace instead
+
+// the modes of merging the variables with a script
+const (
+	// the script is parsed as a Go template (the default)
+	mergeTemplate = "template"
+	// only the {{name}} placeholders of the variables are replaced, the rest of the script is left as it is
+	mergeReplace = "replace"
+)
+
+// the functions available to the scripts in addition to the Go template built-in functions
+var mergeFuncs = template.FuncMap{
+	// splits a value into a list, e.g. {{range split roles ","}}
+	"split": func(s string, sep string) []string {
+		var items []string
+		for _, item := range strings.Split(s, sep) {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+		return items
+	},
+	// joins a list into a value
+	"join": func(items []string, sep string) string { return strings.Join(items, sep) },
+	// returns the value or the default value if it is empty, e.g. {{default "public" schema}}
+	"default": func(def string, value string) string {
+		if len(value) == 0 {
+			return def
+		}
+		return value
+	},
+	// quotes a value as an SQL string literal, e.g. {{quote comment}} for 'it''s'
+	"quote": func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" },
+	// quotes a value as an SQL identifier, e.g. {{ident role}} for "my role"
+	"ident":     func(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` },
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"trim":      strings.TrimSpace,
+	"replace":   func(s string, old string, new string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+}
+
+// variable names that can be used as template functions
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// returns the mode of merging the variables with a script: the mode of the script if specified, otherwise the mode of the manifest
+// name: the name of the script file, used in error messages
+func mergeMode(name string, manifestMode string, scriptMode string) (string, error) {
+	mode := scriptMode
+	if len(mode) == 0 {
+		mode = manifestMode
+	}
+	switch strings.ToLower(mode) {
+	case "", mergeTemplate:
+		return mergeTemplate, nil
+	case mergeReplace:
+		return mergeReplace, nil
+	}
+	return "", errors.New(fmt.Sprintf("!!! the merge mode '%s' of script %s is not valid, valid modes are template and replace\n", mode, name))
+}
+
+// merges the values of the variables with a script
+// name: the name of the script file, used in error messages
+// values: the values of the variables by name
+func mergeScript(name string, script string, values map[string]string) (string, error) {
+	merged, _, err := bindScript(name, script, values, nil)
+	return merged, err
+}
+
+// merges the values of the variables with a script, replacing the bound variables with query parameters
+// the bound variables are merged as $1, $2, ... in the order they are first used, so their values are never part of the script
+// for compatibility with scripts written for string merging, quotes around their placeholders (i.e. '{{name}}') are removed
+// a parameter cannot be part of a string literal or a template expression, so a bound variable used inside quotes
+// (e.g. LIKE '%{{name}}%') or in an action other than {{name}} (e.g. {{if eq name "x"}}) fails with an error;
+// strings are built by concatenating the parameter instead, e.g. LIKE '%' || {{name}} || '%' (CONCAT('%', {{name}}, '%') in MySQL)
+// bound: the values of the variables to bind by name
+// returns the merged script and the values of its parameters in order
+func bindScript(name string, script string, values map[string]string, bound map[string]QueryArg) (string, []QueryArg, error) {
+	var args []QueryArg
+	position := make(map[string]int)
+	// returns the parameter for a bound variable, adding its value to the arguments the first time it is used
+	param := func(varName string) string {
+		if _, found := position[varName]; !found {
+			args = append(args, bound[varName])
+			position[varName] = len(args)
+		}
+		return fmt.Sprintf("$%d", position[varName])
+	}
+	for varName := range bound {
+		placeholder := fmt.Sprintf("{{%s}}", varName)
+		script = strings.ReplaceAll(script, fmt.Sprintf("'%s'", placeholder), placeholder)
+	}
+	if err := checkBoundVars(name, script, bound); err != nil {
+		return "", nil, err
+	}
+	funcs := template.FuncMap{}
+	for varName := range bound {
+		placeholder := fmt.Sprintf("{{%s}}", varName)
+		if identifierRegex.MatchString(varName) {
+			n := varName
+			funcs[varName] = func() string { return param(n) }
+		} else if strings.Contains(script, placeholder) {
+			script = strings.ReplaceAll(script, placeholder, param(varName))
+		}
+	}
+	t, err := parseScript(name, script, values, funcs)
+	if err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, values); err != nil {
+		return "", nil, errors.New(fmt.Sprintf("!!! I cannot merge the variables of script %s: %v\n", name, err))
+	}
+	return buf.String(), args, nil
+}
+
+// merges the values of the variables with a script without parsing it as a template
+// only the {{name}} placeholders of the variables are replaced, so other double braces in the script are left as they are
+// the bound variables are replaced with query parameters as in bindScript
+// returns the merged script and the values of its parameters in order
+func replaceScript(name string, script string, values map[string]string, bound map[string]QueryArg) (string, []QueryArg, error) {
+	for varName := range bound {
+		placeholder := fmt.Sprintf("{{%s}}", varName)
+		script = strings.ReplaceAll(script, fmt.Sprintf("'%s'", placeholder), placeholder)
+	}
+	if err := checkBoundVars(name, script, bound); err != nil {
+		return "", nil, err
+	}
+	// the bound variables are merged as $1, $2, ... in the order they are first used
+	var used []string
+	for varName := range bound {
+		if strings.Contains(script, fmt.Sprintf("{{%s}}", varName)) {
+			used = append(used, varName)
+		}
+	}
+	sort.Slice(used, func(i, j int) bool {
+		return strings.Index(script, fmt.Sprintf("{{%s}}", used[i])) < strings.Index(script, fmt.Sprintf("{{%s}}", used[j]))
+	})
+	var args []QueryArg
+	for _, varName := range used {
+		args = append(args, bound[varName])
+		script = strings.ReplaceAll(script, fmt.Sprintf("{{%s}}", varName), fmt.Sprintf("$%d", len(args)))
+	}
+	for varName, value := range values {
+		script = strings.ReplaceAll(script, fmt.Sprintf("{{%s}}", varName), value)
+	}
+	return script, args, nil
+}
+
+// rejects the bound variables used where they cannot be replaced by a query parameter, as they would be merged as $1, $2, ...
+// i.e. inside quoted strings or identifiers and in template expressions; comments are not checked
+func checkBoundVars(name string, script string, bound map[string]QueryArg) error {
+	if len(bound) == 0 {
+		return nil
+	}
+	var (
+		quote byte
+		// the end of the comment being read, if any: a new line or */
+		comment string
+	)
+	for i := 0; i < len(script); i++ {
+		switch {
+		case comment == "\n":
+			if script[i] == '\n' {
+				comment = ""
+			}
+		case comment == "*/":
+			if strings.HasPrefix(script[i:], "*/") {
+				comment = ""
+				i++
+			}
+		case strings.HasPrefix(script[i:], "{{"):
+			end := strings.Index(script[i:], "}}")
+			// unterminated actions are reported by the template parser
+			if end < 0 {
+				return nil
+			}
+			action := script[i+2 : i+end]
+			i += end + 1
+			varName, expression := boundVar(action, bound)
+			switch {
+			case len(varName) == 0:
+			case quote != 0:
+				return errors.New(fmt.Sprintf("!!! I cannot bind the variable '%s' in script %s as it is inside a quoted string or identifier\n"+
+					"Concatenate the parameter with the string instead, e.g. '%%' || {{%s}} || '%%'\n", varName, name, varName))
+			case expression:
+				return errors.New(fmt.Sprintf("!!! I cannot bind the variable '%s' in script %s as it is used in the template expression {{%s}}, "+
+					"bound variables can only be used as {{%s}}\n", varName, name, action, varName))
+			}
+		case quote != 0:
+			// doubled quotes end and start the quoted string again
+			if script[i] == quote {
+				quote = 0
+			}
+		case script[i] == '\'' || script[i] == '"':
+			quote = script[i]
+		case strings.HasPrefix(script[i:], "--"):
+			comment = "\n"
+		case strings.HasPrefix(script[i:], "/*"):
+			comment = "*/"
+			i++
+		}
+	}
+	return nil
+}
+
+// the identifiers in a template action, outside its string constants
+var actionIdentRegex = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|` + "`[^`]*`" + `|[A-Za-z_][A-Za-z0-9_]*`)
+
+// returns the bound variable referred to by a template action, if any
+// expression: true if the action is not just the placeholder of the variable (e.g. {{if eq name "x"}})
+func boundVar(action string, bound map[string]QueryArg) (varName string, expression bool) {
+	// the placeholder with optional trim markers, e.g. {{- name -}}
+	trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(action, "-"), "-"))
+	if _, found := bound[trimmed]; found {
+		return trimmed, false
+	}
+	for _, ident := range actionIdentRegex.FindAllString(action, -1) {
+		if _, found := bound[ident]; found && identifierRegex.MatchString(ident) {
+			return ident, true
+		}
+	}
+	return "", false
+}
+
+// parses a script as a template where the passed-in variables are available as functions
+// placeholders not matching any variable or function fail with an error naming the script and line
+// funcs: additional functions available to the script (e.g. the bound variables)
+func parseScript(name string, script string, values map[string]string, funcs template.FuncMap) (*template.Template, error) {
+	all := template.FuncMap{}
+	for k, v := range mergeFuncs {
+		all[k] = v
+	}
+	for k, v := range funcs {
+		all[k] = v
+	}
+	for varName, value := range values {
+		if identifierRegex.MatchString(varName) {
+			v := value
+			all[varName] = func() string { return v }
+		} else {
+			// names that are not valid identifiers (e.g. db-name) are merged as plain text placeholders
+			script = strings.ReplaceAll(script, fmt.Sprintf("{{%s}}", varName), value)
+		}
+	}
+	t, err := template.New(name).Funcs(all).Option("missingkey=error").Parse(script)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot merge the variables of script %s: %v\n", name, unresolvedPlaceholder(err)))
+	}
+	return t, nil
+}
+
+// rewords the template error for placeholders not matching any variable
+func unresolvedPlaceholder(err error) string {
+	// e.g. template: create.sql:12: function "schema" not defined
+	msg := err.Error()
+	if i := strings.Index(msg, "function \""); i > 0 && strings.HasSuffix(msg, "not defined") {
+		placeholder := strings.TrimSuffix(strings.TrimPrefix(msg[i:], "function \""), "\" not defined")
+		return fmt.Sprintf("%s: the placeholder {{%s}} does not match any variable of the script", strings.TrimSuffix(msg[:i], ": "), placeholder)
+	}
+	return msg
+}
+
+// returns the names of the functions and fields used by a parsed script
+func scriptIdentifiers(t *template.Template) map[string]bool {
+	names := make(map[string]bool)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IdentifierNode:
+			names[n.Ident] = true
+		case *parse.FieldNode:
+			names[n.Ident[0]] = true
+		}
+	}
+	if t.Tree != nil {
+		walk(t.Tree.Root)
+	}
+	return names
+}
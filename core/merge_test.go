@@ -0,0 +1,182 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"testing"
+)
+
+func TestMergeScript(t *testing.T) {
+	values := map[string]string{"db": "interlink", "env": "prod", "roles": "reader, writer", "db-owner": "admin"}
+	cases := []struct {
+		script, want string
+	}{
+		{"CREATE DATABASE {{db}} OWNER {{db-owner}};", "CREATE DATABASE interlink OWNER admin;"},
+		{"{{if eq env \"prod\"}}SET log=1;{{else}}SET log=0;{{end}}", "SET log=1;"},
+		{"{{range split roles \",\"}}GRANT SELECT TO {{ident .}};{{end}}", `GRANT SELECT TO "reader";GRANT SELECT TO "writer";`},
+		{"SET search_path = {{default \"public\" .db}};", "SET search_path = interlink;"},
+		{"COMMENT IS {{quote \"it's\"}};", "COMMENT IS 'it''s';"},
+	}
+	for _, c := range cases {
+		got, err := mergeScript("test.sql", c.script, values)
+		if err != nil || got != c.want {
+			t.Errorf("merge %q = %q, %v; want %q", c.script, got, err, c.want)
+		}
+	}
+	// unresolved placeholders report the script and line
+	_, err := mergeScript("create.sql", "SELECT 1;\nSELECT {{schema}};", values)
+	if err == nil || !strings.Contains(err.Error(), "create.sql:2") || !strings.Contains(err.Error(), "{{schema}}") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestScriptIdentifiers(t *testing.T) {
+	tmpl, err := parseScript("test.sql", "{{if eq env \"prod\"}}{{range split roles \",\"}}{{.}}{{end}}{{end}}{{.db}}", map[string]string{"env": "", "roles": "", "db": ""}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := scriptIdentifiers(tmpl)
+	for _, name := range []string{"env", "roles", "db"} {
+		if !used[name] {
+			t.Errorf("expected %s to be used", name)
+		}
+	}
+}
+
+func TestBindScript(t *testing.T) {
+	bound := map[string]QueryArg{
+		"name": {Name: "name", Value: "John Smith"},
+		"age":  {Name: "age", Type: "int", Value: "42"},
+	}
+	got, args, err := bindScript("query.sql", "SELECT * FROM person WHERE name = '{{name}}' AND age > {{age}} OR alias = {{name}} LIMIT {{limit}};", map[string]string{"limit": "10"}, bound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT * FROM person WHERE name = $1 AND age > $2 OR alias = $1 LIMIT 10;"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	if len(args) != 2 || args[0].Value != "John Smith" || args[1].Value != "42" {
+		t.Fatalf("unexpected args %v", args)
+	}
+	value, err := args[1].Bind()
+	if v, ok := value.(int64); err != nil || !ok || v != 42 {
+		t.Fatalf("unexpected bound value %v, %v", value, err)
+	}
+	cases := []struct {
+		script, want string
+		valid        bool
+	}{
+		// strings are built by concatenating the parameter
+		{`SELECT * FROM person WHERE name LIKE '%' || {{name}} || '%'`, `SELECT * FROM person WHERE name LIKE '%' || $1 || '%'`, true},
+		{`SELECT * FROM person WHERE name = {{- name -}}`, `SELECT * FROM person WHERE name =$1`, true},
+		// placeholders in comments and quotes in comments are ignored
+		{"SELECT 1 -- it's '{{name}}\nFROM person WHERE name = {{name}}", "SELECT 1 -- it's '$1\nFROM person WHERE name = $1", true},
+		{`SELECT 'it''s', {{name}}`, `SELECT 'it''s', $1`, true},
+		// other template expressions can use bound variables in strings
+		{`SELECT {{if eq env "prod"}}'{{name}}'{{end}}`, `SELECT $1`, true},
+		// a parameter cannot be part of a string literal or identifier
+		{`SELECT * FROM person WHERE name LIKE '%{{name}}%'`, "", false},
+		{`SELECT * FROM person WHERE name LIKE 'it''s {{name}}'`, "", false},
+		{`SELECT "{{name}}" FROM person`, "", false},
+		// the value of a bound variable cannot be used in a template expression
+		{`SELECT {{if eq name "x"}}1{{end}}`, "", false},
+		{`SELECT {{upper name}}`, "", false},
+	}
+	for _, c := range cases {
+		got, _, err := bindScript("query.sql", c.script, map[string]string{"env": "prod"}, bound)
+		if (err == nil) != c.valid {
+			t.Errorf("%s: unexpected error %v", c.script, err)
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), "query.sql") {
+			t.Errorf("%s: the error does not name the script: %v", c.script, err)
+		}
+		if c.valid && got != c.want {
+			t.Errorf("got %q; want %q", got, c.want)
+		}
+	}
+}
+
+func TestReplaceScript(t *testing.T) {
+	script := "-- {\"roles\": [\"{{role}}\"]}\nINSERT INTO matrix VALUES('{{1,2},{3,4}}', '{{role}}', {{db-owner}});"
+	values := map[string]string{"role": "reader", "db-owner": "admin"}
+	// double braces that are not placeholders cannot be parsed as a template
+	if _, err := mergeScript("matrix.sql", script, values); err == nil {
+		t.Fatal("expected the template merge to fail")
+	}
+	got, args, err := replaceScript("matrix.sql", script, values, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-- {\"roles\": [\"reader\"]}\nINSERT INTO matrix VALUES('{{1,2},{3,4}}', 'reader', admin);"; got != want || len(args) != 0 {
+		t.Fatalf("got %q, %v; want %q", got, args, want)
+	}
+	// bound variables are replaced with parameters in the order they are first used
+	bound := map[string]QueryArg{
+		"name": {Name: "name", Value: "John Smith"},
+		"age":  {Name: "age", Type: "int", Value: "42"},
+	}
+	got, args, err = replaceScript("query.sql", "SELECT * FROM person WHERE age > {{age}} AND name = '{{name}}' AND tags = '{{a,b}}' OR alias = {{name}};", nil, bound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT * FROM person WHERE age > $1 AND name = $2 AND tags = '{{a,b}}' OR alias = $2;"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	if len(args) != 2 || args[0].Value != "42" || args[1].Value != "John Smith" {
+		t.Fatalf("unexpected args %v", args)
+	}
+	if _, _, err = replaceScript("query.sql", "SELECT * FROM person WHERE name LIKE '%{{name}}%'", nil, bound); err == nil {
+		t.Fatal("expected a bound variable inside quotes to fail")
+	}
+}
+
+func TestMergeMode(t *testing.T) {
+	cases := []struct {
+		manifest, script, want string
+		valid                  bool
+	}{
+		{"", "", mergeTemplate, true},
+		{"replace", "", mergeReplace, true},
+		{"replace", "template", mergeTemplate, true},
+		{"", "Replace", mergeReplace, true},
+		{"", "text", "", false},
+	}
+	for _, c := range cases {
+		got, err := mergeMode("test.sql", c.manifest, c.script)
+		if (err == nil) != c.valid || got != c.want {
+			t.Errorf("merge mode %q, %q = %q, %v; want %q", c.manifest, c.script, got, err, c.want)
+		}
+	}
+}
+
+func TestVarCheck(t *testing.T) {
+	cases := []struct {
+		v     Var
+		value string
+		valid bool
+	}{
+		{Var{Name: "n", Type: "int"}, "12", true},
+		{Var{Name: "n", Type: "int"}, "12; DROP TABLE", false},
+		{Var{Name: "b", Type: "bool"}, "true", true},
+		{Var{Name: "d", Type: "date"}, "2022-02-30", false},
+		{Var{Name: "u", Type: "uuid"}, "7d444840-9dc0-11d1-b245-5ffdce74fad2", true},
+		{Var{Name: "e", Type: "enum", Values: []string{"asc", "desc"}}, "desc", true},
+		{Var{Name: "e", Type: "enum", Values: []string{"asc", "desc"}}, "up", false},
+		{Var{Name: "r", Type: "regex", Pattern: "[a-z]+"}, "abc1", false},
+		{Var{Name: "t"}, "John Smith", true},
+		{Var{Name: "x", Type: "money"}, "1", false},
+	}
+	for _, c := range cases {
+		if err := c.v.Check(c.value); (err == nil) != c.valid {
+			t.Errorf("check %s %q: %v", c.v.Type, c.value, err)
+		}
+	}
+}
@@ -10,16 +10,26 @@ package core
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sort"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
 )
 
 type Plan struct {
-	Releases []Info `json:"releases"`
+	Releases []Info `json:"releases" yaml:"releases"`
 }
 
 type Info struct {
-	DbVersion  string `json:"dbVersion"`
-	AppVersion string `json:"appVersion"`
-	Path       string `json:"path"`
+	DbVersion  string `json:"dbVersion" yaml:"dbVersion"`
+	AppVersion string `json:"appVersion" yaml:"appVersion"`
+	Path       string `json:"path" yaml:"path"`
+	// the application versions that can be upgraded directly to this release
+	// if omitted, the release can be upgraded from the release with the highest version lower than its own
+	// note: used to upgrade across release lines, e.g. from a patch release of an older major version
+	UpgradeFrom []string `json:"upgradeFrom,omitempty" yaml:"upgradeFrom,omitempty"`
 }
 
 // get a JSON bytes reader for the Plan
@@ -37,9 +47,13 @@ func (plan *Plan) bytes() (*[]byte, error) {
 	return &b, err
 }
 
-// get the Plan in the http Response
+// get the Plan from its content, either in JSON or YAML format
 func (plan *Plan) decode(content []byte) (*Plan, error) {
 	result := new(Plan)
+	if !IsJSON(content) {
+		err := yaml.Unmarshal(content, result)
+		return result, err
+	}
 	err := json.NewDecoder(bytes.NewReader(content)).Decode(result)
 	return result, err
 }
@@ -56,18 +70,163 @@ func (plan *Plan) info(appVersion string) (*Info, int) {
 	return nil, 0
 }
 
-func (plan *Plan) getUpgradeWindow(currentAppVersion string, targetAppVersion string) (currentReleaseIndex int, targetReleaseIndex int) {
-	var (
-		currentIx, targetIx int
-	)
-	for ix, release := range plan.Releases {
-		if release.AppVersion == currentAppVersion {
-			currentIx = ix + 1
+// resolves an application version against the releases in the plan, so that it can be either a version in the plan,
+// latest for the highest version that is not a pre-release, or a version range (e.g. 1.2.x, ^1.2.0 or ~1.2.0) for
+// the highest version in the range that is not a pre-release
+func (plan *Plan) resolve(appVersion string) (string, error) {
+	if info, _ := plan.info(appVersion); info != nil {
+		return appVersion, nil
+	}
+	var resolved *semver
+	result := ""
+	for _, release := range plan.Releases {
+		v, err := parseVersion(release.AppVersion)
+		if err != nil || len(v.pre) > 0 {
+			continue
+		}
+		if appVersion != "latest" && !matchVersion(release.AppVersion, appVersion) {
+			continue
+		}
+		if resolved == nil || v.compare(resolved) > 0 {
+			resolved, result = v, release.AppVersion
+		}
+	}
+	if len(result) == 0 {
+		return "", errors.New(fmt.Sprintf("!!! information for application version '%s' does not exist in the release plan", appVersion))
+	}
+	return result, nil
+}
+
+// returns the application versions that can be upgraded directly to a release
+func (plan *Plan) predecessors(release Info) []string {
+	if len(release.UpgradeFrom) > 0 {
+		return release.UpgradeFrom
+	}
+	previous := ""
+	for _, r := range plan.Releases {
+		if compareVersions(r.AppVersion, release.AppVersion) < 0 && (len(previous) == 0 || compareVersions(r.AppVersion, previous) > 0) {
+			previous = r.AppVersion
+		}
+	}
+	if len(previous) == 0 {
+		return nil
+	}
+	return []string{previous}
+}
+
+// works out the shortest upgrade path between two application versions, following the versions each release can be
+// upgraded from, so that the position of the releases in the plan does not matter
+// returns the releases in the path, including the releases of both application versions
+func (plan *Plan) upgradePath(fromAppVersion string, toAppVersion string) ([]Info, error) {
+	from, _ := plan.info(fromAppVersion)
+	if from == nil {
+		return nil, errors.New(fmt.Sprintf("!!! application version '%s' does not exist in the release plan\n", fromAppVersion))
+	}
+	if to, _ := plan.info(toAppVersion); to == nil {
+		return nil, errors.New(fmt.Sprintf("!!! application version '%s' does not exist in the release plan\n", toAppVersion))
+	}
+	// the releases that can be upgraded to from each application version, higher versions first so that
+	// between paths of the same length, the one skipping patches of older release lines is preferred
+	releases := make([]Info, len(plan.Releases))
+	copy(releases, plan.Releases)
+	sort.SliceStable(releases, func(i, j int) bool {
+		return compareVersions(releases[i].AppVersion, releases[j].AppVersion) > 0
+	})
+	next := make(map[string][]Info)
+	for _, release := range releases {
+		for _, p := range plan.predecessors(release) {
+			next[p] = append(next[p], release)
+		}
+	}
+	// breadth first search from the current application version
+	previous := map[string]*Info{fromAppVersion: nil}
+	queue := []Info{*from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.AppVersion == toAppVersion {
+			path := []Info{current}
+			for p := previous[current.AppVersion]; p != nil; p = previous[p.AppVersion] {
+				path = append([]Info{*p}, path...)
+			}
+			return path, nil
+		}
+		for _, release := range next[current.AppVersion] {
+			if _, visited := previous[release.AppVersion]; !visited {
+				c := current
+				previous[release.AppVersion] = &c
+				queue = append(queue, release)
+			}
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("!!! I cannot find an upgrade path from application version %s to %s in the release plan\n"+
+		"If the releases are in different release lines, add the versions a release can be upgraded from to its upgradeFrom list\n", fromAppVersion, toAppVersion))
+}
+
+// works out the releases to roll back from the releases the database was upgraded along, so that a downgrade reverts
+// the releases actually applied rather than the shortest path in the release plan (e.g. across release lines)
+// applied: the application versions applied to the database in the order they were applied, see releaseHistory
+// returns the releases in the path, including the releases of both application versions
+func (plan *Plan) downgradePath(toAppVersion string, fromAppVersion string, applied []string) ([]Info, error) {
+	to, _ := plan.info(toAppVersion)
+	if to == nil {
+		return nil, errors.New(fmt.Sprintf("!!! application version '%s' does not exist in the release plan\n", toAppVersion))
+	}
+	// releases applied without running any commands are not recorded, but the current release is known
+	chain := pushRelease(append([]string{}, applied...), fromAppVersion)
+	target := -1
+	for i, appVersion := range chain {
+		if appVersion == toAppVersion {
+			target = i
 		}
-		if release.AppVersion == targetAppVersion {
-			targetIx = ix + 1
-			break
+	}
+	if target < 0 {
+		// the database was at a later release when the history was first recorded, so the release plan is used before it
+		if compareVersions(toAppVersion, chain[0]) > 0 {
+			return nil, errors.New(fmt.Sprintf("!!! I cannot downgrade to application version %s as the database was not upgraded through it, "+
+				"the releases applied to the database are %s\n", toAppVersion, strings.Join(chain, ", ")))
+		}
+		chain, target = append([]string{toAppVersion}, chain...), 0
+	}
+	path := []Info{*to}
+	for i := target; i < len(chain)-1; i++ {
+		// releases applied without running any commands between two recorded releases are found in the release plan
+		segment, err := plan.upgradePath(chain[i], chain[i+1])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("!!! the releases applied to the database (%s) do not match the release plan: %s",
+				strings.Join(chain[target:], ", "), strings.TrimPrefix(err.Error(), "!!! ")))
+		}
+		path = append(path, segment[1:]...)
+	}
+	return path, nil
+}
+
+// works out the application versions the database went through from its command history, in the order they were applied
+// a release applied again after a downgrade removes the releases applied after it, as the downgrade has reverted them
+// entries: the successful command history entries, in any order
+func releaseHistory(entries []HistoryEntry) []string {
+	sorted := append([]HistoryEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start.Equal(sorted[j].Start) {
+			return sorted[i].Id < sorted[j].Id
+		}
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	var chain []string
+	for _, entry := range sorted {
+		if entry.Success {
+			chain = pushRelease(chain, entry.AppVersion)
+		}
+	}
+	return chain
+}
+
+// adds a release to the applied releases, removing the releases after it if it was already applied
+func pushRelease(chain []string, appVersion string) []string {
+	for i, v := range chain {
+		if v == appVersion {
+			return chain[:i+1]
 		}
 	}
-	return currentIx, targetIx
+	return append(chain, appVersion)
 }
@@ -0,0 +1,135 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a plan with a hotfix of an older release line added after a new major release
+var hotfixPlan = &Plan{Releases: []Info{
+	{AppVersion: "1.1.0", DbVersion: "1.1.0"},
+	{AppVersion: "1.0.0", DbVersion: "1.0.0"},
+	{AppVersion: "2.0.0", DbVersion: "2.0.0", UpgradeFrom: []string{"1.1.0"}},
+	{AppVersion: "1.1.1", DbVersion: "1.1.1"},
+	{AppVersion: "2.0.1", DbVersion: "2.0.1", UpgradeFrom: []string{"2.0.0", "1.1.1"}},
+	{AppVersion: "2.1.0-rc.1", DbVersion: "2.1.0"},
+}}
+
+func TestUpgradePath(t *testing.T) {
+	cases := []struct {
+		from, to, want string
+	}{
+		{"1.0.0", "2.0.0", "1.0.0,1.1.0,2.0.0"},
+		{"1.0.0", "1.1.1", "1.0.0,1.1.0,1.1.1"},
+		{"1.1.1", "2.0.1", "1.1.1,2.0.1"},
+		{"1.0.0", "2.1.0-rc.1", "1.0.0,1.1.0,2.0.0,2.0.1,2.1.0-rc.1"},
+	}
+	for _, c := range cases {
+		path, err := hotfixPlan.upgradePath(c.from, c.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var versions []string
+		for _, release := range path {
+			versions = append(versions, release.AppVersion)
+		}
+		if got := strings.Join(versions, ","); got != c.want {
+			t.Errorf("upgrade path from %s to %s = %s, want %s", c.from, c.to, got, c.want)
+		}
+	}
+	// the hotfix cannot be upgraded to the major release it was not built on
+	if _, err := hotfixPlan.upgradePath("1.1.1", "2.0.0"); err == nil {
+		t.Fatal("expected no upgrade path from 1.1.1 to 2.0.0")
+	}
+}
+
+func TestDowngradePath(t *testing.T) {
+	cases := []struct {
+		name, to, from, applied, want string
+	}{
+		// the shortest path in the plan goes through 2.0.0, but the database was upgraded through the hotfix
+		{"hotfix line", "1.0.0", "2.0.1", "1.0.0,1.1.0,1.1.1,2.0.1", "1.0.0,1.1.0,1.1.1,2.0.1"},
+		{"major line", "1.1.0", "2.0.1", "1.0.0,1.1.0,2.0.0,2.0.1", "1.1.0,2.0.0,2.0.1"},
+		// releases applied without running any commands are not recorded
+		{"unrecorded releases", "1.0.0", "2.0.1", "1.0.0,1.1.1", "1.0.0,1.1.0,1.1.1,2.0.1"},
+		// databases without history use the release plan
+		{"no history", "1.0.0", "2.0.1", "", "1.0.0,1.1.0,2.0.0,2.0.1"},
+		{"history after the target", "1.0.0", "2.0.1", "1.1.1,2.0.1", "1.0.0,1.1.0,1.1.1,2.0.1"},
+	}
+	for _, c := range cases {
+		var applied []string
+		if len(c.applied) > 0 {
+			applied = strings.Split(c.applied, ",")
+		}
+		path, err := hotfixPlan.downgradePath(c.to, c.from, applied)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var versions []string
+		for _, release := range path {
+			versions = append(versions, release.AppVersion)
+		}
+		if got := strings.Join(versions, ","); got != c.want {
+			t.Errorf("%s: downgrade path from %s to %s = %s, want %s", c.name, c.from, c.to, got, c.want)
+		}
+	}
+	// the database did not go through 2.0.0
+	if _, err := hotfixPlan.downgradePath("2.0.0", "2.0.1", []string{"1.0.0", "1.1.0", "1.1.1", "2.0.1"}); err == nil {
+		t.Fatal("expected an error downgrading to a release that was not applied")
+	}
+}
+
+func TestReleaseHistory(t *testing.T) {
+	start := time.Now()
+	entry := func(appVersion string, minutes int, success bool) HistoryEntry {
+		return HistoryEntry{AppVersion: appVersion, Start: start.Add(time.Duration(minutes) * time.Minute), Success: success}
+	}
+	// most recent first, as returned by the providers
+	entries := []HistoryEntry{
+		entry("2.0.1", 9, true),
+		entry("1.1.1", 8, true),
+		// the downgrade to 1.1.0 reverted 2.0.0
+		entry("1.1.0", 7, true),
+		entry("2.0.0", 6, true),
+		entry("2.0.0", 5, true),
+		// a failed command does not apply the release
+		entry("3.0.0", 4, false),
+		entry("1.1.0", 3, true),
+		entry("1.0.0", 2, true),
+		entry("1.0.0", 1, true),
+	}
+	if got := strings.Join(releaseHistory(entries), ","); got != "1.0.0,1.1.0,1.1.1,2.0.1" {
+		t.Fatalf("unexpected release history %s", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		alias, want string
+	}{
+		{"1.0.0", "1.0.0"},
+		{"latest", "2.0.1"},
+		{"1.x", "1.1.1"},
+		{"1.1", "1.1.1"},
+		{"~1.0.0", "1.0.0"},
+		{"^1.0.0", "1.1.1"},
+		{"2.1.0-rc.1", "2.1.0-rc.1"},
+	}
+	for _, c := range cases {
+		if got, err := hotfixPlan.resolve(c.alias); err != nil || got != c.want {
+			t.Errorf("resolve(%s) = %s, %v; want %s", c.alias, got, err, c.want)
+		}
+	}
+	if _, err := hotfixPlan.resolve("3.x"); err == nil {
+		t.Fatal("expected 3.x not to resolve")
+	}
+}
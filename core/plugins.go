@@ -0,0 +1,368 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+)
+
+// PluginReport the database plugins found in the plugin search path
+type PluginReport struct {
+	// the directories searched for plugins, in order
+	SearchPath []string `json:"searchPath"`
+	// the allow-list file, if any
+	AllowList string `json:"allowList,omitempty"`
+	// the plugins found
+	Plugins []PluginStatus `json:"plugins"`
+}
+
+// PluginStatus the information and status of a database plugin
+type PluginStatus struct {
+	// the name of the plugin, as used in Db.Provider
+	Name string `json:"name"`
+	// the path to the plugin executable, or built-in for native providers
+	Path string `json:"path"`
+	// the version reported by the plugin
+	Version string `json:"version,omitempty"`
+	// the protocol version negotiated with the plugin: 2 for gRPC, 1 for the legacy net/rpc protocol
+	Protocol int `json:"protocol,omitempty"`
+	// the optional features reported by the plugin
+	Capabilities []string `json:"capabilities,omitempty"`
+	// the SHA-256 checksum of the plugin executable
+	Checksum string `json:"checksum,omitempty"`
+	// true if the plugin can be used
+	OK bool `json:"ok"`
+	// a description of the status of the plugin
+	Status string `json:"status"`
+}
+
+// Valid returns true if all the plugins can be used
+func (r *PluginReport) Valid() bool {
+	for _, p := range r.Plugins {
+		if !p.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Table returns the plugins as a table
+func (r *PluginReport) Table() *Table {
+	table := &Table{Header: Row{"name", "path", "version", "protocol", "capabilities", "checksum", "status"}}
+	for _, p := range r.Plugins {
+		protocol := ""
+		if p.Protocol > 0 {
+			protocol = fmt.Sprintf("%d", p.Protocol)
+		}
+		table.Rows = append(table.Rows, Row{p.Name, p.Path, p.Version, protocol, strings.Join(p.Capabilities, ","), p.Checksum, p.Status})
+	}
+	return table
+}
+
+// ListPlugins launches each plugin in the search path to report its name, version and capabilities
+func ListPlugins(cfg *Config) (*PluginReport, error) {
+	report, paths, err := newPluginReport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range nativeProviders {
+		report.Plugins = append(report.Plugins, PluginStatus{Name: name, Path: "built-in", Version: "built-in", OK: true, Status: "ok"})
+	}
+	allowList, err := loadAllowList(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// the plugin processes must not write to the output of the command
+	logger := hclog.New(&hclog.LoggerOptions{Name: "plugin", Output: io.Discard, Level: hclog.Off})
+	for _, path := range paths {
+		status := PluginStatus{Name: pluginName(path), Path: path}
+		provider, client, err := launchPlugin(status.Name, path, allowList, logger)
+		if err != nil {
+			status.Status = strings.TrimSpace(strings.TrimPrefix(err.Error(), "!!! "))
+			report.Plugins = append(report.Plugins, status)
+			continue
+		}
+		info, err := pluginInfo(provider)
+		// the protocol version negotiated during the handshake
+		status.Protocol = client.NegotiatedVersion()
+		client.Kill()
+		switch {
+		case status.Protocol == LegacyProtocolVersion:
+			// plugins built for older DbMan versions complete the handshake but only support part of the features
+			status.Version, status.OK, status.Status = "unknown", true, "ok, built for an older version of DbMan: the database is not locked, "+
+				"the command history, upgrade progress and script checksums are not recorded, query parameters are merged and backups are not supported"
+		case err != nil:
+			// plugins built before plugin information was available complete the handshake but cannot describe themselves
+			status.Version, status.OK, status.Status = "unknown", true, "ok, the plugin does not report its version"
+		default:
+			status.Version, status.Capabilities, status.OK, status.Status = info.Version, info.Capabilities, true, "ok"
+		}
+		report.Plugins = append(report.Plugins, status)
+	}
+	return report, nil
+}
+
+// VerifyPlugins checks the checksum of each plugin in the search path against the allow-list
+// if there is no allow-list, the checksums are reported so that they can be added to one
+func VerifyPlugins(cfg *Config) (*PluginReport, error) {
+	report, paths, err := newPluginReport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	allowList, err := loadAllowList(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		status := PluginStatus{Name: pluginName(path), Path: path}
+		status.Checksum, err = fileChecksum(path)
+		if err != nil {
+			status.Status = fmt.Sprintf("I cannot read the plugin: %v", err)
+			report.Plugins = append(report.Plugins, status)
+			continue
+		}
+		allowed, found := allowList[filepath.Base(path)]
+		switch {
+		case allowList == nil:
+			status.OK, status.Status = true, "not verified, there is no allow-list"
+		case !found:
+			status.Status = "the plugin is not in the allow-list"
+		case !strings.EqualFold(allowed, status.Checksum):
+			status.Status = "the checksum does not match the allow-list, the plugin might have been tampered with"
+		default:
+			status.OK, status.Status = true, "ok"
+		}
+		report.Plugins = append(report.Plugins, status)
+	}
+	return report, nil
+}
+
+// creates a report with the search path and returns the plugin executables found in it
+func newPluginReport(cfg *Config) (*PluginReport, []string, error) {
+	report := &PluginReport{SearchPath: pluginSearchPath(cfg), AllowList: cfg.GetString(PluginsAllowList), Plugins: make([]PluginStatus, 0)}
+	paths, err := findPlugins(report.SearchPath)
+	return report, paths, err
+}
+
+// returns the directories where plugins are searched for, in order:
+// the directories in Plugins (separated by the OS path list separator, i.e. : or ;) and the directory of the
+// DbMan executable
+// the working directory is only searched if Plugins is not set, as in previous versions, so that a plugin is not
+// picked up from wherever DbMan happens to be launched
+func pluginSearchPath(cfg *Config) []string {
+	var dirs []string
+	if cfg != nil {
+		for _, dir := range filepath.SplitList(cfg.GetString(Plugins)) {
+			if dir = strings.TrimSpace(dir); len(dir) > 0 {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = append(dirs, ".")
+	}
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+	// removes duplicates, keeping the first occurrence
+	var unique []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			abs = dir
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			unique = append(unique, dir)
+		}
+	}
+	return unique
+}
+
+// returns the path of the executable of the plugin with the specified name, the first found in the search path
+func findPlugin(searchPath []string, name string) (string, error) {
+	file := pluginFile(name)
+	for _, dir := range searchPath {
+		path := filepath.Join(dir, file)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("!!! I cannot find the database provider plugin '%s' in %s\n"+
+		"Copy the plugin to one of these directories or add its directory to Plugins (OX_DBM_PLUGINS)\n", file, strings.Join(searchPath, ", ")))
+}
+
+// returns the paths of the plugin executables in the search path, only the first plugin found for each name
+func findPlugins(searchPath []string) ([]string, error) {
+	var paths []string
+	names := make(map[string]bool)
+	for _, dir := range searchPath {
+		matches, err := filepath.Glob(filepath.Join(dir, pluginFile("*")))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			if info, err := os.Stat(path); err != nil || info.IsDir() || names[pluginName(path)] {
+				continue
+			}
+			names[pluginName(path)] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// the name of the executable of the plugin with the specified name
+func pluginFile(name string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf("%s%s.exe", PluginPrefix, name)
+	}
+	return fmt.Sprintf("%s%s", PluginPrefix, name)
+}
+
+// the name of the plugin with the specified executable
+func pluginName(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), PluginPrefix), ".exe")
+}
+
+// reads the allow-list of plugin checksums, in the format written by sha256sum (i.e. checksum, spaces and file name)
+// returns nil if PluginsAllowList is not set
+func loadAllowList(cfg *Config) (map[string]string, error) {
+	path := cfg.GetString(PluginsAllowList)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot read the plugin allow-list: %v\n", err))
+	}
+	defer f.Close()
+	allowList := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		// sha256sum prefixes the file name with * in binary mode
+		allowList[filepath.Base(strings.TrimPrefix(fields[1], "*"))] = strings.ToLower(fields[0])
+	}
+	return allowList, scanner.Err()
+}
+
+// returns the SHA-256 checksum of a file as a hex string
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// launches a plugin process and connects to it
+// allowList: the checksums of the allowed plugins, if not nil the plugin must be in the list and match its checksum
+func launchPlugin(name string, path string, allowList map[string]string, logger hclog.Logger) (DatabaseProvider, *plugin.Client, error) {
+	config := &plugin.ClientConfig{
+		HandshakeConfig: plugin.HandshakeConfig{
+			ProtocolVersion:  ProtocolVersion,
+			MagicCookieKey:   MagicCookieKey,
+			MagicCookieValue: MagicCookieValue(name),
+		},
+		// the plugin chooses the highest protocol version it supports: gRPC, or net/rpc for plugins built for older DbMan versions
+		VersionedPlugins: map[int]plugin.PluginSet{
+			LegacyProtocolVersion: {name: &DatabaseProviderPlugin{}},
+			ProtocolVersion:       {name: &DatabaseProviderGRPCPlugin{}},
+		},
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
+		Cmd:              exec.Command(path),
+		Logger:           logger,
+	}
+	// go-plugin checks the checksum of the executable before launching it
+	if allowList != nil {
+		allowed, found := allowList[filepath.Base(path)]
+		if !found {
+			return nil, nil, errors.New(fmt.Sprintf("!!! the plugin '%s' is not in the plugin allow-list\n", path))
+		}
+		checksum, err := hex.DecodeString(allowed)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("!!! the checksum of plugin '%s' in the allow-list is not valid: %v\n", path, err))
+		}
+		config.SecureConfig = &plugin.SecureConfig{Checksum: checksum, Hash: sha256.New()}
+	}
+	client := plugin.NewClient(config)
+	// connect to the db plugin via RPC
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, pluginError(name, path, err)
+	}
+	// request the plugin
+	raw, err := rpcClient.Dispense(name)
+	if err != nil {
+		client.Kill()
+		return nil, nil, errors.New(fmt.Sprintf("!!! I cannot load db provider %s from '%s': %v\n", name, path, err))
+	}
+	switch p := raw.(type) {
+	// the legacy protocol exchanges JSON strings
+	case DatabaseProvider:
+		return p, client, nil
+	// the gRPC protocol exchanges typed messages, decorated in the same way as native providers
+	case DatabasePlugin:
+		return &DatabasePluginDecorator{Plugin: p, Name: name}, client, nil
+	}
+	client.Kill()
+	return nil, nil, errors.New(fmt.Sprintf("!!! I cannot load db provider %s from '%s': unexpected plugin type %T\n", name, path, raw))
+}
+
+// rewords the errors returned by go-plugin when a plugin cannot be launched
+func pluginError(name string, path string, err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "checksums did not match"):
+		return errors.New(fmt.Sprintf("!!! the checksum of plugin '%s' does not match the plugin allow-list, the plugin might have been tampered with\n", path))
+	case strings.Contains(msg, "exited before we could connect"), strings.Contains(msg, "Unrecognized remote plugin message"):
+		// plugins exit straight away if the magic cookie does not match the name they were built for
+		return errors.New(fmt.Sprintf("!!! the plugin '%s' did not complete the handshake: it is not a DbMan database provider plugin "+
+			"or it was built for a name other than '%s' (magic cookie %s=%s)\n", path, name, MagicCookieKey, MagicCookieValue(name)))
+	case strings.Contains(msg, "Incompatible API version"):
+		return errors.New(fmt.Sprintf("!!! the plugin '%s' was built for a different protocol version, DbMan supports protocol versions %d and %d: %s\n",
+			path, LegacyProtocolVersion, ProtocolVersion, msg))
+	}
+	return errors.New(fmt.Sprintf("!!! I cannot load db provider %s from '%s': %s\n", name, path, msg))
+}
+
+// asks a plugin for its name, version and capabilities
+func pluginInfo(provider DatabaseProvider) (*PluginInfo, error) {
+	result := NewParameterFromJSON(provider.GetPluginInfo())
+	if result.HasError() {
+		return nil, result.Error()
+	}
+	if m, ok := result.Get("result").(map[string]interface{}); ok {
+		return NewPluginInfoFromMap(m)
+	}
+	return nil, errors.New("!!! The database plugin did not return its information\n")
+}
@@ -0,0 +1,205 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/viper"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"reflect"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindPlugin(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	for _, path := range []string{filepath.Join(first, pluginFile("mysql")), filepath.Join(second, pluginFile("mysql")), filepath.Join(second, pluginFile("oracle"))} {
+		if err := os.WriteFile(path, []byte{}, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	searchPath := []string{first, second}
+	// the first plugin found in the search path is used
+	path, err := findPlugin(searchPath, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != first {
+		t.Fatalf("expected the plugin in %s, got %s", first, path)
+	}
+	if _, err = findPlugin(searchPath, "mssql"); err == nil {
+		t.Fatal("expected a missing plugin to be reported")
+	}
+	paths, err := findPlugins(searchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || pluginName(paths[0]) != "mysql" || pluginName(paths[1]) != "oracle" {
+		t.Fatalf("unexpected plugins %v", paths)
+	}
+}
+
+func TestPluginSearchPath(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := t.TempDir(), t.TempDir()
+	cfg := &Config{cfg: viper.New()}
+	// the working directory is only searched if no plugin directories are configured
+	if got := pluginSearchPath(cfg); !reflect.DeepEqual(got, []string{".", filepath.Dir(exe)}) {
+		t.Fatalf("unexpected search path %v", got)
+	}
+	cfg.cfg.Set(Plugins, strings.Join([]string{first, " ", second, first}, string(os.PathListSeparator)))
+	if got := pluginSearchPath(cfg); !reflect.DeepEqual(got, []string{first, second, filepath.Dir(exe)}) {
+		t.Fatalf("unexpected search path %v", got)
+	}
+}
+
+// a database plugin returning fixed values, the methods not overridden are not called by the test
+type fakeDbPlugin struct {
+	DatabasePlugin
+	version *Version
+}
+
+func (p *fakeDbPlugin) GetVersion() (*Version, error) {
+	return p.version, nil
+}
+
+func (p *fakeDbPlugin) GetProgress() (*Progress, error) {
+	return nil, nil
+}
+
+func (p *fakeDbPlugin) RunCommand(cmd *Command) (bytes.Buffer, error) {
+	log := bytes.Buffer{}
+	log.WriteString(cmd.Scripts[0].Content)
+	return log, errors.New("syntax error")
+}
+
+func (p *fakeDbPlugin) Backup(backup *Backup) (bytes.Buffer, error) {
+	backup.Size = 42
+	return bytes.Buffer{}, nil
+}
+
+func TestGRPCProvider(t *testing.T) {
+	impl := &fakeDbPlugin{version: &Version{AppVersion: "1.0.0", DbVersion: "1", Time: time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)}}
+	client, server := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{"fake": &DatabaseProviderGRPCPlugin{Impl: impl, Name: "fake"}})
+	defer client.Close()
+	defer server.Stop()
+	raw, err := client.Dispense("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &DatabasePluginDecorator{Plugin: raw.(DatabasePlugin), Name: "fake"}
+	// the version and its time are not changed by the protocol
+	v := NewParameterFromJSON(provider.GetVersion()).GetVersion()
+	if v == nil || v.AppVersion != "1.0.0" || !v.Time.Equal(impl.version.Time) {
+		t.Fatalf("unexpected version %v", v)
+	}
+	// a database without a version does not return a version
+	impl.version = nil
+	if v = NewParameterFromJSON(provider.GetVersion()).GetVersion(); v != nil {
+		t.Fatalf("expected no version, got %v", v)
+	}
+	if p := NewParameterFromJSON(provider.GetProgress()).GetProgress(); p != nil {
+		t.Fatalf("expected no progress, got %v", p)
+	}
+	// the command log is returned with the error
+	result := NewParameterFromJSON(provider.RunCommand((&Command{Name: "test", Scripts: []Script{{Name: "test", Content: "SELEC 1"}}}).ToString()))
+	if !result.HasError() || result.Error().Error() != "syntax error" || result.GetLog() != "SELEC 1\n" {
+		t.Fatalf("unexpected command result %v %s", result.Error(), result.GetLog())
+	}
+	// the backup metadata populated by the plugin is returned
+	backup := &Backup{Name: "test"}
+	if _, err = raw.(DatabasePlugin).Backup(backup); err != nil || backup.Size != 42 || backup.Name != "test" {
+		t.Fatalf("unexpected backup %v %v", backup, err)
+	}
+	if info := NewParameterFromJSON(provider.GetPluginInfo()); info.HasError() {
+		t.Fatal(info.Error())
+	}
+}
+
+// the net/rpc server of a plugin built for an older DbMan version, which only has the methods of the legacy protocol
+type legacyRPCServer struct {
+	Impl DatabaseProvider
+}
+
+func (s *legacyRPCServer) Setup(args string, resp *string) error {
+	*resp = s.Impl.Setup(args)
+	return nil
+}
+
+func (s *legacyRPCServer) GetInfo(args string, resp *string) error {
+	*resp = s.Impl.GetInfo()
+	return nil
+}
+
+func (s *legacyRPCServer) GetVersion(args string, resp *string) error {
+	*resp = s.Impl.GetVersion()
+	return nil
+}
+
+func (s *legacyRPCServer) SetVersion(args string, resp *string) error {
+	*resp = s.Impl.SetVersion(args)
+	return nil
+}
+
+func (s *legacyRPCServer) RunCommand(args string, resp *string) error {
+	*resp = s.Impl.RunCommand(args)
+	return nil
+}
+
+func (s *legacyRPCServer) RunQuery(args string, resp *string) error {
+	*resp = s.Impl.RunQuery(args)
+	return nil
+}
+
+func TestLegacyPlugin(t *testing.T) {
+	dm, _ := newTestDbMan(t, "0.0.1")
+	// serves the SQLite provider as a plugin built for an older DbMan version
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", &legacyRPCServer{Impl: dm.DbPlugin()}); err != nil {
+		t.Fatal(err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+	dm.db = &DatabaseProviderManager{provider: &DatabaseProviderRPC{Client: client}, protocol: LegacyProtocolVersion}
+	// the database is changed without locking it or recording the command history and upgrade progress
+	if log := mustRun(t, "deploy")(dm.Deploy(false)); !strings.Contains(log, "the database is not locked") {
+		t.Fatalf("expected a warning that the database is not locked:\n%s", log)
+	}
+	dm.Cfg.cfg.Set(AppVersion, "0.0.3")
+	mustRun(t, "upgrade")(dm.Upgrade(false, false, ""))
+	if v := dbVersion(t, dm); v != "0.0.3" {
+		t.Fatalf("expected version 0.0.3, got %s", v)
+	}
+	// the input values are merged with the query as the plugin cannot bind parameters
+	table, _, _, err := dm.Query("total", map[string]string{"id": "2"})
+	if err != nil || len(table.Rows) != 1 || table.Rows[0][0] != "3" {
+		t.Fatalf("unexpected query result %v, %v", table, err)
+	}
+	if _, _, _, err = dm.Query("total", map[string]string{"id": "2", "offset": "1; DROP TABLE t1"}); err == nil {
+		t.Fatal("expected an invalid input value to be rejected")
+	}
+	// the features the plugin does not support fail with an error
+	if _, err, _ = dm.Upgrade(false, true, ""); err == nil || !strings.Contains(err.Error(), "does not record the progress") {
+		t.Fatalf("expected resuming the upgrade to fail, got %v", err)
+	}
+	if _, _, err, _ = dm.Verify(); err == nil || !strings.Contains(err.Error(), "does not support GetChecksums") {
+		t.Fatalf("expected verifying the scripts to fail, got %v", err)
+	}
+}
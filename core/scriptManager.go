@@ -12,15 +12,25 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	. "southwinds.dev/dbman/plugin"
 	"strings"
+	"sync"
 	"time"
 )
 
 // ScriptManager the source of database scripts
 type ScriptManager struct {
 	cfg *Config
+	// the script source and the repository uri it was created for
+	source    ScriptSource
+	sourceUri string
+	lock      sync.Mutex
+	// if true, the content cached on disk is ignored and downloaded again
+	refresh bool
+	// if not nil, the files read during the current operation by path (see withSnapshot)
+	files map[string][]byte
 }
 
 // NewScriptManager factory function
@@ -33,15 +43,35 @@ func NewScriptManager(cfg *Config) (*ScriptManager, error) {
 	return source, nil
 }
 
+// withSnapshot returns a script manager to use for the duration of an operation
+// the files read from the script repository (i.e. plan, manifests and scripts) are memoised, so that they are
+// fetched only once and do not change if the repository is updated while the operation runs
+func (s *ScriptManager) withSnapshot() *ScriptManager {
+	// creates the source if required so that it is reused by later operations,
+	// if it cannot be created the error is returned when the snapshot reads the first file
+	_, _ = s.getSource()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return &ScriptManager{
+		cfg:       s.cfg,
+		source:    s.source,
+		sourceUri: s.sourceUri,
+		refresh:   s.refresh,
+		files:     make(map[string][]byte),
+	}
+}
+
+// setRefresh discards the script source so that it is created again, ignoring any content cached on disk
+func (s *ScriptManager) setRefresh() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.refresh = true
+	s.source, s.sourceUri = nil, ""
+}
+
 // fetchPlan fetches the getReleaseInfo plan
 func (s *ScriptManager) fetchPlan() (*Plan, error) {
-	// get the base uri to retrieve scripts (includes credentials if set)
-	baseUri, err := s.getRepoUri()
-	if err != nil {
-		return nil, err
-	}
-	// note: passing http headers results in 503 on gitlab.com when using credentials on URI
-	content, err := s.readFile(fmt.Sprintf("%s/plan.json", baseUri))
+	_, content, err := s.find(".", planFiles)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("! cannot retrieve release plan: %v", err))
 	}
@@ -53,16 +83,8 @@ func (s *ScriptManager) fetchPlan() (*Plan, error) {
 // FetchFile reads a file stored in the remote repository
 // path: the relative path to the file in the repository
 func (s *ScriptManager) FetchFile(path string) (*string, error) {
-	// get the base uri to retrieve scripts (includes credentials if set)
-	baseUri, err := s.getRepoUri()
-	if err != nil {
-		return nil, err
-	}
-	// builds a uri to the specific file
-	uri := fmt.Sprintf("%s%s", baseUri, path)
 	// fetch the file
-	// note: passing http headers results in 503 on gitlab.com when using credentials on URI
-	content, err := s.readFile(uri)
+	content, err := s.read(path)
 	// if the request was unsuccessful then return the error
 	if err != nil {
 		return nil, err
@@ -82,22 +104,14 @@ func (s *ScriptManager) FetchFile(path string) (*string, error) {
 // - appVersion: the version of the application release to fetchManifest
 // - contentTypes: list of content type content to fetchManifest
 func (s *ScriptManager) fetchManifest(appVersion string) (*Info, *Manifest, error) {
-	// get the base uri to retrieve scripts (includes credentials if set)
-	baseUri, err := s.getRepoUri()
-	if err != nil {
-		return nil, nil, err
-	}
 	// get the ReleaseInfo information based on the
 	release, err := s.getReleaseInfo(appVersion)
 	if err != nil {
 		// could not find ReleaseInfo information in the getReleaseInfo plan
 		return nil, nil, err
 	}
-	// builds a uri to fetchManifest the specific release manifest
-	uri := fmt.Sprintf("%s/%s/manifest.json", baseUri, release.Path)
 	// fetchManifest the manifest
-	// note: passing http headers results in 503 on gitlab.com when using credentials on URI
-	content, err := s.readFile(uri)
+	manifestPath, content, err := s.find(release.Path, manifestFiles)
 	// if the request was unsuccessful then return the error
 	if err != nil {
 		return nil, nil, err
@@ -105,59 +119,54 @@ func (s *ScriptManager) fetchManifest(appVersion string) (*Info, *Manifest, erro
 	// request was good so construct a release manifest reference
 	man := &Manifest{}
 	man, err = man.Decode(content)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("!!! I cannot decode the manifest '%s' of release %s: %v\n", manifestPath, appVersion, err))
+	}
 	return release, man, nil
 }
 
 func (s *ScriptManager) fetchCommandContent(appVersion string, subPath string, command Command) (*Command, error) {
-	// get the base uri to retrieve scripts (includes credentials if set)
-	baseUri, err := s.getRepoUri()
-	if err != nil {
-		return nil, err
-	}
-	// get the ReleaseInfo information based on the
-	release, err := s.getReleaseInfo(appVersion)
+	// get the ReleaseInfo information and manifest of the release
+	release, manifest, err := s.fetchManifest(appVersion)
 	if err != nil {
 		// could not find ReleaseInfo information in the getReleaseInfo plan
 		return nil, err
 	}
-	command.Scripts, err = s.addScriptsContent(baseUri, fmt.Sprintf("%s/%s", release.Path, subPath), command.Scripts)
+	command.Scripts, err = s.addScriptsContent(path.Join(release.Path, subPath), command.Scripts, manifest.Merge, mergeContext(release, manifest))
 	if err != nil {
 		return nil, err
 	}
 	return &command, nil
 }
 
-func (s *ScriptManager) fetchQueryContent(appVersion string, subPath string, query Query, params map[string]string) (*Query, error) {
-	// get the base uri to retrieve scripts (includes credentials if set)
-	baseUri, err := s.getRepoUri()
-	if err != nil {
-		return nil, err
-	}
-	// get the ReleaseInfo information based on the
-	release, err := s.getReleaseInfo(appVersion)
+// fetches the content of a query in the release and merges its variables
+// bind: if true, the input variables are bound to query parameters, otherwise their values are merged with the query
+func (s *ScriptManager) fetchQueryContent(appVersion string, subPath string, query Query, params map[string]string, bind bool) (*Query, error) {
+	// get the ReleaseInfo information and manifest of the release
+	release, manifest, err := s.fetchManifest(appVersion)
 	if err != nil {
 		// could not find ReleaseInfo information in the getReleaseInfo plan
 		return nil, err
 	}
-	query, err = s.addQueryContent(baseUri, fmt.Sprintf("%s/%s", release.Path, subPath), query, params)
+	query, err = s.addQueryContent(path.Join(release.Path, subPath), query, params, bind, manifest.Merge, mergeContext(release, manifest))
 	if err != nil {
 		return &query, err
 	}
 	return &query, nil
 }
 
-// get the release information for a given application version
+// get the release information for a given application version, which can be an alias such as latest or 1.2.x
 func (s *ScriptManager) getReleaseInfo(appVersion string) (*Info, error) {
 	plan, err := s.fetchPlan()
 	if err != nil {
 		return nil, err
 	}
-	for _, release := range plan.Releases {
-		if release.AppVersion == appVersion {
-			return &release, nil
-		}
+	appVersion, err = plan.resolve(appVersion)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New(fmt.Sprintf("!!! information for application version '%s' does not exist in the release plan", appVersion))
+	release, _ := plan.info(appVersion)
+	return release, nil
 }
 
 func (s *ScriptManager) get(key string) string {
@@ -165,15 +174,22 @@ func (s *ScriptManager) get(key string) string {
 }
 
 // add the content from the remote repository to the passed-in scripts
-func (s *ScriptManager) addScriptsContent(baseUri string, path string, scripts []Script) ([]Script, error) {
+// merge: the merge mode of the manifest, used for the scripts not specifying their own
+// ctx: the values available to the variables merged from the run context
+func (s *ScriptManager) addScriptsContent(path string, scripts []Script, merge string, ctx map[string]string) ([]Script, error) {
 	var result []Script
 	for _, script := range scripts {
-		content, err := s.getContent(baseUri, path, script.File)
+		content, err := s.getContent(path, script.File)
 		if err != nil {
 			return nil, err
 		}
 		script.Content = content
-		mergedScript, err := s.merge(script.Content, script.Vars, nil)
+		script.FileChecksum = Checksum(content)
+		mode, err := mergeMode(script.File, merge, script.Merge)
+		if err != nil {
+			return nil, err
+		}
+		mergedScript, _, err := s.merge(script.File, mode, script.Content, script.Vars, nil, false, ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -184,39 +200,109 @@ func (s *ScriptManager) addScriptsContent(baseUri string, path string, scripts [
 }
 
 // add the content of the query from the remote repository
-func (s *ScriptManager) addQueryContent(baseUri string, path string, query Query, params map[string]string) (Query, error) {
+// bind: if true, the input variables are bound to query parameters, otherwise their values are merged with the query
+// merge: the merge mode of the manifest, used if the query does not specify its own
+// ctx: the values available to the variables merged from the run context
+func (s *ScriptManager) addQueryContent(path string, query Query, params map[string]string, bind bool, merge string, ctx map[string]string) (Query, error) {
 	// retrieve content from the remote repository
-	content, err := s.getContent(baseUri, path, query.File)
+	content, err := s.getContent(path, query.File)
 	if err != nil {
 		return Query{}, err
 	}
 	// assign the content to the query
 	query.Content = content
-	// merge vars
-	mergedQuery, err := s.merge(query.Content, query.Vars, params)
+	mode, err := mergeMode(query.File, merge, query.Merge)
+	if err != nil {
+		return query, err
+	}
+	// merge vars, binding the input values to the query parameters if required
+	mergedQuery, args, err := s.merge(query.File, mode, query.Content, query.Vars, params, bind, ctx)
 	if err != nil {
 		return query, err
 	}
 	query.Content = mergedQuery
+	query.Args = args
 	return query, nil
 }
 
-// get the content of a particular script on a git repo via http client
-func (s *ScriptManager) getContent(baseUri string, path string, file string) (string, error) {
-	// get the uri of the script
-	uri := fmt.Sprintf("%v/%v/%v", baseUri, path, file)
-	// issue an http request for the content
-	// note: passing http headers results in 503 on gitlab.com when using credentials on URI
-	content, err := s.readFile(uri)
+// get the content of a particular script in the script repository
+func (s *ScriptManager) getContent(dir string, file string) (string, error) {
+	content, err := s.read(path.Join(dir, file))
 	if err != nil {
 		return "", err
 	}
 	return string(content[:]), err
 }
 
+// reads a file in the script repository verifying its signature if required
+func (s *ScriptManager) read(p string) ([]byte, error) {
+	content, err := s.readRaw(p)
+	if err != nil {
+		return nil, err
+	}
+	return content, s.verify(p, content)
+}
+
+// reads the first file found in a directory out of a list of file names (e.g. manifest.json or manifest.yaml)
+// verifying its signature if required
+// returns the path of the file found and its content
+func (s *ScriptManager) find(dir string, names []string) (string, []byte, error) {
+	p, content, err := findFile(s.readRaw, dir, names)
+	if err != nil {
+		return "", nil, err
+	}
+	return p, content, s.verify(p, content)
+}
+
+// reads a file in the script repository
+// within an operation snapshot, the file is read from the repository only once
+func (s *ScriptManager) readRaw(p string) ([]byte, error) {
+	p = cleanPath(p)
+	s.lock.Lock()
+	content, found := s.files[p]
+	s.lock.Unlock()
+	if found {
+		return content, nil
+	}
+	source, err := s.getSource()
+	if err != nil {
+		return nil, err
+	}
+	content, err = source.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	if s.files != nil {
+		s.files[p] = content
+	}
+	s.lock.Unlock()
+	return content, nil
+}
+
+// if a Repo.PublicKey is set, verifies the content of a file in the script repository against its signature
+// so that unsigned or tampered content is rejected before it is used
+func (s *ScriptManager) verify(path string, content []byte) error {
+	if len(s.get(RepoPublicKey)) == 0 {
+		return nil
+	}
+	key, err := parsePublicKey(s.get(RepoPublicKey))
+	if err != nil {
+		return err
+	}
+	return verifySignature(key, s.readRaw, cleanPath(path), content)
+}
+
 // merges the passed-in script with the values in of the script vars
-func (s *ScriptManager) merge(script string, vars []Var, params map[string]string) (string, error) {
-	// merge vars if any
+// name: the script file name, used in error messages
+// mode: how the variables are merged, i.e. template or replace
+// params: the values of the variables merged from the input (i.e. command line or query string)
+// bind: if true, the input variables are bound to query parameters instead of being merged with the script
+// ctx: the values of the variables merged from the run context (i.e. appVersion, dbVersion and description)
+// returns the merged script and the values bound to its parameters
+func (s *ScriptManager) merge(name string, mode string, script string, vars []Var, params map[string]string, bind bool, ctx map[string]string) (string, []QueryArg, error) {
+	values := make(map[string]string)
+	bound := make(map[string]QueryArg)
 	for _, variable := range vars {
 		var value string
 		// if variable is in configuration
@@ -232,15 +318,47 @@ func (s *ScriptManager) merge(script string, vars []Var, params map[string]strin
 		// if a variable has a value passed-in as an input from the CLI or http URI
 		if len(variable.FromInput) > 0 {
 			value = params[variable.FromInput]
+		} else
+		// if a variable has a value from the run context
+		if len(variable.FromContext) > 0 {
+			v, found := ctx[variable.FromContext]
+			if !found {
+				return "", nil, errors.New(fmt.Sprintf("!!! the variable '%s' of script %s refers to an unknown context value '%s', valid values are appVersion, dbVersion and description\n", variable.Name, name, variable.FromContext))
+			}
+			value = v
+		}
+		// if no value has been found use the default value
+		if len(value) == 0 {
+			value = variable.Default
+		}
+		// validate the value against the type of the variable
+		if err := variable.Check(value); err != nil {
+			return "", nil, errors.New(fmt.Sprintf("!!! I cannot merge script %s: %v\n", name, err))
 		}
-		// validate for suspicious values
-		if s.suspicious(value) {
-			return "", errors.New(fmt.Sprintf("!!! I found suspicious content for variable '%s'", variable.Name))
+		// input values are bound to query parameters so are never merged with the script
+		if len(variable.FromInput) > 0 && bind {
+			bound[variable.Name] = QueryArg{Name: variable.Name, Type: variable.Type, Value: value}
+			continue
 		}
-		// merge the variable value
-		script = strings.Replace(script, fmt.Sprintf("{{%s}}", variable.Name), value, -1)
+		// validate for suspicious values, except for the values defined by the release itself
+		if len(variable.FromContext) == 0 && value != variable.Default && s.suspicious(value) {
+			return "", nil, errors.New(fmt.Sprintf("!!! I found suspicious content for variable '%s'", variable.Name))
+		}
+		values[variable.Name] = value
+	}
+	if mode == mergeReplace {
+		return replaceScript(name, script, values, bound)
+	}
+	return bindScript(name, script, values, bound)
+}
+
+// the values available to the variables merged from the run context of a release
+func mergeContext(release *Info, manifest *Manifest) map[string]string {
+	return map[string]string{
+		"appVersion":  release.AppVersion,
+		"dbVersion":   release.DbVersion,
+		"description": manifest.Description,
 	}
-	return script, nil
 }
 
 func (s *ScriptManager) suspicious(value string) bool {
@@ -250,7 +368,7 @@ func (s *ScriptManager) suspicious(value string) bool {
 		len(strings.Split(value, "\n")) > 1
 }
 
-func (s ScriptManager) getRepoUri() (string, error) {
+func (s *ScriptManager) getRepoUri() (string, error) {
 	uri := s.get(RepoURI)
 	if len(uri) == 0 {
 		return "", errors.New(fmt.Sprintf("!!! The Repo.URI is not defined"))
@@ -263,35 +381,65 @@ func (s ScriptManager) getRepoUri() (string, error) {
 	return uri, nil
 }
 
-func (s *ScriptManager) readFile(uri string) ([]byte, error) {
-	if strings.HasPrefix(uri, "http") {
-		return getHttpFile(uri, fmt.Sprintf("%s:%s", s.get(RepoUsername), s.get(RepoPassword)))
-	} else {
-		return getFsFile(uri)
-	}
-	return nil, nil
-}
-
-// getFsFile reads a file from the file system
-func getFsFile(uri string) ([]byte, error) {
-	path, err := filepath.Abs(uri)
+// gets the script source for the configured repository uri
+// the source is created again if the repository uri or reference changes
+func (s *ScriptManager) getSource() (ScriptSource, error) {
+	// get the repository uri (includes credentials if set)
+	uri, err := s.getRepoUri()
 	if err != nil {
 		return nil, err
 	}
-	return os.ReadFile(path)
-}
-
-// getHttpFile reads a file from a http endpoint
-func getHttpFile(uri, creds string) ([]byte, error) {
-	// if credentials are provided
-	if len(creds) > 0 {
-		// add them to the uri scheme
-		u, err := addCredsToHttpURI(uri, creds)
+	key := fmt.Sprintf("%s@%s", uri, s.get(RepoRef))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.source == nil || s.sourceUri != key {
+		source, err := s.newSource(uri)
 		if err != nil {
 			return nil, err
 		}
-		uri = u
+		s.source, s.sourceUri = source, key
+	}
+	return s.source, nil
+}
+
+// creates the script source for the repository uri
+func (s *ScriptManager) newSource(uri string) (ScriptSource, error) {
+	repoUri := s.get(RepoURI)
+	if isGitUri(repoUri) {
+		creds := ""
+		if len(s.get(RepoUsername)) > 0 && len(s.get(RepoPassword)) > 0 {
+			creds = fmt.Sprintf("%s:%s", s.get(RepoUsername), s.get(RepoPassword))
+		}
+		return newGitSource(repoUri, s.get(RepoRef), creds, s.cacheDir(".dbman_git"), s.refresh)
+	}
+	return newScriptSource(uri, newContentCache(s.cacheDir(".dbman_cache"), s.refresh))
+}
+
+// the directory where the content of the script repository is cached, next to the configuration file
+func (s *ScriptManager) cacheDir(name string) string {
+	if len(s.cfg.ConfigFileUsed()) == 0 {
+		return filepath.Join(os.TempDir(), name)
+	}
+	return filepath.Join(filepath.Dir(s.cfg.ConfigFileUsed()), name)
+}
+
+// revision returns the revision of the repository the scripts are read from (e.g. a git commit SHA)
+// or an empty string if the script source is not versioned
+func (s *ScriptManager) revision() string {
+	source, err := s.getSource()
+	if err != nil {
+		return ""
 	}
+	if versioned, ok := source.(interface{ Revision() string }); ok {
+		return versioned.Revision()
+	}
+	return ""
+}
+
+// httpGet reads a file from a http endpoint
+// etag: if not empty, the file is only downloaded if its ETag is different, otherwise notModified is true
+// returns the content and ETag of the file
+func httpGet(uri string, etag string) (content []byte, newEtag string, notModified bool, err error) {
 	// create an http client with defined timeout
 	client := http.Client{
 		Timeout: 60 * time.Second,
@@ -299,11 +447,14 @@ func getHttpFile(uri, creds string) ([]byte, error) {
 	// create a new http request
 	req, err := http.NewRequest("GET", uri, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
-	// add headers to disable caching
-	req.Header.Add("Cache-Control", `no-cache"`)
+	// add headers to disable caching by intermediaries, the content is revalidated using its ETag instead
+	req.Header.Add("Cache-Control", "no-cache")
 	req.Header.Add("Pragma", "no-cache")
+	if len(etag) > 0 {
+		req.Header.Add("If-None-Match", etag)
+	}
 	// execute the request
 	resp, err := client.Do(req)
 	defer func() {
@@ -312,38 +463,26 @@ func getHttpFile(uri, creds string) ([]byte, error) {
 		}
 	}()
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
 	if resp != nil {
 		switch resp.StatusCode {
+		case 304:
+			return nil, etag, true, nil
 		case 401:
 			fallthrough
 		case 403:
-			return nil, errors.New(fmt.Sprintf("!!! I do not have permission to get the content of %s\n", uri))
+			return nil, "", false, errors.New(fmt.Sprintf("!!! I do not have permission to get the content of %s\n", uri))
 		case 404:
-			return nil, errors.New(fmt.Sprintf("!!! I cannot find %s\n", uri))
+			return nil, "", false, errors.New(fmt.Sprintf("!!! I cannot find %s\n", uri))
 		case 408:
-			return nil, errors.New(fmt.Sprintf("!!! I cannot retrieve content of %s as the server is not responding\n", uri))
+			return nil, "", false, errors.New(fmt.Sprintf("!!! I cannot retrieve content of %s as the server is not responding\n", uri))
 		case 500:
-			return nil, errors.New(fmt.Sprintf("!!! I cannot retrieve content of %s as the server responded with an internal error\n", uri))
+			return nil, "", false, errors.New(fmt.Sprintf("!!! I cannot retrieve content of %s as the server responded with an internal error\n", uri))
 		}
 		// return the byte content in the response body
-		return ioutil.ReadAll(resp.Body)
+		content, err = ioutil.ReadAll(resp.Body)
+		return content, resp.Header.Get("ETag"), false, err
 	}
-	return nil, fmt.Errorf("server sent no response: %s", err)
-}
-
-// addCredsToHttpURI add credentials to http(s) URI
-func addCredsToHttpURI(uri string, creds string) (string, error) {
-	// if there are no credentials or the uri is a file path
-	if len(creds) == 0 || strings.HasPrefix(uri, "http") {
-		// skip and return as is
-		return uri, nil
-	}
-	parts := strings.Split(uri, "/")
-	if !strings.HasPrefix(parts[0], "http") {
-		return uri, fmt.Errorf("invalid URI scheme, http(s) expected when specifying credentials\n")
-	}
-	parts[2] = fmt.Sprintf("%s@%s", creds, parts[2])
-	return strings.Join(parts, "/"), nil
+	return nil, "", false, fmt.Errorf("server sent no response: %s", err)
 }
@@ -7,7 +7,11 @@
 
 package core
 
-import "testing"
+import (
+	"github.com/spf13/viper"
+	"strings"
+	"testing"
+)
 
 func TestNewScriptManager(t *testing.T) {
 	// create an instance of the current configuration set
@@ -41,3 +45,21 @@ func TestNewScriptManager(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestFetchManifest_DecodeError(t *testing.T) {
+	// the repository files are read from the operation snapshot
+	sm := &ScriptManager{
+		cfg: &Config{Cache: NewCache(), cfg: viper.New()},
+		files: map[string][]byte{
+			"plan.json":        []byte(`{"releases":[{"appVersion":"0.0.1","dbVersion":"0.0.1","path":"v1"}]}`),
+			"v1/manifest.json": []byte(`{"dbVersion": `),
+		},
+	}
+	_, _, err := sm.fetchManifest("0.0.1")
+	if err == nil {
+		t.Fatal("expected an error for a manifest that cannot be decoded")
+	}
+	if !strings.Contains(err.Error(), "v1/manifest.json") {
+		t.Fatalf("the error does not name the manifest: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// the files of a minimal script repository
+var testRepo = map[string]string{
+	"plan.json":            `{"releases":[{"appVersion":"0.0.1","dbVersion":"0.0.1","path":"v1"}]}`,
+	"v1/manifest.json":     `{"dbVersion":"0.0.1"}`,
+	"v1/schema/create.sql": "CREATE TABLE test();",
+}
+
+func TestScriptSource_Dir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range testRepo {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	source, err := NewScriptSource("file://" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+}
+
+func TestScriptSource_TarGz(t *testing.T) {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range testRepo {
+		// archives from git hosting services have all the files under a single root directory
+		tw.WriteHeader(&tar.Header{Name: "repo-main/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	archive := filepath.Join(t.TempDir(), "release.tar.gz")
+	os.WriteFile(archive, buf.Bytes(), 0644)
+	source, err := NewScriptSource(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+}
+
+func TestScriptSource_Zip(t *testing.T) {
+	buf := bytes.Buffer{}
+	zw := zip.NewWriter(&buf)
+	for name, content := range testRepo {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	archive := filepath.Join(t.TempDir(), "release.zip")
+	os.WriteFile(archive, buf.Bytes(), 0644)
+	source, err := NewScriptSource(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+}
+
+func TestScriptSource_Embed(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, content := range testRepo {
+		fsys["db/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	RegisterEmbeddedScripts("test", fsys)
+	source, err := NewScriptSource("embed://test/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+	if _, err = NewScriptSource("embed://unknown"); err == nil {
+		t.Fatal("expected an error for an unregistered repository")
+	}
+}
+
+func TestScriptSource_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range testRepo {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@test"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+	}
+	git("init", "--quiet")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	// a later commit changing the plan must not be visible at tag v1
+	os.WriteFile(filepath.Join(dir, "plan.json"), []byte(`{"releases":[]}`), 0644)
+	git("commit", "--quiet", "-am", "v2")
+	cache := t.TempDir()
+	source, err := newGitSource("git+file://"+dir, "v1", "", cache, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+	if len(source.(*gitSource).Revision()) != 40 {
+		t.Fatalf("unexpected revision %q", source.(*gitSource).Revision())
+	}
+	// the cached clone is fetched again
+	if source, err = newGitSource("git+file://"+dir, "", "", cache, false); err != nil {
+		t.Fatal(err)
+	}
+	if plan, _ := source.ReadPlan(); string(plan) != `{"releases":[]}` {
+		t.Fatalf("unexpected plan at HEAD %q", plan)
+	}
+	if _, err = newGitSource("git+file://"+dir, "unknown", "", cache, true); err == nil {
+		t.Fatal("expected an error for an unknown reference")
+	}
+}
+
+// the credentials are sent in a header and never stored in the clone
+func TestScriptSource_GitCredentials(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	out, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skip("git exec path not found")
+	}
+	backend := filepath.Join(strings.TrimSpace(string(out)), "git-http-backend")
+	if _, err = os.Stat(backend); err != nil {
+		t.Skip("git-http-backend is not installed")
+	}
+	root := t.TempDir()
+	dir := filepath.Join(root, "repo")
+	for name, content := range testRepo {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "-A"}, {"commit", "--quiet", "-m", "v1"}} {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@test"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+	}
+	git := &cgi.Handler{Path: backend, Env: []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pwd, ok := r.BasicAuth(); !ok || user != "user" || pwd != "s3cr3t" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		git.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	uri := fmt.Sprintf("git+%s/repo/.git", server.URL)
+	cache := t.TempDir()
+	if _, err = newGitSource(uri, "", "", cache, false); err == nil {
+		t.Fatal("expected an error without credentials")
+	}
+	source, err := newGitSource(uri, "", "user:s3cr3t", cache, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+	// the cached clone is fetched again with the credentials
+	if source, err = newGitSource(uri, "", "user:s3cr3t", cache, false); err != nil {
+		t.Fatal(err)
+	}
+	config, err := os.ReadFile(filepath.Join(source.(*gitSource).dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(config), "s3cr3t") {
+		t.Fatalf("the credentials are stored in the configuration of the clone:\n%s", config)
+	}
+}
+
+func TestScriptSource_Bundle(t *testing.T) {
+	files := make(map[string][]byte)
+	for name, content := range testRepo {
+		files[name] = []byte(content)
+	}
+	index := &BundleIndex{Format: BundleFormat, Created: time.Now().UTC()}
+	var buf bytes.Buffer
+	if err := writeBundle(&buf, index, files); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Files) != len(testRepo) {
+		t.Fatalf("unexpected index files %v", index.Files)
+	}
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	os.WriteFile(bundle, buf.Bytes(), 0644)
+	source, err := NewScriptSource(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSource(t, source)
+	// a bundle with a file not matching its checksum is rejected
+	extracted, _ := readTarGz(buf.Bytes())
+	extracted["v1/schema/create.sql"] = []byte("DROP TABLE test;")
+	if err = verifyBundle(extracted); err == nil {
+		t.Fatal("expected an error for a modified bundle file")
+	}
+}
+
+func TestScriptSource_YAML(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "v1"), 0755)
+	os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("releases:\n  - appVersion: 0.0.1\n    dbVersion: 1.0\n    path: v1\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "v1", "manifest.yaml"), []byte("dbVersion: 1.0\ndbProvider: _pgsql\n"), 0644)
+	source, err := NewScriptSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := source.ReadPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := new(Plan).decode(content)
+	if err != nil || len(plan.Releases) != 1 || plan.Releases[0].DbVersion != "1.0" {
+		t.Fatalf("unexpected plan %+v: %v", plan, err)
+	}
+	if _, err = source.ReadManifest("v1"); err != nil {
+		t.Fatal(err)
+	}
+	// converting to json and back keeps the content
+	converted, err := ConvertRelease(filepath.Join(dir, "v1", "manifest.yaml"), "", false)
+	if err != nil || filepath.Base(converted) != "manifest.json" {
+		t.Fatalf("unexpected conversion %s: %v", converted, err)
+	}
+	manifest, err := source.ReadManifest("v1")
+	if err != nil || !IsJSON(manifest) {
+		t.Fatalf("unexpected manifest %q: %v", manifest, err)
+	}
+	m, err := new(Manifest).Decode(manifest)
+	if err != nil || m.DbVersion != "1.0" || m.DbProvider != "_pgsql" {
+		t.Fatalf("unexpected manifest %+v: %v", m, err)
+	}
+}
+
+func checkSource(t *testing.T, source ScriptSource) {
+	plan, err := source.ReadPlan()
+	if err != nil || string(plan) != testRepo["plan.json"] {
+		t.Fatalf("unexpected plan %q: %v", plan, err)
+	}
+	manifest, err := source.ReadManifest("v1")
+	if err != nil || string(manifest) != testRepo["v1/manifest.json"] {
+		t.Fatalf("unexpected manifest %q: %v", manifest, err)
+	}
+	script, err := source.ReadFile("/v1/schema/create.sql")
+	if err != nil || string(script) != testRepo["v1/schema/create.sql"] {
+		t.Fatalf("unexpected script %q: %v", script, err)
+	}
+	files, err := source.List("v1")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("unexpected files %v: %v", files, err)
+	}
+	if _, err = source.ReadFile("v1/missing.sql"); err == nil {
+		t.Fatal("expected an error reading a missing file")
+	}
+}
@@ -0,0 +1,140 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semantic versions (https://semver.org), allowing a leading v and missing minor and patch parts (i.e. 1.0 is 1.0.0)
+var semverRegex = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// semver a parsed semantic version
+type semver struct {
+	// the major, minor and patch numbers
+	parts [3]int
+	// the pre-release identifiers, e.g. rc.1 in 1.0.0-rc.1
+	pre string
+}
+
+// parses a semantic version, build metadata is ignored
+func parseVersion(version string) (*semver, error) {
+	m := semverRegex.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return nil, errors.New(fmt.Sprintf("!!! '%s' is not a semantic version\n", version))
+	}
+	v := &semver{pre: m[4]}
+	for i := 0; i < 3; i++ {
+		if len(m[i+1]) > 0 {
+			// the regex only matches digits so the conversion cannot fail other than for out of range numbers
+			n, err := strconv.Atoi(m[i+1])
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("!!! '%s' is not a semantic version: %v\n", version, err))
+			}
+			v.parts[i] = n
+		}
+	}
+	return v, nil
+}
+
+// compares two versions, returns -1, 0 or 1 if v is lower, equal or greater than o
+func (v *semver) compare(o *semver) int {
+	for i := 0; i < 3; i++ {
+		if v.parts[i] != o.parts[i] {
+			return sign(v.parts[i] - o.parts[i])
+		}
+	}
+	// a pre-release version is lower than its normal version
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+	// pre-release identifiers are compared one by one, numerically if both are numbers and lexically otherwise
+	pa, pb := strings.Split(v.pre, "."), strings.Split(o.pre, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return sign(na - nb)
+			}
+		case errA == nil:
+			// numeric identifiers have lower precedence than alphanumeric ones
+			return -1
+		case errB == nil:
+			return 1
+		case pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return sign(len(pa) - len(pb))
+}
+
+// compares two versions (e.g. 1.2.10 and 1.10.0) as semantic versions, or lexically if they are not semantic versions
+// returns -1, 0 or 1 if a is lower, equal or greater than b
+func compareVersions(a string, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return va.compare(vb)
+}
+
+// returns true if the version matches a version range:
+// 1, 1.2, 1.x or 1.2.x: any version with the same major, or major and minor numbers
+// ^1.2.0: any version with the same major number, not lower than 1.2.0
+// ~1.2.0: any version with the same major and minor numbers, not lower than 1.2.0
+func matchVersion(version string, versionRange string) bool {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	// the number of leading parts that must be the same
+	fixed := 0
+	switch {
+	case strings.HasPrefix(versionRange, "^"):
+		fixed, versionRange = 1, versionRange[1:]
+	case strings.HasPrefix(versionRange, "~"):
+		fixed, versionRange = 2, versionRange[1:]
+	default:
+		for _, wildcard := range []string{".x", ".X", ".*"} {
+			versionRange = strings.TrimSuffix(strings.TrimSuffix(versionRange, wildcard), wildcard)
+		}
+		fixed = len(strings.Split(strings.TrimPrefix(versionRange, "v"), "."))
+	}
+	min, err := parseVersion(versionRange)
+	if err != nil || fixed > 3 {
+		return false
+	}
+	for i := 0; i < fixed; i++ {
+		if v.parts[i] != min.parts[i] {
+			return false
+		}
+	}
+	return v.compare(min) >= 0
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// the extension of the detached signature files, stored next to the signed files in the script repository
+const signatureExt = ".sig"
+
+// GenerateSigningKey creates a new ed25519 key pair used to sign releases
+// the base64 encoded private key is written to keyFile and the public key to keyFile.pub
+// returns the base64 encoded public key, to be set as the Repo.PublicKey
+func GenerateSigningKey(keyFile string) (string, error) {
+	if _, err := os.Stat(keyFile); err == nil {
+		return "", errors.New(fmt.Sprintf("!!! the key file %s already exists, I will not overwrite it\n", keyFile))
+	}
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	publicKey := base64.StdEncoding.EncodeToString(public)
+	if err = os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(private)+"\n"), 0600); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(keyFile+".pub", []byte(publicKey+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return publicKey, nil
+}
+
+// SignRelease writes a detached signature for the release plan, the release manifests and all the files
+// they reference in a script repository directory
+// dir: the root directory of the script repository
+// keyFile: the file containing the base64 encoded ed25519 private key
+// returns the paths of the signed files
+func SignRelease(dir string, keyFile string) ([]string, error) {
+	keyValue, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot read the signing key: %v\n", err))
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(keyValue)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New(fmt.Sprintf("!!! the signing key in %s is not a base64 encoded ed25519 private key\n", keyFile))
+	}
+	source, err := newDirSource(dir)
+	if err != nil {
+		return nil, err
+	}
+	content, err := source.ReadPlan()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := new(Plan).decode(content)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot decode the release plan: %v\n", err))
+	}
+	files, err := releaseFiles(source, plan, "")
+	if err != nil {
+		return nil, err
+	}
+	var signed []string
+	for p, content := range files {
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedMessage(p, content)))
+		if err = os.WriteFile(filepath.Join(dir, filepath.FromSlash(p+signatureExt)), []byte(signature+"\n"), 0644); err != nil {
+			return nil, errors.New(fmt.Sprintf("!!! I cannot write the signature of %s: %v\n", p, err))
+		}
+		signed = append(signed, p)
+	}
+	sort.Strings(signed)
+	return signed, nil
+}
+
+// the message signed for a file: its path in the repository followed by its content, so that a signed file
+// cannot be swapped for another signed file with its signature
+func signedMessage(p string, content []byte) []byte {
+	return append([]byte(p+"\n"), content...)
+}
+
+// parses an ed25519 public key, either base64 encoded or in a file containing the base64 encoded key
+func parsePublicKey(value string) (ed25519.PublicKey, error) {
+	encoded := strings.TrimSpace(value)
+	if content, err := os.ReadFile(encoded); err == nil {
+		encoded = strings.TrimSpace(string(content))
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("!!! the Repo.PublicKey is not a base64 encoded ed25519 public key or a file containing it\n")
+	}
+	return key, nil
+}
+
+// verifies the content of a file in the script repository against its detached signature
+// readFile: reads a file in the script repository, used to read the signature
+// p: the path of the file in the repository
+func verifySignature(key ed25519.PublicKey, readFile func(string) ([]byte, error), p string, content []byte) error {
+	encoded, err := readFile(p + signatureExt)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot find the signature of %s, unsigned content is not accepted as Repo.PublicKey is set\n", p))
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || !ed25519.Verify(key, signedMessage(p, content), signature) {
+		return errors.New(fmt.Sprintf("!!! the signature of %s is not valid, the content might have been tampered with\n", p))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignRelease(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range testRepo {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	keyFile := filepath.Join(t.TempDir(), "release.key")
+	publicKey, err := GenerateSigningKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := SignRelease(dir, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plan and the manifest, the test manifest does not reference any scripts
+	if len(signed) != 2 {
+		t.Fatalf("unexpected signed files %v", signed)
+	}
+	// the public key can be passed as a value or a file
+	key, err := parsePublicKey(keyFile + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := parsePublicKey(publicKey); err != nil || !value.Equal(key) {
+		t.Fatalf("the public key value does not match the public key file: %v", err)
+	}
+	source, _ := NewScriptSource(dir)
+	if err = verifySignature(key, source.ReadFile, "plan.json", []byte(testRepo["plan.json"])); err != nil {
+		t.Fatal(err)
+	}
+	if err = verifySignature(key, source.ReadFile, "plan.json", []byte(`{"releases":[]}`)); err == nil {
+		t.Fatal("expected an error for tampered content")
+	}
+	if err = verifySignature(key, source.ReadFile, "v1/schema/create.sql", []byte(testRepo["v1/schema/create.sql"])); err == nil {
+		t.Fatal("expected an error for unsigned content")
+	}
+	// a signed file and its signature copied over another signed file are rejected
+	swapped := func(p string) ([]byte, error) { return source.ReadFile("plan.json" + signatureExt) }
+	if err = verifySignature(key, swapped, "v1/manifest.json", []byte(testRepo["plan.json"])); err == nil {
+		t.Fatal("expected an error for a signed file moved to another path")
+	}
+}
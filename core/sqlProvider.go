@@ -0,0 +1,715 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	. "southwinds.dev/dbman/plugin"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the format of the times stored by the generic provider, which stores times as text so that its tables are portable
+// the format has a fixed length so that the times can be sorted as text
+const sqlTimeFormat = "2006-01-02 15:04:05.000000"
+
+// SQLDialect the database specific statements and syntax used by the generic database/sql provider
+// empty fields use the defaults, which only use standard SQL
+type SQLDialect struct {
+	// returns the query parameter with the passed-in number, e.g. ? or $1; if nil ? is used
+	Param func(n int) string
+	// true if the parameters are positional (e.g. ?), so their values are passed once for each parameter in a query
+	Positional bool
+	// true if a backslash escapes the next character in quoted strings (e.g. MySQL)
+	BackslashEscapes bool
+	// the statement creating the version table, the time column must be a text column
+	VersionTable string
+	// the query returning the database server information: database, operating system, compiler and processor bits
+	// if empty, the information only contains the driver name
+	InfoQuery string
+}
+
+var (
+	// the dialects of the database/sql drivers linked in DbMan by driver name: postgres (lib/pq) and sqlite
+	sqlDialects = map[string]SQLDialect{
+		"postgres": {Param: func(n int) string { return fmt.Sprintf("$%d", n) }, InfoQuery: `SELECT version(), '', '', ''`},
+		"sqlite":   {Param: func(n int) string { return fmt.Sprintf("?%d", n) }, InfoQuery: `SELECT 'SQLite ' || sqlite_version(), '', '', ''`},
+	}
+	sqlDialectsLock sync.RWMutex
+)
+
+// RegisterSQLDialect registers the dialect of a database/sql driver used by the generic provider (_sql)
+// drivers without a registered dialect use the default dialect
+// other drivers are added by applications embedding DbMan, importing the driver and registering its dialect, e.g. for MySQL:
+//
+//	import _ "github.com/go-sql-driver/mysql"
+//
+//	core.RegisterSQLDialect("mysql", core.SQLDialect{Positional: true, BackslashEscapes: true})
+func RegisterSQLDialect(driver string, dialect SQLDialect) {
+	sqlDialectsLock.Lock()
+	defer sqlDialectsLock.Unlock()
+	sqlDialects[driver] = dialect
+}
+
+// Implementation of DbMan's database provider wrapping any database/sql driver registered in DbMan
+// NOTE:
+//   - SQLProvider implicitly implements the DatabaseProvider interface
+//   - the driver is selected by Db.Driver and connects using Db.DSN, or Db.AdminDSN for commands run as admin
+//   - the DSN refers to the database, so commands that do not use the database still connect to it
+//   - backups are not supported as there is not a standard way of taking them
+type SQLProvider struct {
+	cfg     *Conf
+	dialect SQLDialect
+	// serialises the callers of Lock in this instance
+	local LocalLock
+	// guards lockName
+	mu sync.Mutex
+	// the name of the lock held by this instance, if any
+	lockName string
+}
+
+// pass DbMan configuration to the database provider
+// config: configuration passed-in by DbMan to the plugin
+func (db *SQLProvider) Setup(config *Conf) error {
+	if config == nil {
+		return errors.New("!!! the generic Database plugin was not provided with a valid configuration\n")
+	}
+	db.cfg = config
+	driver, _ := db.get("Db.Driver")
+	sqlDialectsLock.RLock()
+	db.dialect = sqlDialects[driver]
+	sqlDialectsLock.RUnlock()
+	return nil
+}
+
+// this function retrieves database version information in a Version struct
+// Version: the current database version, nil if the version table is empty
+// error: if failed to retrieve the version from the database, e.g. if the version table does not exist
+func (db *SQLProvider) GetVersion() (*Version, error) {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var (
+		v                                = &Version{}
+		description, versionTime, source sql.NullString
+	)
+	rows, err := conn.Query(`SELECT appVersion, dbVersion, description, time, source FROM version ORDER BY time DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// not all databases support LIMIT, so only the first row is read
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	if err = rows.Scan(&v.AppVersion, &v.DbVersion, &description, &versionTime, &source); err != nil {
+		return nil, err
+	}
+	v.Description, v.Source, v.Time = description.String, source.String, db.parseTime(versionTime.String)
+	return v, nil
+}
+
+// this function runs a database command
+// command: the struct containing the information required to run the command
+// whether a script can contain more than one statement depends on the driver
+func (db *SQLProvider) RunCommand(command *Command) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	log := bytes.Buffer{}
+	conn, err := db.newConn(command.AsAdmin)
+	if err != nil {
+		return log, err
+	}
+	defer conn.Close()
+	log.WriteString(fmt.Sprintf("? I am creating a db connection that is %v and %v\n",
+		db.label("transactional", "non-transactional", command.Transactional),
+		db.label("as an admin", "as a user", command.AsAdmin)))
+	if command.Transactional {
+		tx, err := conn.Begin()
+		if err != nil {
+			return log, err
+		}
+		for _, script := range command.Scripts {
+			_, err = tx.Exec(script.Content)
+			log.WriteString(fmt.Sprintf("? I have executed the script '%s'\n", script.Name))
+			if err != nil {
+				_ = tx.Rollback()
+				return log, fmt.Errorf("failed to execute script: %s, %s", script.File, err)
+			}
+		}
+		return log, tx.Commit()
+	}
+	for _, script := range command.Scripts {
+		if _, err = conn.Exec(script.Content); err != nil {
+			return log, fmt.Errorf("failed to execute script: %s, %s", script.File, err)
+		}
+	}
+	return log, nil
+}
+
+// this function runs a database query
+// query: the struct containing the information required to run the query
+func (db *SQLProvider) RunQuery(query *Query) (*Table, error) {
+	conn, err := db.newConn(false)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args, err := query.BoundArgs()
+	if err != nil {
+		return nil, err
+	}
+	content, args := db.bind(query.Content, args...)
+	rows, err := conn.Query(content, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return db.table(rows)
+}
+
+// this function sets the version in the database
+// version: struct containing version information to persist in the database
+func (db *SQLProvider) SetVersion(version *Version) error {
+	conn, err := db.newConn(false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ddl := db.dialect.VersionTable
+	if len(ddl) == 0 {
+		ddl = `CREATE TABLE version
+            (
+                appVersion  VARCHAR(25) NOT NULL,
+                dbVersion   VARCHAR(25) NOT NULL,
+                description VARCHAR(1000),
+                time        VARCHAR(30) NOT NULL,
+                source      VARCHAR(250),
+                CONSTRAINT version_app_version_db_release_pk PRIMARY KEY (appVersion, dbVersion)
+            )`
+	}
+	if err = db.createTable(conn, "version", ddl); err != nil {
+		return err
+	}
+	// if the version was already there (e.g. after a downgrade), update the entry so that it becomes the latest version
+	err = db.upsert(conn,
+		`UPDATE version SET description = $3, source = $4, time = $5 WHERE appVersion = $1 AND dbVersion = $2`,
+		`INSERT INTO version(appVersion, dbVersion, description, source, time) VALUES($1, $2, $3, $4, $5)`,
+		version.AppVersion, version.DbVersion, version.Description, version.Source, db.formatTime(time.Now()))
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the version table: %v\n", err))
+	}
+	// record the checksums of the scripts executed for the release
+	return db.setChecksums(conn, version.Scripts)
+}
+
+// this function retrieves the checksums of the scripts executed for all applied releases
+func (db *SQLProvider) GetChecksums() ([]ScriptChecksum, error) {
+	checksums := make([]ScriptChecksum, 0)
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// if no checksums have been recorded yet, there is nothing to return
+	if !db.tableExists(conn, "version_script") {
+		return checksums, nil
+	}
+	rows, err := conn.Query(`SELECT appVersion, dbVersion, command, script, file, checksum, fileChecksum, time
+		FROM version_script
+		ORDER BY time, appVersion, command, script`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the version_script table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			c          = ScriptChecksum{}
+			scriptTime string
+		)
+		if err = rows.Scan(&c.AppVersion, &c.DbVersion, &c.Command, &c.Script, &c.File, &c.Checksum, &c.FileChecksum, &scriptTime); err != nil {
+			return nil, err
+		}
+		c.Time = db.parseTime(scriptTime)
+		checksums = append(checksums, c)
+	}
+	return checksums, rows.Err()
+}
+
+// returns information about the database server as a DbInfo struct, using the info query of the driver dialect
+func (db *SQLProvider) GetInfo() (*DbInfo, error) {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	driver, _ := db.get("Db.Driver")
+	info := &DbInfo{Database: fmt.Sprintf("%s database/sql driver", driver), OperatingSystem: "unknown", Compiler: "unknown", ProcessorBits: "unknown"}
+	if len(db.dialect.InfoQuery) == 0 {
+		return info, nil
+	}
+	var values [4]sql.NullString
+	if err = conn.QueryRow(db.dialect.InfoQuery).Scan(&values[0], &values[1], &values[2], &values[3]); err != nil {
+		return nil, err
+	}
+	for i, field := range []*string{&info.Database, &info.OperatingSystem, &info.Compiler, &info.ProcessorBits} {
+		if len(values[i].String) > 0 {
+			*field = values[i].String
+		}
+	}
+	return info, nil
+}
+
+// backups are not supported by the generic provider
+func (db *SQLProvider) Backup(backup *Backup) (bytes.Buffer, error) {
+	driver, _ := db.get("Db.Driver")
+	return bytes.Buffer{}, errors.New(fmt.Sprintf("!!! I cannot take backups of databases using the generic provider with the '%s' driver, "+
+		"use the database tools instead\n", driver))
+}
+
+// restores are not supported by the generic provider
+func (db *SQLProvider) Restore(backup *Backup) (bytes.Buffer, error) {
+	driver, _ := db.get("Db.Driver")
+	return bytes.Buffer{}, errors.New(fmt.Sprintf("!!! I cannot restore databases using the generic provider with the '%s' driver, "+
+		"use the database tools instead\n", driver))
+}
+
+// this function acquires a lock preventing other DbMan instances from changing the database
+// the lock is a row in the dbman_lock table holding the owner of the lock, as locks are not standard SQL
+// lock: the lock information, if held by another instance the function waits up to the lock timeout
+func (db *SQLProvider) Lock(lock *Lock) error {
+	deadline := time.Now().Add(time.Duration(lock.Timeout) * time.Second)
+	// the lock is not re-entrant, wait for other callers in this instance to release it
+	if !db.local.Acquire(deadline) {
+		return errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by another operation of this instance\n", lock.Name, lock.Timeout))
+	}
+	if err := db.lockRow(lock, deadline); err != nil {
+		db.local.Release()
+		return err
+	}
+	db.mu.Lock()
+	db.lockName = lock.Name
+	db.mu.Unlock()
+	return nil
+}
+
+// inserts the lock row, waiting until the deadline if another instance holds it
+func (db *SQLProvider) lockRow(lock *Lock, deadline time.Time) error {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = db.createTable(conn, "dbman_lock", `CREATE TABLE dbman_lock
+            (
+                name  VARCHAR(100) NOT NULL PRIMARY KEY,
+                owner VARCHAR(250) NOT NULL,
+                time  VARCHAR(30) NOT NULL
+            )`)
+	if err != nil {
+		return err
+	}
+	for {
+		// the primary key only allows one instance to insert the lock row
+		query, args := db.bind(`INSERT INTO dbman_lock(name, owner, time) VALUES($1, $2, $3)`, lock.Name, lock.Owner, db.formatTime(time.Now()))
+		if _, err = conn.Exec(query, args...); err == nil {
+			return nil
+		}
+		// if the lock timeout has elapsed, find out who is holding the lock
+		if time.Now().After(deadline) {
+			var owner, since string
+			query, args = db.bind(`SELECT owner, time FROM dbman_lock WHERE name = $1`, lock.Name)
+			if conn.QueryRow(query, args...).Scan(&owner, &since) != nil {
+				return errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s': %v\n", lock.Name, err))
+			}
+			return errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by %s since %s\n"+
+				"If the instance holding the lock is no longer running, delete its row from the dbman_lock table\n", lock.Name, lock.Timeout, owner, since))
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// this function releases the lock acquired by Lock
+func (db *SQLProvider) Unlock(lock *Lock) error {
+	db.mu.Lock()
+	lockName := db.lockName
+	db.lockName = ""
+	db.mu.Unlock()
+	// nothing to release
+	if len(lockName) == 0 {
+		return nil
+	}
+	// let the next caller in this instance acquire the lock
+	defer db.local.Release()
+	conn, err := db.newConn(true)
+	if err == nil {
+		defer conn.Close()
+		query, args := db.bind(`DELETE FROM dbman_lock WHERE name = $1`, lockName)
+		_, err = conn.Exec(query, args...)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot release the lock on database '%s': %v\n", lock.Name, err))
+	}
+	return nil
+}
+
+// this function records the execution of a release command in the command_history table
+func (db *SQLProvider) SetHistory(entry *HistoryEntry) error {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = db.createTable(conn, "command_history", `CREATE TABLE command_history
+            (
+                id         BIGINT NOT NULL PRIMARY KEY,
+                appVersion VARCHAR(25) NOT NULL,
+                dbVersion  VARCHAR(25) NOT NULL,
+                command    VARCHAR(100) NOT NULL,
+                scripts    VARCHAR(4000),
+                start_time VARCHAR(30) NOT NULL,
+                end_time   VARCHAR(30) NOT NULL,
+                duration   BIGINT NOT NULL,
+                success    INTEGER NOT NULL,
+                error      VARCHAR(4000),
+                username   VARCHAR(100),
+                host       VARCHAR(250)
+            )`)
+	if err != nil {
+		return err
+	}
+	// the script names are stored as a JSON array
+	scripts, err := json.Marshal(entry.Scripts)
+	if err != nil {
+		return err
+	}
+	success := 0
+	if entry.Success {
+		success = 1
+	}
+	// auto-generated keys are not standard SQL, so the id is worked out within the transaction inserting the entry
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	query, args := db.bind(`INSERT INTO command_history(id, appVersion, dbVersion, command, scripts, start_time, end_time, duration, success, error, username, host)
+		SELECT COALESCE(MAX(id), 0) + 1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11 FROM command_history`,
+		entry.AppVersion, entry.DbVersion, entry.Command, string(scripts), db.formatTime(entry.Start), db.formatTime(entry.End),
+		entry.Duration, success, entry.Error, entry.User, entry.Host)
+	if _, err = tx.Exec(query, args...); err != nil {
+		_ = tx.Rollback()
+		return errors.New(fmt.Sprintf("!!! I cannot update the command_history table: %v\n", err))
+	}
+	return tx.Commit()
+}
+
+// this function retrieves the command execution history entries matching the filter, most recent first
+func (db *SQLProvider) GetHistory(filter *HistoryFilter) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// if no command has been recorded yet, there is nothing to return
+	if !db.tableExists(conn, "command_history") {
+		return entries, nil
+	}
+	// build the where clause from the filter
+	var (
+		where []string
+		args  []interface{}
+	)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if len(filter.AppVersion) > 0 {
+		addCondition("appVersion = $%d", filter.AppVersion)
+	}
+	if len(filter.Command) > 0 {
+		addCondition("command = $%d", filter.Command)
+	}
+	if len(filter.Status) > 0 {
+		success := 0
+		if strings.EqualFold(filter.Status, "success") {
+			success = 1
+		}
+		addCondition("success = $%d", success)
+	}
+	if !filter.Since.IsZero() {
+		addCondition("start_time >= $%d", db.formatTime(filter.Since))
+	}
+	query := `SELECT id, appVersion, dbVersion, command, scripts, start_time, end_time, duration, success, error, username, host
+		FROM command_history`
+	if len(where) > 0 {
+		query += fmt.Sprintf(" WHERE %s", strings.Join(where, " AND "))
+	}
+	query += " ORDER BY id DESC"
+	query, args = db.bind(query, args...)
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the command_history table: %v\n", err))
+	}
+	defer rows.Close()
+	// not all databases support LIMIT, so the rows are read up to the limit
+	for (filter.Limit <= 0 || len(entries) < filter.Limit) && rows.Next() {
+		var (
+			e                           = HistoryEntry{}
+			scripts, errMsg, user, host sql.NullString
+			startTime, endTime          string
+			success                     int
+		)
+		err = rows.Scan(&e.Id, &e.AppVersion, &e.DbVersion, &e.Command, &scripts, &startTime, &endTime, &e.Duration, &success, &errMsg, &user, &host)
+		if err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(scripts.String), &e.Scripts)
+		e.Start, e.End, e.Success = db.parseTime(startTime), db.parseTime(endTime), success == 1
+		e.Error, e.User, e.Host = errMsg.String, user.String, host.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// this function records the progress of an upgrade in the upgrade_progress table
+// the table holds a single row with the progress of the last upgrade
+func (db *SQLProvider) SetProgress(progress *Progress) error {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = db.createTable(conn, "upgrade_progress", `CREATE TABLE upgrade_progress
+            (
+                id       INTEGER NOT NULL PRIMARY KEY,
+                progress VARCHAR(4000) NOT NULL,
+                time     VARCHAR(30) NOT NULL
+            )`)
+	if err != nil {
+		return err
+	}
+	err = db.upsert(conn,
+		`UPDATE upgrade_progress SET progress = $1, time = $2 WHERE id = 1`,
+		`INSERT INTO upgrade_progress(id, progress, time) VALUES(1, $1, $2)`,
+		progress.ToString(), db.formatTime(time.Now()))
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the upgrade_progress table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the progress of the last upgrade, nil if no upgrade has been recorded
+func (db *SQLProvider) GetProgress() (*Progress, error) {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if !db.tableExists(conn, "upgrade_progress") {
+		return nil, nil
+	}
+	var progress string
+	err = conn.QueryRow(`SELECT progress FROM upgrade_progress WHERE id = 1`).Scan(&progress)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the upgrade_progress table: %v\n", err))
+	}
+	return NewProgress(progress)
+}
+
+// =========================================================================
+// UTILITY FUNCTIONS
+// =========================================================================
+
+// records the checksums of the scripts executed for a release in the version_script table
+// if a script is applied again (e.g. after a downgrade), its checksum is replaced
+func (db *SQLProvider) setChecksums(conn *sql.DB, scripts []ScriptChecksum) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+	err := db.createTable(conn, "version_script", `CREATE TABLE version_script
+            (
+                appVersion   VARCHAR(25) NOT NULL,
+                dbVersion    VARCHAR(25) NOT NULL,
+                command      VARCHAR(100) NOT NULL,
+                script       VARCHAR(100) NOT NULL,
+                file         VARCHAR(250) NOT NULL,
+                checksum     CHAR(64) NOT NULL,
+                fileChecksum CHAR(64) NOT NULL,
+                time         VARCHAR(30) NOT NULL,
+                CONSTRAINT version_script_pk PRIMARY KEY (appVersion, command, script)
+            )`)
+	if err != nil {
+		return err
+	}
+	for _, s := range scripts {
+		err = db.upsert(conn,
+			`UPDATE version_script SET dbVersion = $4, file = $5, checksum = $6, fileChecksum = $7, time = $8 WHERE appVersion = $1 AND command = $2 AND script = $3`,
+			`INSERT INTO version_script(appVersion, command, script, dbVersion, file, checksum, fileChecksum, time) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+			s.AppVersion, s.Command, s.Script, s.DbVersion, s.File, s.Checksum, s.FileChecksum, db.formatTime(s.Time))
+		if err != nil {
+			return errors.New(fmt.Sprintf("!!! I cannot record the checksum of script '%s': %v\n", s.Script, err))
+		}
+	}
+	return nil
+}
+
+// updates a row or inserts it if the update did not change any row, as upserts are not standard SQL
+// both statements take the same parameters
+func (db *SQLProvider) upsert(conn *sql.DB, update string, insert string, args ...interface{}) error {
+	query, queryArgs := db.bind(update, args...)
+	result, err := conn.Exec(query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	query, queryArgs = db.bind(insert, args...)
+	_, err = conn.Exec(query, queryArgs...)
+	return err
+}
+
+// creates a table if it does not exist, as CREATE TABLE IF NOT EXISTS is not standard SQL
+func (db *SQLProvider) createTable(conn *sql.DB, name string, ddl string) error {
+	if db.tableExists(conn, name) {
+		return nil
+	}
+	if _, err := conn.Exec(ddl); err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the %s table: %v\n", name, err))
+	}
+	return nil
+}
+
+// checks if a table exists by querying it, as the catalog of each database is different
+func (db *SQLProvider) tableExists(conn *sql.DB, name string) bool {
+	rows, err := conn.Query(fmt.Sprintf("SELECT 1 FROM %s WHERE 1 = 0", name))
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// converts the query parameters ($1, $2, ...) to the syntax of the driver dialect
+// returns the converted query and the values of its parameters
+func (db *SQLProvider) bind(query string, args ...interface{}) (string, []interface{}) {
+	if len(args) == 0 {
+		return query, nil
+	}
+	var positional []interface{}
+	converted := RewriteParams(query, func(n int) string {
+		if n < 1 || n > len(args) {
+			return fmt.Sprintf("$%d", n)
+		}
+		if db.dialect.Positional || db.dialect.Param == nil {
+			positional = append(positional, args[n-1])
+			return "?"
+		}
+		return db.dialect.Param(n)
+	}, db.dialect.BackslashEscapes)
+	if db.dialect.Positional || db.dialect.Param == nil {
+		return converted, positional
+	}
+	return converted, args
+}
+
+// puts together a generic table from the rows returned by a query
+func (db *SQLProvider) table(rows *sql.Rows) (*Table, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{Header: columns, Rows: make([]Row, 0)}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(Row, 0, len(columns))
+		for _, value := range values {
+			switch v := value.(type) {
+			case nil:
+				row = append(row, "")
+			case []byte:
+				row = append(row, string(v))
+			case time.Time:
+				row = append(row, v.String())
+			case float64:
+				row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				row = append(row, fmt.Sprintf("%v", v))
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, rows.Err()
+}
+
+// opens a connection to the database using the registered database/sql driver
+// admin: if true, uses Db.AdminDSN if it is set
+func (db *SQLProvider) newConn(admin bool) (*sql.DB, error) {
+	driver, found := db.get("Db.Driver")
+	if !found || len(driver) == 0 {
+		return nil, errors.New("!!! could not find Db.Driver config value\n")
+	}
+	dsn, found := db.get("Db.DSN")
+	if admin {
+		if adminDsn, ok := db.get("Db.AdminDSN"); ok && len(adminDsn) > 0 {
+			dsn, found = adminDsn, true
+		}
+	}
+	if !found || len(dsn) == 0 {
+		return nil, errors.New("!!! could not find Db.DSN config value\n")
+	}
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot use the '%s' driver, available drivers are %s: %v\n", driver, strings.Join(sql.Drivers(), ", "), err))
+	}
+	// a single connection so that the scripts of a command share the same session
+	conn.SetMaxOpenConns(1)
+	if err = conn.Ping(); err != nil {
+		_ = conn.Close()
+		return nil, errors.New(fmt.Sprintf("!!! I cannot connect to the database: %v\n", err))
+	}
+	return conn, nil
+}
+
+func (db *SQLProvider) label(textTrue string, textFalse string, use bool) string {
+	if use {
+		return textTrue
+	}
+	return textFalse
+}
+
+func (db *SQLProvider) get(key string) (string, bool) {
+	return db.cfg.GetString(key)
+}
+
+func (db *SQLProvider) formatTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeFormat)
+}
+
+func (db *SQLProvider) parseTime(value string) time.Time {
+	t, _ := time.Parse(sqlTimeFormat, value)
+	return t
+}
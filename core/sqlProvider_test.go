@@ -0,0 +1,124 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLProviderBind(t *testing.T) {
+	cases := []struct {
+		driver string
+		query  string
+		args   []interface{}
+	}{
+		{"postgres", "SELECT * FROM t WHERE a = $2 AND b = $1 AND c = '$1'", []interface{}{1, 2}},
+		{"sqlite", "SELECT * FROM t WHERE a = ?2 AND b = ?1 AND c = '$1'", []interface{}{1, 2}},
+		{"mysql", "SELECT * FROM t WHERE a = ? AND b = ? AND c = '$1'", []interface{}{2, 1}},
+		{"unknown", "SELECT * FROM t WHERE a = ? AND b = ? AND c = '$1'", []interface{}{2, 1}},
+	}
+	for _, c := range cases {
+		db := &SQLProvider{dialect: sqlDialects[c.driver]}
+		query, args := db.bind("SELECT * FROM t WHERE a = $2 AND b = $1 AND c = '$1'", 1, 2)
+		if query != c.query || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%s: got %s %v, want %s %v", c.driver, query, args, c.query, c.args)
+		}
+	}
+}
+
+// runs the generic provider end to end on the SQLite driver linked in DbMan
+func TestSQLProvider_SQLite(t *testing.T) {
+	config, _ := json.Marshal(map[string]interface{}{"db": map[string]string{"driver": "sqlite", "dsn": filepath.Join(t.TempDir(), "test.db")}})
+	conf, err := NewConf(string(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &SQLProvider{}
+	if err = db.Setup(conf); err != nil {
+		t.Fatal(err)
+	}
+	// setting an existing version again makes it the latest version
+	for _, v := range []*Version{{AppVersion: "0.0.1", DbVersion: "1"}, {AppVersion: "0.0.2", DbVersion: "2"}, {AppVersion: "0.0.1", DbVersion: "1", Description: "downgraded"}} {
+		if err = db.SetVersion(v); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	version, err := db.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.AppVersion != "0.0.1" || version.Description != "downgraded" {
+		t.Fatalf("unexpected version %+v", version)
+	}
+	// the ids of the history entries are generated in sequence
+	start := time.Now().UTC()
+	for i, entry := range []HistoryEntry{
+		{AppVersion: "0.0.1", DbVersion: "1", Command: "create", Success: true},
+		{AppVersion: "0.0.2", DbVersion: "2", Command: "upgrade", Success: false, Error: "failed"},
+		{AppVersion: "0.0.2", DbVersion: "2", Command: "upgrade", Success: true},
+	} {
+		entry.Start, entry.End = start.Add(time.Duration(i)*time.Minute), start.Add(time.Duration(i)*time.Minute+time.Second)
+		if err = db.SetHistory(&entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := db.GetHistory(&HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Id != 3 || entries[2].Id != 1 {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	cases := []struct {
+		filter HistoryFilter
+		ids    []int64
+	}{
+		{HistoryFilter{AppVersion: "0.0.2"}, []int64{3, 2}},
+		{HistoryFilter{Status: "failure"}, []int64{2}},
+		{HistoryFilter{Command: "upgrade", Status: "success"}, []int64{3}},
+		{HistoryFilter{Since: start.Add(time.Minute)}, []int64{3, 2}},
+		{HistoryFilter{Limit: 1}, []int64{3}},
+	}
+	for _, c := range cases {
+		entries, err = db.GetHistory(&c.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, e := range entries {
+			ids = append(ids, e.Id)
+		}
+		if !reflect.DeepEqual(ids, c.ids) {
+			t.Errorf("filter %+v: got ids %v, want %v", c.filter, ids, c.ids)
+		}
+	}
+	// the lock row allows only one instance to hold the lock
+	lock := &Lock{Name: "test", Owner: "test-1", Timeout: 1}
+	if err = db.Lock(lock); err != nil {
+		t.Fatal(err)
+	}
+	other := &SQLProvider{}
+	_ = other.Setup(conf)
+	if err = other.Lock(&Lock{Name: "test", Owner: "test-2", Timeout: 1}); err == nil || !strings.Contains(err.Error(), "test-1") {
+		t.Fatalf("expected an error naming the owner of the lock, got %v", err)
+	}
+	if err = db.Unlock(lock); err != nil {
+		t.Fatal(err)
+	}
+	if err = other.Lock(lock); err != nil {
+		t.Fatal(err)
+	}
+	_ = other.Unlock(lock)
+}
@@ -0,0 +1,649 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	_ "modernc.org/sqlite"
+	"os"
+	"path/filepath"
+	"runtime"
+	. "southwinds.dev/dbman/plugin"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the format of the times stored in SQLite, which does not have a time data type
+// the format has a fixed length so that the times can be sorted as text
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// Implementation of DbMan's database provider for SQLite
+// NOTE:
+//   - SQLiteProvider implicitly implements the DatabaseProvider interface
+//   - the database is a file located by Db.Path, so the host, port and credentials are not used
+//   - the file is created the first time a command connects to the database
+//   - commands that do not use the database (useDb: false) also run against the database file
+type SQLiteProvider struct {
+	cfg *Conf
+	// serialises the callers of Lock in this process
+	local LocalLock
+	// guards lockFile
+	mu sync.Mutex
+	// the lock file held by this instance, if any
+	lockFile string
+}
+
+// pass DbMan configuration to the database provider
+// config: configuration passed-in by DbMan to the plugin
+func (db *SQLiteProvider) Setup(config *Conf) error {
+	if config != nil {
+		db.cfg = config
+		return nil
+	}
+	return errors.New("!!! the SQLite Database plugin was not provided with a valid configuration\n")
+}
+
+// this function retrieves database version information in a Version struct
+// Version: the current database version, nil if the version table is empty
+// error: if failed to retrieve the version from the database, e.g. if the version table does not exist
+func (db *SQLiteProvider) GetVersion() (*Version, error) {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var (
+		v           = &Version{}
+		versionTime string
+	)
+	err = conn.QueryRow(`
+		SELECT appVersion, dbVersion, COALESCE(description, ''), time, COALESCE(source, '')
+		FROM version
+		ORDER BY time DESC
+		LIMIT 1`).Scan(&v.AppVersion, &v.DbVersion, &v.Description, &versionTime, &v.Source)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.Time = db.parseTime(versionTime)
+	return v, nil
+}
+
+// this function runs a database command
+// command: the struct containing the information required to run the command
+// as SQLite does not have users, the command always runs with the permissions of DbMan on the database file
+func (db *SQLiteProvider) RunCommand(command *Command) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	log := bytes.Buffer{}
+	// SQLite does not have a server to connect to, so commands that do not use the database (e.g. setting pragmas
+	// stored in the file) also run against the database file, rather than against a throwaway in-memory database
+	conn, err := db.newConn(true)
+	if err != nil {
+		return log, err
+	}
+	defer conn.Close()
+	log.WriteString(fmt.Sprintf("? I am creating a db connection that is %v and to the db file%v\n",
+		db.label("transactional", "non-transactional", command.Transactional),
+		db.label("", ", as SQLite does not have a server", command.UseDb)))
+	if command.Transactional {
+		tx, err := conn.Begin()
+		if err != nil {
+			return log, err
+		}
+		for _, script := range command.Scripts {
+			_, err = tx.Exec(script.Content)
+			log.WriteString(fmt.Sprintf("? I have executed the script '%s'\n", script.Name))
+			if err != nil {
+				_ = tx.Rollback()
+				return log, fmt.Errorf("failed to execute script: %s, %s", script.File, err)
+			}
+		}
+		return log, tx.Commit()
+	}
+	for _, script := range command.Scripts {
+		if _, err = conn.Exec(script.Content); err != nil {
+			return log, fmt.Errorf("failed to execute script: %s, %s", script.File, err)
+		}
+	}
+	return log, nil
+}
+
+// this function runs a database query
+// query: the struct containing the information required to run the query
+func (db *SQLiteProvider) RunQuery(query *Query) (*Table, error) {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args, err := query.BoundArgs()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite numbered parameters are ?1, ?2, ...
+	content := RewriteParams(query.Content, func(n int) string { return fmt.Sprintf("?%d", n) }, false)
+	rows, err := conn.Query(content, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return db.table(rows)
+}
+
+// this function sets the version in the database
+// version: struct containing version information to persist in the database
+func (db *SQLiteProvider) SetVersion(version *Version) error {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS version
+            (
+                appVersion  TEXT NOT NULL,
+                dbVersion   TEXT NOT NULL,
+                description TEXT,
+                time        TEXT NOT NULL,
+                source      TEXT,
+                CONSTRAINT version_app_version_db_release_pk PRIMARY KEY (appVersion, dbVersion)
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the version table: %v\n", err))
+	}
+	// if the version was already there (e.g. after a downgrade), update the entry so that it becomes the latest version
+	_, err = conn.Exec(`INSERT INTO version(appVersion, dbVersion, description, source, time) VALUES(?, ?, ?, ?, ?)
+			ON CONFLICT (appVersion, dbVersion) DO UPDATE
+			SET description = excluded.description, source = excluded.source, time = excluded.time`,
+		version.AppVersion, version.DbVersion, version.Description, version.Source, db.formatTime(time.Now()))
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the version table: %v\n", err))
+	}
+	// record the checksums of the scripts executed for the release
+	return db.setChecksums(conn, version.Scripts)
+}
+
+// this function retrieves the checksums of the scripts executed for all applied releases
+func (db *SQLiteProvider) GetChecksums() ([]ScriptChecksum, error) {
+	checksums := make([]ScriptChecksum, 0)
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// if no checksums have been recorded yet, there is nothing to return
+	exists, err := db.tableExists(conn, "version_script")
+	if err != nil || !exists {
+		return checksums, err
+	}
+	rows, err := conn.Query(`
+		SELECT appVersion, dbVersion, command, script, file, checksum, fileChecksum, time
+		FROM version_script
+		ORDER BY time, appVersion, command, script`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the version_script table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			c          = ScriptChecksum{}
+			scriptTime string
+		)
+		if err = rows.Scan(&c.AppVersion, &c.DbVersion, &c.Command, &c.Script, &c.File, &c.Checksum, &c.FileChecksum, &scriptTime); err != nil {
+			return nil, err
+		}
+		c.Time = db.parseTime(scriptTime)
+		checksums = append(checksums, c)
+	}
+	return checksums, rows.Err()
+}
+
+// returns information about the SQLite library as a DbInfo struct
+func (db *SQLiteProvider) GetInfo() (*DbInfo, error) {
+	conn, err := db.newConn(false)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var version string
+	if err = conn.QueryRow(`SELECT sqlite_version()`).Scan(&version); err != nil {
+		return nil, err
+	}
+	return &DbInfo{
+		Database:        fmt.Sprintf("SQLite %s", version),
+		OperatingSystem: fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+		Compiler:        fmt.Sprintf("embedded in DbMan, built with %s", runtime.Version()),
+		ProcessorBits:   fmt.Sprintf("%d-bit", strconv.IntSize),
+	}, nil
+}
+
+// this function takes a backup of the database into a new database file using VACUUM INTO
+// backup: the backup metadata, populated with the database version information and size of the backup
+func (db *SQLiteProvider) Backup(backup *Backup) (bytes.Buffer, error) {
+	log := bytes.Buffer{}
+	switch strings.ToLower(backup.Format) {
+	case "", "file":
+		backup.Format = "file"
+	default:
+		return log, errors.New(fmt.Sprintf("!!! I do not support backup format '%s', try file\n", backup.Format))
+	}
+	// record the version of the database being backed up
+	version, err := db.GetVersion()
+	if err != nil || version == nil {
+		log.WriteString(fmt.Sprintf("! I cannot find any version information for the database, the backup will not record a version\n"))
+	} else {
+		backup.AppVersion = version.AppVersion
+		backup.DbVersion = version.DbVersion
+	}
+	backup.Database, _ = db.get("Db.Name")
+	conn, err := db.newConn(true)
+	if err != nil {
+		return log, err
+	}
+	defer conn.Close()
+	log.WriteString(fmt.Sprintf("? I am copying database '%s' to '%s'\n", backup.Database, backup.Path))
+	// VACUUM INTO writes a consistent copy of the database even if it is being changed
+	if _, err = conn.Exec(`VACUUM INTO ?`, backup.Path); err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot copy the database: %v\n", err))
+	}
+	info, err := os.Stat(backup.Path)
+	if err != nil {
+		return log, err
+	}
+	backup.Size = info.Size()
+	return log, nil
+}
+
+// this function restores the database by replacing the database file with the backup copy
+// backup: the metadata of the backup to restore
+func (db *SQLiteProvider) Restore(backup *Backup) (bytes.Buffer, error) {
+	log := bytes.Buffer{}
+	path, err := db.path()
+	if err != nil {
+		return log, err
+	}
+	content, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot read the database copy for backup '%s': %v\n", backup.Name, err))
+	}
+	log.WriteString(fmt.Sprintf("? I am restoring backup '%s' from '%s'\n", backup.Name, backup.Path))
+	// writes to a temporary file first so that the database is replaced in one go
+	tmp := path + ".restore"
+	if err = os.WriteFile(tmp, content, 0600); err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot restore the database: %v\n", err))
+	}
+	// removes the journal files of the database being replaced, as they do not apply to the restored database
+	for _, journal := range []string{"-journal", "-wal", "-shm"} {
+		_ = os.Remove(path + journal)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return log, errors.New(fmt.Sprintf("!!! I cannot restore the database: %v\n", err))
+	}
+	return log, nil
+}
+
+// this function acquires a lock preventing other DbMan instances from changing the database
+// the lock is a file next to the database file, holding the owner of the lock
+// lock: the lock information, if held by another instance the function waits up to the lock timeout
+func (db *SQLiteProvider) Lock(lock *Lock) error {
+	deadline := time.Now().Add(time.Duration(lock.Timeout) * time.Second)
+	// the lock is not re-entrant, wait for other callers in this instance to release it
+	if !db.local.Acquire(deadline) {
+		return errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by another operation of this instance\n", lock.Name, lock.Timeout))
+	}
+	lockFile, err := db.lockPath(lock, deadline)
+	if err != nil {
+		db.local.Release()
+		return err
+	}
+	db.mu.Lock()
+	db.lockFile = lockFile
+	db.mu.Unlock()
+	return nil
+}
+
+// creates the lock file, waiting until the deadline if another instance holds it
+func (db *SQLiteProvider) lockPath(lock *Lock, deadline time.Time) (string, error) {
+	path, err := db.path()
+	if err != nil {
+		return "", err
+	}
+	lockFile := path + ".lock"
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = f.WriteString(fmt.Sprintf("%s since %s\n", lock.Owner, time.Now().UTC().Format(time.RFC3339)))
+			_ = f.Close()
+			if err != nil {
+				_ = os.Remove(lockFile)
+				return "", errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s': %v\n", lock.Name, err))
+			}
+			return lockFile, nil
+		}
+		if !os.IsExist(err) {
+			return "", errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s': %v\n", lock.Name, err))
+		}
+		// if the lock timeout has elapsed, find out who is holding the lock
+		if time.Now().After(deadline) {
+			holder, _ := os.ReadFile(lockFile)
+			return "", errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by %s\n"+
+				"If the instance holding the lock is no longer running, delete the lock file '%s'\n", lock.Name, lock.Timeout, strings.TrimSpace(string(holder)), lockFile))
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// this function releases the lock acquired by Lock
+func (db *SQLiteProvider) Unlock(lock *Lock) error {
+	db.mu.Lock()
+	lockFile := db.lockFile
+	db.lockFile = ""
+	db.mu.Unlock()
+	// nothing to release
+	if len(lockFile) == 0 {
+		return nil
+	}
+	// let the next caller in this instance acquire the lock
+	defer db.local.Release()
+	if err := os.Remove(lockFile); err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot release the lock on database '%s': %v\n", lock.Name, err))
+	}
+	return nil
+}
+
+// this function records the execution of a release command in the command_history table
+func (db *SQLiteProvider) SetHistory(entry *HistoryEntry) error {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS command_history
+            (
+                id         INTEGER PRIMARY KEY AUTOINCREMENT,
+                appVersion TEXT NOT NULL,
+                dbVersion  TEXT NOT NULL,
+                command    TEXT NOT NULL,
+                scripts    TEXT,
+                start_time TEXT NOT NULL,
+                end_time   TEXT NOT NULL,
+                duration   INTEGER NOT NULL,
+                success    INTEGER NOT NULL,
+                error      TEXT,
+                username   TEXT,
+                host       TEXT
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the command_history table: %v\n", err))
+	}
+	// SQLite does not have arrays so the script names are stored as a JSON array
+	scripts, err := json.Marshal(entry.Scripts)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(
+		`INSERT INTO command_history(appVersion, dbVersion, command, scripts, start_time, end_time, duration, success, error, username, host)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.AppVersion, entry.DbVersion, entry.Command, string(scripts), db.formatTime(entry.Start), db.formatTime(entry.End), entry.Duration, entry.Success, entry.Error, entry.User, entry.Host)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the command_history table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the command execution history entries matching the filter, most recent first
+func (db *SQLiteProvider) GetHistory(filter *HistoryFilter) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// if no command has been recorded yet, there is nothing to return
+	exists, err := db.tableExists(conn, "command_history")
+	if err != nil || !exists {
+		return entries, err
+	}
+	// build the where clause from the filter
+	var (
+		where []string
+		args  []interface{}
+	)
+	if len(filter.AppVersion) > 0 {
+		where, args = append(where, "appVersion = ?"), append(args, filter.AppVersion)
+	}
+	if len(filter.Command) > 0 {
+		where, args = append(where, "command = ?"), append(args, filter.Command)
+	}
+	if len(filter.Status) > 0 {
+		where, args = append(where, "success = ?"), append(args, strings.EqualFold(filter.Status, "success"))
+	}
+	if !filter.Since.IsZero() {
+		where, args = append(where, "start_time >= ?"), append(args, db.formatTime(filter.Since))
+	}
+	query := `SELECT id, appVersion, dbVersion, command, COALESCE(scripts, '[]'), start_time, end_time, duration, success, COALESCE(error, ''), COALESCE(username, ''), COALESCE(host, '')
+		FROM command_history`
+	if len(where) > 0 {
+		query += fmt.Sprintf(" WHERE %s", strings.Join(where, " AND "))
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the command_history table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			e                           = HistoryEntry{}
+			scripts, startTime, endTime string
+		)
+		err = rows.Scan(&e.Id, &e.AppVersion, &e.DbVersion, &e.Command, &scripts, &startTime, &endTime, &e.Duration, &e.Success, &e.Error, &e.User, &e.Host)
+		if err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(scripts), &e.Scripts)
+		e.Start, e.End = db.parseTime(startTime), db.parseTime(endTime)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// this function records the progress of an upgrade in the upgrade_progress table
+// the table holds a single row with the progress of the last upgrade
+func (db *SQLiteProvider) SetProgress(progress *Progress) error {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS upgrade_progress
+            (
+                id       INTEGER PRIMARY KEY CHECK (id = 1),
+                progress TEXT NOT NULL,
+                time     TEXT NOT NULL
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the upgrade_progress table: %v\n", err))
+	}
+	_, err = conn.Exec(`INSERT INTO upgrade_progress(id, progress, time) VALUES(1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET progress = excluded.progress, time = excluded.time`, progress.ToString(), db.formatTime(time.Now()))
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the upgrade_progress table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the progress of the last upgrade, nil if no upgrade has been recorded
+func (db *SQLiteProvider) GetProgress() (*Progress, error) {
+	conn, err := db.newConn(true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	exists, err := db.tableExists(conn, "upgrade_progress")
+	if err != nil || !exists {
+		return nil, err
+	}
+	var progress string
+	err = conn.QueryRow(`SELECT progress FROM upgrade_progress WHERE id = 1`).Scan(&progress)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the upgrade_progress table: %v\n", err))
+	}
+	return NewProgress(progress)
+}
+
+// =========================================================================
+// UTILITY FUNCTIONS
+// =========================================================================
+
+// records the checksums of the scripts executed for a release in the version_script table
+// if a script is applied again (e.g. after a downgrade), its checksum is replaced
+func (db *SQLiteProvider) setChecksums(conn *sql.DB, scripts []ScriptChecksum) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+	_, err := conn.Exec(`CREATE TABLE IF NOT EXISTS version_script
+            (
+                appVersion   TEXT NOT NULL,
+                dbVersion    TEXT NOT NULL,
+                command      TEXT NOT NULL,
+                script       TEXT NOT NULL,
+                file         TEXT NOT NULL,
+                checksum     TEXT NOT NULL,
+                fileChecksum TEXT NOT NULL,
+                time         TEXT NOT NULL,
+                CONSTRAINT version_script_pk PRIMARY KEY (appVersion, command, script)
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the version_script table: %v\n", err))
+	}
+	for _, s := range scripts {
+		_, err = conn.Exec(`INSERT INTO version_script(appVersion, dbVersion, command, script, file, checksum, fileChecksum, time) VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (appVersion, command, script) DO UPDATE
+			SET dbVersion = excluded.dbVersion, file = excluded.file, checksum = excluded.checksum, fileChecksum = excluded.fileChecksum, time = excluded.time`,
+			s.AppVersion, s.DbVersion, s.Command, s.Script, s.File, s.Checksum, s.FileChecksum, db.formatTime(s.Time))
+		if err != nil {
+			return errors.New(fmt.Sprintf("!!! I cannot record the checksum of script '%s': %v\n", s.Script, err))
+		}
+	}
+	return nil
+}
+
+// checks if a table exists in the database
+func (db *SQLiteProvider) tableExists(conn *sql.DB, name string) (bool, error) {
+	var count int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	return count > 0, err
+}
+
+// puts together a generic table from the rows returned by a query
+func (db *SQLiteProvider) table(rows *sql.Rows) (*Table, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{Header: columns, Rows: make([]Row, 0)}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(Row, 0, len(columns))
+		for _, value := range values {
+			switch v := value.(type) {
+			case nil:
+				row = append(row, "")
+			case []byte:
+				row = append(row, string(v))
+			case time.Time:
+				row = append(row, v.String())
+			case float64:
+				row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				row = append(row, fmt.Sprintf("%v", v))
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, rows.Err()
+}
+
+// returns the path to the database file
+func (db *SQLiteProvider) path() (string, error) {
+	path, found := db.get("Db.Path")
+	if !found || len(path) == 0 {
+		return "", errors.New("!!! could not find Db.Path config value\n")
+	}
+	return path, nil
+}
+
+// opens a connection to the database file
+// database: if false, opens an in-memory database instead, after checking the directory of the database file exists,
+// so that the connection can be checked without creating the database file
+func (db *SQLiteProvider) newConn(database bool) (*sql.DB, error) {
+	path, err := db.path()
+	if err != nil {
+		return nil, err
+	}
+	dsn := ":memory:"
+	if database {
+		dsn = path
+	} else if _, err = os.Stat(filepath.Dir(path)); err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot find the directory of the database file '%s': %v\n", path, err))
+	}
+	// waits for other connections writing to the database rather than failing straight away
+	conn, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", dsn))
+	if err != nil {
+		return nil, err
+	}
+	// a single connection so that the scripts of a command share pragmas and temporary objects
+	conn.SetMaxOpenConns(1)
+	if err = conn.Ping(); err != nil {
+		_ = conn.Close()
+		return nil, errors.New(fmt.Sprintf("!!! I cannot open the database file '%s': %v\n", path, err))
+	}
+	return conn, nil
+}
+
+func (db *SQLiteProvider) label(textTrue string, textFalse string, use bool) string {
+	if use {
+		return textTrue
+	}
+	return textFalse
+}
+
+func (db *SQLiteProvider) get(key string) (string, bool) {
+	return db.cfg.GetString(key)
+}
+
+func (db *SQLiteProvider) formatTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+func (db *SQLiteProvider) parseTime(value string) time.Time {
+	t, _ := time.Parse(sqliteTimeFormat, value)
+	return t
+}
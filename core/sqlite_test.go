@@ -0,0 +1,199 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	. "southwinds.dev/dbman/plugin"
+	"testing"
+	"time"
+)
+
+// creates a SQLite provider for a database file in a temporary directory
+func newTestSQLite(t *testing.T) *SQLiteProvider {
+	path := filepath.Join(t.TempDir(), "test.db")
+	config, _ := json.Marshal(map[string]interface{}{"db": map[string]string{"path": path, "name": "test"}})
+	conf, err := NewConf(string(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &SQLiteProvider{}
+	if err = db.Setup(conf); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLiteProvider_Version(t *testing.T) {
+	db := newTestSQLite(t)
+	if _, err := db.GetVersion(); err == nil {
+		t.Fatal("expected an error as the version table does not exist")
+	}
+	scripts := []ScriptChecksum{{AppVersion: "0.0.1", DbVersion: "1", Command: "create", Script: "schema", File: "schema.sql", Checksum: "abc", Time: time.Now()}}
+	if err := db.SetVersion(&Version{AppVersion: "0.0.1", DbVersion: "1", Description: "created", Source: "test", Scripts: scripts}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetVersion(&Version{AppVersion: "0.0.2", DbVersion: "2", Description: "upgraded"}); err != nil {
+		t.Fatal(err)
+	}
+	// setting an existing version again makes it the latest version
+	if err := db.SetVersion(&Version{AppVersion: "0.0.1", DbVersion: "1", Description: "downgraded"}); err != nil {
+		t.Fatal(err)
+	}
+	version, err := db.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.AppVersion != "0.0.1" || version.Description != "downgraded" {
+		t.Fatalf("unexpected version %+v", version)
+	}
+	checksums, err := db.GetChecksums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksums) != 1 || checksums[0].Checksum != "abc" {
+		t.Fatalf("unexpected checksums %+v", checksums)
+	}
+}
+
+func TestSQLiteProvider_RunCommand(t *testing.T) {
+	db := newTestSQLite(t)
+	create := &Command{Name: "create", UseDb: true, Scripts: []Script{{Name: "table", Content: "CREATE TABLE person(name TEXT NOT NULL, age INTEGER)"}}}
+	if _, err := db.RunCommand(create); err != nil {
+		t.Fatal(err)
+	}
+	// a failed script rolls back the scripts executed before it in a transactional command
+	insert := &Command{Name: "insert", UseDb: true, Transactional: true, Scripts: []Script{
+		{Name: "insert", Content: "INSERT INTO person(name, age) VALUES('John Smith', 42)"},
+		{Name: "invalid", Content: "INSERT INTO person(name) VALUES(NULL)"},
+	}}
+	if _, err := db.RunCommand(insert); err == nil {
+		t.Fatal("expected an error for the invalid script")
+	}
+	count := &Query{Name: "count", Content: "SELECT COUNT(*) FROM person"}
+	if table, err := db.RunQuery(count); err != nil || table.Rows[0][0] != "0" {
+		t.Fatalf("the transaction was not rolled back: %v, %v", table, err)
+	}
+	insert.Scripts = insert.Scripts[:1]
+	if _, err := db.RunCommand(insert); err != nil {
+		t.Fatal(err)
+	}
+	// commands that do not use the database run against the database file
+	pragma := &Command{Name: "pragma", UseDb: false, Scripts: []Script{{Name: "version", Content: "PRAGMA user_version = 7"}}}
+	if _, err := db.RunCommand(pragma); err != nil {
+		t.Fatal(err)
+	}
+	if table, err := db.RunQuery(&Query{Name: "version", Content: "PRAGMA user_version"}); err != nil || table.Rows[0][0] != "7" {
+		t.Fatalf("the command did not run against the database file: %v, %v", table, err)
+	}
+}
+
+func TestSQLiteProvider_RunQuery(t *testing.T) {
+	db := newTestSQLite(t)
+	create := &Command{Name: "create", UseDb: true, Scripts: []Script{{Name: "table", Content: `
+		CREATE TABLE person(name TEXT, age INTEGER);
+		INSERT INTO person VALUES('John Smith', 42), ('Jane Doe', 35), ('$1', 20)`}}}
+	if _, err := db.RunCommand(create); err != nil {
+		t.Fatal(err)
+	}
+	// the parameters are bound in the order of their numbers, and are not replaced in strings
+	query := &Query{
+		Name:    "person",
+		Content: "SELECT name, age FROM person WHERE age > $2 AND name <> '$1' AND name LIKE '%' || $1 || '%' ORDER BY age",
+		Args:    []QueryArg{{Name: "name", Value: "o"}, {Name: "age", Type: "int", Value: "30"}},
+	}
+	table, err := db.RunQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 2 || table.Header[0] != "name" || table.Rows[0][0] != "Jane Doe" || table.Rows[1][1] != "42" {
+		t.Fatalf("unexpected result %+v", table)
+	}
+	query.Args[1].Value = "thirty"
+	if _, err = db.RunQuery(query); err == nil {
+		t.Fatal("expected an error for an invalid int value")
+	}
+}
+
+func TestSQLiteProvider_Lock(t *testing.T) {
+	db := newTestSQLite(t)
+	lock := &Lock{Name: "test", Owner: "test-1", Timeout: 1}
+	if err := db.Lock(lock); err != nil {
+		t.Fatal(err)
+	}
+	// another instance waits up to the timeout
+	other := &SQLiteProvider{cfg: db.cfg}
+	started := time.Now()
+	if err := other.Lock(&Lock{Name: "test", Owner: "test-2", Timeout: 1}); err == nil {
+		t.Fatal("expected an error as the lock is held by another instance")
+	}
+	if time.Since(started) < time.Second {
+		t.Fatal("the lock was not waited for")
+	}
+	// another caller in the same instance acquires the lock when it is released
+	acquired := make(chan error)
+	go func() {
+		acquired <- db.Lock(&Lock{Name: "test", Owner: "test-3", Timeout: 5})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := db.Unlock(lock); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Unlock(lock); err != nil {
+		t.Fatal(err)
+	}
+	// nothing to release
+	if err := db.Unlock(lock); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Lock(lock); err != nil {
+		t.Fatal(err)
+	}
+	_ = other.Unlock(lock)
+}
+
+func TestSQLiteProvider_BackupRestore(t *testing.T) {
+	db := newTestSQLite(t)
+	create := &Command{Name: "create", UseDb: true, Scripts: []Script{{Name: "table", Content: "CREATE TABLE person(name TEXT); INSERT INTO person VALUES('John Smith')"}}}
+	if _, err := db.RunCommand(create); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetVersion(&Version{AppVersion: "0.0.1", DbVersion: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	backup := &Backup{Name: "test", Path: filepath.Join(t.TempDir(), "test.db")}
+	if _, err := db.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.AppVersion != "0.0.1" || backup.DbVersion != "1" || backup.Format != "file" || backup.Size == 0 {
+		t.Fatalf("unexpected backup metadata %+v", backup)
+	}
+	if _, err := db.Backup(&Backup{Name: "test", Path: backup.Path, Format: "directory"}); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+	// changes after the backup are lost when it is restored
+	drop := &Command{Name: "drop", UseDb: true, Scripts: []Script{{Name: "drop", Content: "DROP TABLE person"}}}
+	if _, err := db.RunCommand(drop); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Restore(backup); err != nil {
+		t.Fatal(err)
+	}
+	table, err := db.RunQuery(&Query{Name: "person", Content: "SELECT name FROM person"})
+	if err != nil || len(table.Rows) != 1 || table.Rows[0][0] != "John Smith" {
+		t.Fatalf("the backup was not restored: %v, %v", table, err)
+	}
+	if _, err = os.Stat(backup.Path); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	. "southwinds.dev/dbman/plugin"
+	"strings"
+)
+
+// ValidationReport the result of validating the release plan and the release manifests
+type ValidationReport struct {
+	// the number of releases validated
+	Releases int `json:"releases"`
+	// the number of issues that would make a release fail
+	Errors int `json:"errors"`
+	// the number of issues that should be reviewed
+	Warnings int `json:"warnings"`
+	// the issues found
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// ValidationIssue a problem found in the release plan or a release manifest
+type ValidationIssue struct {
+	// the application version of the release with the issue, empty for plan issues
+	AppVersion string `json:"appVersion,omitempty"`
+	// either error or warning
+	Severity string `json:"severity"`
+	// the name of the check that found the issue
+	Check string `json:"check"`
+	// a description of the issue
+	Message string `json:"message"`
+}
+
+// Valid returns true if no errors have been found
+func (r *ValidationReport) Valid() bool {
+	return r.Errors == 0
+}
+
+// Table returns the issues as a table
+func (r *ValidationReport) Table() *Table {
+	table := &Table{Header: Row{"appVersion", "severity", "check", "message"}}
+	for _, issue := range r.Issues {
+		table.Rows = append(table.Rows, Row{issue.AppVersion, issue.Severity, issue.Check, issue.Message})
+	}
+	return table
+}
+
+func (r *ValidationReport) add(appVersion string, severity string, check string, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		AppVersion: appVersion,
+		Severity:   severity,
+		Check:      check,
+		Message:    fmt.Sprintf(format, args...),
+	})
+	if severity == "error" {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// validates the release plan and the manifests of all its releases
+func (s *ScriptManager) validate() (*ValidationReport, error) {
+	plan, err := s.fetchPlan()
+	if err != nil {
+		return nil, err
+	}
+	report := &ValidationReport{Releases: len(plan.Releases)}
+	appVersions := make(map[string]bool)
+	for ix, release := range plan.Releases {
+		if len(release.AppVersion) == 0 {
+			report.add("", "error", "plan", "release %d in the plan does not have an appVersion", ix+1)
+		} else if appVersions[release.AppVersion] {
+			report.add(release.AppVersion, "error", "duplicate-release", "the application version is defined more than once in the plan")
+		}
+		appVersions[release.AppVersion] = true
+		if len(release.Path) == 0 {
+			report.add(release.AppVersion, "error", "plan", "the release does not have a path")
+		}
+		if _, err = parseVersion(release.AppVersion); err != nil && len(release.AppVersion) > 0 {
+			report.add(release.AppVersion, "warning", "semver", "the application version is not a semantic version, so it is ordered as text")
+		}
+		if _, err = parseVersion(release.DbVersion); err != nil {
+			report.add(release.AppVersion, "warning", "semver", "the db version '%s' is not a semantic version, so it is ordered as text", release.DbVersion)
+		}
+		// releases are ordered by version but a plan out of order is likely a mistake
+		if ix > 0 && len(release.UpgradeFrom) == 0 && compareVersions(release.AppVersion, plan.Releases[ix-1].AppVersion) < 0 {
+			report.add(release.AppVersion, "warning", "plan-order", "the release is listed after the higher version %s, releases are upgraded in version order "+
+				"so, if it is a patch of an older release line, consider adding the versions it upgrades from to its upgradeFrom list", plan.Releases[ix-1].AppVersion)
+		}
+		s.validateUpgradeFrom(report, plan, release)
+		s.validateRelease(report, release)
+	}
+	return report, nil
+}
+
+// validates the releases a release can be upgraded from
+func (s *ScriptManager) validateUpgradeFrom(report *ValidationReport, plan *Plan, release Info) {
+	for _, appVersion := range plan.predecessors(release) {
+		previous, _ := plan.info(appVersion)
+		if previous == nil {
+			report.add(release.AppVersion, "error", "upgrade-from", "the release upgrades from application version %s, which is not in the plan", appVersion)
+			continue
+		}
+		if compareVersions(appVersion, release.AppVersion) >= 0 {
+			report.add(release.AppVersion, "error", "upgrade-from", "the release upgrades from application version %s, which is not lower than its own", appVersion)
+		}
+		// db versions can stay the same across application versions but must not go backwards
+		if compareVersions(release.DbVersion, previous.DbVersion) < 0 {
+			report.add(release.AppVersion, "error", "db-version-order", "the db version %s is lower than the db version %s of release %s it upgrades from",
+				release.DbVersion, previous.DbVersion, previous.AppVersion)
+		}
+	}
+}
+
+// validates the manifest of a release and the files it references
+func (s *ScriptManager) validateRelease(report *ValidationReport, release Info) {
+	appVer := release.AppVersion
+	_, content, err := s.find(release.Path, manifestFiles)
+	if err != nil {
+		report.add(appVer, "error", "missing-file", "I cannot read the release manifest: %s", strings.TrimSpace(err.Error()))
+		return
+	}
+	manifest, err := new(Manifest).Decode(content)
+	if err != nil {
+		report.add(appVer, "error", "manifest", "I cannot decode the release manifest: %v", err)
+		return
+	}
+	if manifest.DbVersion != release.DbVersion {
+		report.add(appVer, "error", "db-version", "the manifest db version %s does not match the plan db version %s", manifest.DbVersion, release.DbVersion)
+	}
+	if msg := checkProvider(manifest.DbProvider, pluginSearchPath(s.cfg)); len(msg) > 0 {
+		report.add(appVer, "error", "db-provider", "%s", msg)
+	}
+	if _, err := mergeMode("", manifest.Merge, ""); err != nil {
+		report.add(appVer, "error", "merge-mode", "the manifest has merge mode '%s', valid modes are template and replace", manifest.Merge)
+	}
+	// commands
+	commands := make(map[string]bool)
+	for _, command := range manifest.Commands {
+		if commands[command.Name] {
+			report.add(appVer, "error", "duplicate-name", "the command '%s' is defined more than once", command.Name)
+		}
+		commands[command.Name] = true
+		scripts := make(map[string]bool)
+		for _, script := range command.Scripts {
+			if scripts[script.Name] {
+				report.add(appVer, "error", "duplicate-name", "the script '%s' is defined more than once in command '%s'", script.Name, command.Name)
+			}
+			scripts[script.Name] = true
+			s.validateFile(report, appVer, path.Join(release.Path, manifest.CommandsPath), script.File, script.Vars,
+				manifest.Merge, script.Merge, fmt.Sprintf("script '%s' in command '%s'", script.Name, command.Name))
+		}
+	}
+	// command references
+	refs := map[string][]string{
+		"create":            manifest.Create.Commands,
+		"deploy":            manifest.Deploy.Commands,
+		"upgrade prepare":   {manifest.Upgrade.Prepare},
+		"upgrade alter":     {manifest.Upgrade.Alter},
+		"upgrade deploy":    {manifest.Upgrade.Deploy},
+		"downgrade prepare": {manifest.Downgrade.Prepare},
+		"downgrade revert":  {manifest.Downgrade.Revert},
+		"downgrade deploy":  {manifest.Downgrade.Deploy},
+	}
+	for _, action := range []string{"create", "deploy", "upgrade prepare", "upgrade alter", "upgrade deploy", "downgrade prepare", "downgrade revert", "downgrade deploy"} {
+		for _, name := range refs[action] {
+			if len(name) > 0 && !commands[name] {
+				report.add(appVer, "error", "unresolved-command", "the %s action refers to command '%s', which is not defined", action, name)
+			}
+		}
+	}
+	// queries
+	queries := make(map[string]bool)
+	for _, query := range manifest.Queries {
+		if queries[query.Name] {
+			report.add(appVer, "error", "duplicate-name", "the query '%s' is defined more than once", query.Name)
+		}
+		queries[query.Name] = true
+		s.validateFile(report, appVer, path.Join(release.Path, manifest.QueriesPath), query.File, query.Vars,
+			manifest.Merge, query.Merge, fmt.Sprintf("query '%s'", query.Name))
+	}
+}
+
+// validates that a script file exists and that its placeholders match the declared merge variables
+// manifestMerge, merge: the merge modes of the manifest and of the script
+func (s *ScriptManager) validateFile(report *ValidationReport, appVer string, dir string, file string, vars []Var, manifestMerge string, merge string, owner string) {
+	if len(file) == 0 {
+		report.add(appVer, "error", "missing-file", "the %s does not specify a file", owner)
+		return
+	}
+	content, err := s.read(path.Join(dir, file))
+	if err != nil {
+		report.add(appVer, "error", "missing-file", "I cannot read the file %s of the %s: %s", file, owner, strings.TrimSpace(err.Error()))
+		return
+	}
+	values := make(map[string]string)
+	for _, v := range vars {
+		values[v.Name] = ""
+		if len(v.FromConf) == 0 && len(v.FromValue) == 0 && len(v.FromInput) == 0 && len(v.FromContext) == 0 && len(v.Default) == 0 {
+			report.add(appVer, "warning", "var-source", "the variable '%s' of the %s does not define where its value comes from", v.Name, owner)
+		}
+		s.validateVarType(report, appVer, v, owner)
+	}
+	mode, err := mergeMode(file, manifestMerge, merge)
+	if err != nil {
+		// an invalid merge mode of the manifest is reported once for the manifest
+		if len(merge) > 0 {
+			report.add(appVer, "error", "merge-mode", "the %s has merge mode '%s', valid modes are template and replace", owner, merge)
+		}
+		return
+	}
+	used := make(map[string]bool)
+	// scripts merged by replacing the placeholders are not templates, so only the placeholders are checked
+	if mode == mergeTemplate {
+		t, err := parseScript(file, string(content), values, nil)
+		if err != nil {
+			report.add(appVer, "error", "unresolved-var", "the %s cannot be merged: %s", owner, strings.TrimSpace(strings.TrimPrefix(err.Error(), "!!! ")))
+			return
+		}
+		used = scriptIdentifiers(t)
+	}
+	for _, v := range vars {
+		if !used[v.Name] && !strings.Contains(string(content), fmt.Sprintf("{{%s}}", v.Name)) {
+			report.add(appVer, "warning", "unused-var", "the variable '%s' of the %s is not used in %s", v.Name, owner, file)
+		}
+	}
+}
+
+// validates the type of a merge variable and its default value
+func (s *ScriptManager) validateVarType(report *ValidationReport, appVer string, v Var, owner string) {
+	switch strings.ToLower(v.Type) {
+	case "", "text", "int", "bool", "date", "uuid":
+	case "enum":
+		if len(v.Values) == 0 {
+			report.add(appVer, "error", "var-type", "the enum variable '%s' of the %s does not define its values", v.Name, owner)
+			return
+		}
+	case "regex":
+		if _, err := regexp.Compile(v.Pattern); err != nil || len(v.Pattern) == 0 {
+			report.add(appVer, "error", "var-type", "the regex variable '%s' of the %s does not define a valid pattern", v.Name, owner)
+			return
+		}
+	default:
+		report.add(appVer, "error", "var-type", "the variable '%s' of the %s has type '%s', valid types are int, bool, date, uuid, text, enum and regex", v.Name, owner, v.Type)
+		return
+	}
+	if len(v.Default) > 0 {
+		if err := v.Check(v.Default); err != nil {
+			report.add(appVer, "error", "var-type", "the default value of the %s is not valid: %v", owner, err)
+		}
+	}
+}
+
+// returns a message if the database provider is not known, or an empty string otherwise
+// searchPath: the directories where database provider plugins are searched for
+func checkProvider(name string, searchPath []string) string {
+	if len(name) == 0 {
+		return "the manifest does not specify a database provider"
+	}
+	if strings.HasPrefix(name, "_") {
+		for _, native := range nativeProviders {
+			if strings.EqualFold(name, native) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("'%s' is not a native database provider, available native providers are %s", name, strings.Join(nativeProviders, ", "))
+	}
+	if _, err := findPlugin(searchPath, name); err != nil {
+		return fmt.Sprintf("I cannot find the database provider plugin '%s' in %s", pluginFile(name), strings.Join(searchPath, ", "))
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package core
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"0.0.4", "0.0.4", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.2.10", "1.10.0", -1},
+		{"v2.0.0", "1.9.9", 1},
+		{"1.0.0-rc1", "1.0.0-rc2", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+	}
+	for _, c := range cases {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestCheckProvider(t *testing.T) {
+	for _, native := range []string{"_pgsql", "_SQLite"} {
+		if msg := checkProvider(native, nil); len(msg) > 0 {
+			t.Fatal(msg)
+		}
+	}
+	if msg := checkProvider("_unknown", nil); len(msg) == 0 {
+		t.Fatal("expected an unknown native provider to be reported")
+	}
+	if msg := checkProvider("", nil); len(msg) == 0 {
+		t.Fatal("expected a missing provider to be reported")
+	}
+}
@@ -76,16 +76,84 @@ const docTemplate = `{
                 }
             }
         },
+        "/db/backup": {
+            "post": {
+                "description": "Takes a logical backup of the database and writes it with its metadata (including the application and database versions) to DbMan's backup directory.",
+                "produces": [
+                    "application/json",
+                    " text/plain"
+                ],
+                "tags": [
+                    "Database"
+                ],
+                "summary": "Takes a backup of the database.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "the format of the backup, either file (default) or directory",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "if true, waits for the operation to complete and returns its execution logs",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "202": {
+                        "description": "the started job",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/db/create": {
             "post": {
                 "description": "When the database does not already exists, this operation executes the manifest commands required to create the new database.",
                 "produces": [
-                    "text/plain"
+                    "application/json",
+                    " text/plain"
                 ],
                 "tags": [
                     "Database"
                 ],
                 "summary": "Creates a new database",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "if true, returns the merged scripts that would be executed without running them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "if true, waits for the operation to complete and returns its execution logs",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -93,6 +161,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "202": {
+                        "description": "the started job",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -106,12 +186,74 @@ const docTemplate = `{
             "post": {
                 "description": "When the database is empty, this operation executes the manifest commands required to deploy the  database schema and objects.",
                 "produces": [
-                    "text/plain"
+                    "application/json",
+                    " text/plain"
                 ],
                 "tags": [
                     "Database"
                 ],
                 "summary": "Deploys the schema and objects in an empty database.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "if true, returns the merged scripts that would be executed without running them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "if true, waits for the operation to complete and returns its execution logs",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "202": {
+                        "description": "the started job",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/db/downgrade": {
+            "post": {
+                "description": "This operation executes the manifest commands required to roll back an existing database schema and objects to a previous version. The target version is defined by DbMan's configuration value \"AppVersion\".",
+                "produces": [
+                    "application/json",
+                    " text/plain"
+                ],
+                "tags": [
+                    "Database"
+                ],
+                "summary": "Downgrade a database to a previous version.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "if true, waits for the operation to complete and returns its execution logs",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -119,6 +261,85 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "202": {
+                        "description": "the started job",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/db/history": {
+            "get": {
+                "description": "Lists the release commands executed on the database, most recent first, including the scripts run, timings, status and who ran them.",
+                "produces": [
+                    "application/json",
+                    " application/yaml",
+                    " application/xml",
+                    " text/csv"
+                ],
+                "tags": [
+                    "Database"
+                ],
+                "summary": "Gets the command execution history.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only returns commands of the specified application version",
+                        "name": "appVersion",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only returns executions of the specified command",
+                        "name": "command",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only returns executions with the specified status, either success or failure",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only returns executions started after a duration ago (e.g. 24h) or a date (e.g. 2006-01-02)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of executions to return, defaults to 50, 0 for all",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "Table"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -228,16 +449,103 @@ const docTemplate = `{
                 }
             }
         },
+        "/db/restore/{name}": {
+            "post": {
+                "description": "Restores a backup taken by DbMan. The restore is refused if the database has a version newer than the backup, unless it is forced.",
+                "produces": [
+                    "application/json",
+                    " text/plain"
+                ],
+                "tags": [
+                    "Database"
+                ],
+                "summary": "Restores the database from a backup.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "the name of the backup to restore, in the backup directory",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "restores the backup even if the database has a newer version",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "if true, waits for the operation to complete and returns its execution logs",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "202": {
+                        "description": "the started job",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/db/upgrade": {
             "post": {
                 "description": "This operation executes the manifest commands required to upgrade an existing database schema and objects to a new version. The target version is defined by DbMan's configuration value \"AppVersion\". This operation support rolling upgrades.",
                 "produces": [
-                    "text/plain"
+                    "application/json",
+                    " text/plain"
                 ],
                 "tags": [
                     "Database"
                 ],
                 "summary": "Upgrade a database to a specific version.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "if true, returns the merged scripts that would be executed without running them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "if true, continues a failed upgrade from the command that failed",
+                        "name": "resume",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "continues a failed upgrade from the specified command",
+                        "name": "fromCommand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "if true, waits for the operation to complete and returns its execution logs",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -245,6 +553,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "202": {
+                        "description": "the started job",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -254,6 +574,127 @@ const docTemplate = `{
                 }
             }
         },
+        "/db/upgrade/stream": {
+            "get": {
+                "description": "Streams the log of the running or last upgrade job as server-sent events while it is written: a 'log' event for each chunk of the log and an 'end' event with the job status when the job completes.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Database"
+                ],
+                "summary": "Streams the log of the last upgrade.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "get": {
+                "description": "Lists the jobs started by the http service, most recent first, including their status and timings.",
+                "produces": [
+                    "application/json",
+                    " application/yaml"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Gets the recent jobs.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/core.Job"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Gets the status, timings and log of a job started by the http service.",
+                "produces": [
+                    "application/json",
+                    " application/yaml"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Gets a job.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "the job identifier",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/core.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/stream": {
+            "get": {
+                "description": "Streams the log of a job as server-sent events while it is written: a 'log' event for each chunk of the log and an 'end' event with the job status when the job completes.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Streams the log of a job.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "the job identifier",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/ready": {
             "get": {
                 "description": "Checks that DbMan is ready to accept calls",
@@ -279,6 +720,121 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/release/validate": {
+            "get": {
+                "description": "Checks every release in the plan for unresolved command references, duplicate names, missing files, unresolved or unused merge variables, unknown database providers and db versions going backwards.",
+                "produces": [
+                    "application/json",
+                    " application/yaml"
+                ],
+                "tags": [
+                    "Release"
+                ],
+                "summary": "Validates the release plan and manifests.",
+                "responses": {
+                    "200": {
+                        "description": "the validation report, the releases are valid if it has no errors",
+                        "schema": {
+                            "$ref": "#/definitions/core.ValidationReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "core.Job": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "the name of the operation run by the job (e.g. upgrade)",
+                    "type": "string"
+                },
+                "elapsed": {
+                    "description": "the execution time of the operation",
+                    "type": "string"
+                },
+                "ended": {
+                    "description": "the time the job ended",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "the error returned by the operation if it failed",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "the unique identifier of the job",
+                    "type": "string"
+                },
+                "log": {
+                    "description": "the operation log, written so far if the job is running",
+                    "type": "string"
+                },
+                "mutating": {
+                    "description": "true if the operation changes the database",
+                    "type": "boolean"
+                },
+                "started": {
+                    "description": "the time the job started",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "the status of the job: running, succeeded or failed",
+                    "type": "string"
+                }
+            }
+        },
+        "core.ValidationIssue": {
+            "type": "object",
+            "properties": {
+                "appVersion": {
+                    "description": "the application version of the release with the issue, empty for plan issues",
+                    "type": "string"
+                },
+                "check": {
+                    "description": "the name of the check that found the issue",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "a description of the issue",
+                    "type": "string"
+                },
+                "severity": {
+                    "description": "either error or warning",
+                    "type": "string"
+                }
+            }
+        },
+        "core.ValidationReport": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "the number of issues that would make a release fail",
+                    "type": "integer"
+                },
+                "issues": {
+                    "description": "the issues found",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/core.ValidationIssue"
+                    }
+                },
+                "releases": {
+                    "description": "the number of releases validated",
+                    "type": "integer"
+                },
+                "warnings": {
+                    "description": "the number of issues that should be reviewed",
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,50 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Backup carries the metadata of a logical backup of the managed database
+type Backup struct {
+	// the unique name of the backup
+	Name string `json:"name"`
+	// the path to the file or directory where the database dump is written
+	Path string `json:"path"`
+	// the format of the database dump, either file or directory
+	Format string `json:"format"`
+	// the name of the database the backup was taken from
+	Database string `json:"database"`
+	// the database provider used to take the backup
+	Provider string `json:"provider"`
+	// the application version in the version table when the backup was taken
+	AppVersion string `json:"appVersion,omitempty"`
+	// the database version in the version table when the backup was taken
+	DbVersion string `json:"dbVersion,omitempty"`
+	// the time the backup was taken
+	Time time.Time `json:"time"`
+	// the size of the database dump in bytes
+	Size int64 `json:"size"`
+}
+
+// NewBackup creates a new backup from a serialised json string
+func NewBackup(jsonString string) (*Backup, error) {
+	b := &Backup{}
+	err := json.Unmarshal([]byte(jsonString), b)
+	return b, err
+}
+
+func (b *Backup) ToString() string {
+	bytes, e := json.Marshal(b)
+	if e != nil {
+		return ""
+	}
+	return string(bytes)
+}
@@ -0,0 +1,54 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// ScriptChecksum the checksum of a merged script executed as part of a release
+type ScriptChecksum struct {
+	// the application version of the release defining the script
+	AppVersion string `json:"appVersion"`
+	// the database version of the release defining the script
+	DbVersion string `json:"dbVersion"`
+	// the name of the command the script belongs to
+	Command string `json:"command"`
+	// the name of the script
+	Script string `json:"script"`
+	// the script file name in the repository
+	File string `json:"file"`
+	// the SHA-256 checksum of the merged script content
+	Checksum string `json:"checksum"`
+	// the SHA-256 checksum of the script file content, before merging its variables
+	FileChecksum string `json:"fileChecksum"`
+	// the time the script was executed
+	Time time.Time `json:"time"`
+}
+
+// NewScriptChecksum creates the checksums of a merged script and of its file
+func NewScriptChecksum(appVersion string, dbVersion string, command string, script Script) ScriptChecksum {
+	return ScriptChecksum{
+		AppVersion:   appVersion,
+		DbVersion:    dbVersion,
+		Command:      command,
+		Script:       script.Name,
+		File:         script.File,
+		Checksum:     Checksum(script.Content),
+		FileChecksum: script.FileChecksum,
+		Time:         time.Now().UTC(),
+	}
+}
+
+// Checksum returns the hex encoded SHA-256 checksum of the passed-in content
+func Checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
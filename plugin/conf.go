@@ -58,6 +58,15 @@ func (c *Conf) GetString(key string) (string, bool) {
 	return "", false
 }
 
+// ToString serialises the configuration as a JSON string
+func (c *Conf) ToString() string {
+	bytes, err := json.Marshal(c.value)
+	if err != nil {
+		return "{}"
+	}
+	return string(bytes)
+}
+
 func (c *Conf) fromJSON(jsonString string) error {
 	m := make(map[string]interface{})
 	err := json.Unmarshal([]byte(jsonString), &m)
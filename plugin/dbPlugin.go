@@ -22,12 +22,39 @@ type DatabasePlugin interface {
 	// execute the specified db scripts
 	RunCommand(cmd *Command) (bytes.Buffer, error)
 
-	// set the version
+	// set the version and record the checksums of the scripts executed for it
 	SetVersion(version *Version) error
 
+	// get the checksums of the scripts executed for all applied releases
+	GetChecksums() ([]ScriptChecksum, error)
+
+	// record the execution of a release command
+	SetHistory(entry *HistoryEntry) error
+
+	// get the command execution history entries matching the filter
+	GetHistory(filter *HistoryFilter) ([]HistoryEntry, error)
+
+	// record the progress of an upgrade
+	SetProgress(progress *Progress) error
+
+	// get the progress of the last upgrade, nil if no upgrade has been recorded
+	GetProgress() (*Progress, error)
+
 	// execute a query
 	RunQuery(query *Query) (*Table, error)
 
 	// get database server information
 	GetInfo() (*DbInfo, error)
+
+	// take a logical backup of the database, populating the passed-in backup metadata
+	Backup(backup *Backup) (bytes.Buffer, error)
+
+	// restore the database from a logical backup
+	Restore(backup *Backup) (bytes.Buffer, error)
+
+	// acquire a cluster wide lock on the database, waiting up to the lock timeout if another instance holds it
+	Lock(lock *Lock) error
+
+	// release the cluster wide lock on the database
+	Unlock(lock *Lock) error
 }
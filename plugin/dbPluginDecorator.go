@@ -8,15 +8,16 @@
 package plugin
 
 import (
-	"fmt"
 	"github.com/hashicorp/go-plugin"
 )
 
 // DatabasePluginDecorator the decorator wraps the DatabasePlugin interface and exposes it as a DatabaseProvider interface
-// the DatabaseProvider is the underlying interface used by the net/rpc protocol to communicate with the plugin
+// the DatabaseProvider is the underlying interface used by DbMan and by the legacy net/rpc protocol to communicate with the plugin
 // whereas the DatabasePlugin interface is a friendlier version used by plugin writers
 type DatabasePluginDecorator struct {
 	Plugin DatabasePlugin
+	// the name the plugin is served as
+	Name string
 }
 
 func (db *DatabasePluginDecorator) Setup(config string) string {
@@ -28,7 +29,9 @@ func (db *DatabasePluginDecorator) Setup(config string) string {
 		return output.ToError(err)
 	}
 	// allocate the parsed object to cfg
-	db.Plugin.Setup(c)
+	if err = db.Plugin.Setup(c); err != nil {
+		return output.ToError(err)
+	}
 	// return the output
 	return output.ToString()
 }
@@ -97,6 +100,73 @@ func (db *DatabasePluginDecorator) SetVersion(versionInfo string) string {
 	return v.ToString()
 }
 
+// RPC serialisation wrapper for getting the checksums of the scripts executed for all applied releases
+func (db *DatabasePluginDecorator) GetChecksums() string {
+	output := NewParameter()
+	checksums, err := db.Plugin.GetChecksums()
+	if err != nil {
+		return output.ToError(err)
+	}
+	output.Set("result", checksums)
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for recording the execution of a release command
+func (db *DatabasePluginDecorator) SetHistory(entryInfo string) string {
+	output := NewParameter()
+	entry, err := NewHistoryEntry(entryInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	err = db.Plugin.SetHistory(entry)
+	if err != nil {
+		return output.ToError(err)
+	}
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for getting the command execution history
+func (db *DatabasePluginDecorator) GetHistory(filterInfo string) string {
+	output := NewParameter()
+	filter, err := NewHistoryFilter(filterInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	entries, err := db.Plugin.GetHistory(filter)
+	if err != nil {
+		return output.ToError(err)
+	}
+	output.Set("result", entries)
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for recording the progress of an upgrade
+func (db *DatabasePluginDecorator) SetProgress(progressInfo string) string {
+	output := NewParameter()
+	progress, err := NewProgress(progressInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	err = db.Plugin.SetProgress(progress)
+	if err != nil {
+		return output.ToError(err)
+	}
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for getting the progress of the last upgrade
+func (db *DatabasePluginDecorator) GetProgress() string {
+	output := NewParameter()
+	progress, err := db.Plugin.GetProgress()
+	if err != nil {
+		return output.ToError(err)
+	}
+	if progress != nil {
+		output.Set("result", progress)
+	}
+	return output.ToString()
+}
+
 func (db *DatabasePluginDecorator) GetInfo() string {
 	// create the output struct
 	output := NewParameter()
@@ -115,21 +185,104 @@ func (db *DatabasePluginDecorator) GetInfo() string {
 	return output.ToString()
 }
 
+// RPC serialisation wrapper for describing the plugin
+// plugins not implementing PluginDescriptor are reported with an unknown version and capabilities
+func (db *DatabasePluginDecorator) GetPluginInfo() string {
+	output := NewParameter()
+	output.Set("result", describePlugin(db.Plugin, db.Name))
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for taking a database backup
+func (db *DatabasePluginDecorator) Backup(backupInfo string) string {
+	output := NewParameter()
+	backup, err := NewBackup(backupInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	log, err := db.Plugin.Backup(backup)
+	if log.Len() > 0 {
+		output.Log(log.String())
+	}
+	if err != nil {
+		return output.ToError(err)
+	}
+	// return the backup metadata populated by the plugin
+	output.Set("result", backup)
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for restoring a database backup
+func (db *DatabasePluginDecorator) Restore(backupInfo string) string {
+	output := NewParameter()
+	backup, err := NewBackup(backupInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	log, err := db.Plugin.Restore(backup)
+	if log.Len() > 0 {
+		output.Log(log.String())
+	}
+	if err != nil {
+		return output.ToError(err)
+	}
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for acquiring the database lock
+func (db *DatabasePluginDecorator) Lock(lockInfo string) string {
+	output := NewParameter()
+	lock, err := NewLock(lockInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	err = db.Plugin.Lock(lock)
+	if err != nil {
+		return output.ToError(err)
+	}
+	return output.ToString()
+}
+
+// RPC serialisation wrapper for releasing the database lock
+func (db *DatabasePluginDecorator) Unlock(lockInfo string) string {
+	output := NewParameter()
+	lock, err := NewLock(lockInfo)
+	if err != nil {
+		return output.ToError(err)
+	}
+	err = db.Plugin.Unlock(lock)
+	if err != nil {
+		return output.ToError(err)
+	}
+	return output.ToString()
+}
+
 // launch the database plugin
 func ServeDbPlugin(pluginName string, impl DatabasePlugin) {
-	// launch the plugin as an rpc server
+	// launch the plugin as a gRPC server, also serving the legacy net/rpc protocol for older DbMan versions
+	// the protocol is selected during the handshake, using the highest version supported by both DbMan and the plugin
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: plugin.HandshakeConfig{
-			ProtocolVersion:  1,
-			MagicCookieKey:   "dbman-db-provider",
-			MagicCookieValue: fmt.Sprintf("dbman-db-%s", pluginName),
+			ProtocolVersion:  ProtocolVersion,
+			MagicCookieKey:   MagicCookieKey,
+			MagicCookieValue: MagicCookieValue(pluginName),
 		},
-		Plugins: map[string]plugin.Plugin{
-			pluginName: &DatabaseProviderPlugin{
-				Impl: &DatabasePluginDecorator{
-					Plugin: impl,
+		VersionedPlugins: map[int]plugin.PluginSet{
+			LegacyProtocolVersion: {
+				pluginName: &DatabaseProviderPlugin{
+					Impl: &DatabasePluginDecorator{
+						Plugin: impl,
+						Name:   pluginName,
+					},
+				},
+			},
+			ProtocolVersion: {
+				pluginName: &DatabaseProviderGRPCPlugin{
+					Impl: impl,
+					Name: pluginName,
 				},
 			},
 		},
+		GRPCServer: plugin.DefaultGRPCServer,
 	})
 }
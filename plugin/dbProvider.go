@@ -15,15 +15,45 @@ type DatabaseProvider interface {
 	// get database server general information
 	GetInfo() string
 
+	// get the name, version and capabilities of the plugin
+	GetPluginInfo() string
+
 	// get database release version information
 	GetVersion() string
 
 	// set database release version information
 	SetVersion(versionInfo string) string
 
+	// get the checksums of the scripts executed for all applied releases
+	GetChecksums() string
+
+	// record the execution of a release command
+	SetHistory(entry string) string
+
+	// get the command execution history entries matching the filter
+	GetHistory(filter string) string
+
+	// record the progress of an upgrade
+	SetProgress(progress string) string
+
+	// get the progress of the last upgrade
+	GetProgress() string
+
 	// execute the specified command
 	RunCommand(cmd string) string
 
 	// execute the specified query
 	RunQuery(query string) string
+
+	// take a logical backup of the database
+	Backup(backup string) string
+
+	// restore the database from a logical backup
+	Restore(backup string) string
+
+	// acquire a cluster wide lock on the database
+	Lock(lock string) string
+
+	// release the cluster wide lock on the database
+	Unlock(lock string) string
 }
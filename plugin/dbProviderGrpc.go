@@ -0,0 +1,164 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"southwinds.dev/dbman/plugin/pb"
+)
+
+// Database Provider gRPC client
+// implements the DatabasePlugin interface so that DbMan can use it in the same way as a native provider
+type DatabaseProviderGRPC struct {
+	Client pb.DatabaseProviderClient
+}
+
+func (db *DatabaseProviderGRPC) Setup(config *Conf) error {
+	r, err := db.Client.Setup(context.Background(), &pb.SetupRequest{Config: config.ToString()})
+	if err != nil {
+		return err
+	}
+	return fromPbError(r.GetError())
+}
+
+// PluginInfo returns the name, version and capabilities reported by the plugin, or nil if the plugin cannot be reached
+func (db *DatabaseProviderGRPC) PluginInfo() *PluginInfo {
+	r, err := db.Client.GetPluginInfo(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil
+	}
+	return fromPbPluginInfo(r)
+}
+
+func (db *DatabaseProviderGRPC) GetInfo() (*DbInfo, error) {
+	r, err := db.Client.GetInfo(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return fromPbDbInfo(r.GetInfo()), fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) GetVersion() (*Version, error) {
+	r, err := db.Client.GetVersion(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return fromPbVersion(r.GetVersion()), fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) SetVersion(version *Version) error {
+	r, err := db.Client.SetVersion(context.Background(), toPbVersion(version))
+	if err != nil {
+		return err
+	}
+	return fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) GetChecksums() ([]ScriptChecksum, error) {
+	r, err := db.Client.GetChecksums(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return fromPbChecksums(r.GetChecksums()), fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) SetHistory(entry *HistoryEntry) error {
+	r, err := db.Client.SetHistory(context.Background(), toPbHistoryEntry(entry))
+	if err != nil {
+		return err
+	}
+	return fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) GetHistory(filter *HistoryFilter) ([]HistoryEntry, error) {
+	r, err := db.Client.GetHistory(context.Background(), toPbHistoryFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]HistoryEntry, 0, len(r.GetEntries()))
+	for _, entry := range r.GetEntries() {
+		entries = append(entries, *fromPbHistoryEntry(entry))
+	}
+	return entries, fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) SetProgress(progress *Progress) error {
+	r, err := db.Client.SetProgress(context.Background(), toPbProgress(progress))
+	if err != nil {
+		return err
+	}
+	return fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) GetProgress() (*Progress, error) {
+	r, err := db.Client.GetProgress(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return fromPbProgress(r.GetProgress()), fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) RunCommand(cmd *Command) (bytes.Buffer, error) {
+	log := bytes.Buffer{}
+	r, err := db.Client.RunCommand(context.Background(), toPbCommand(cmd))
+	if err != nil {
+		return log, err
+	}
+	log.WriteString(r.GetLog())
+	return log, fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) RunQuery(query *Query) (*Table, error) {
+	r, err := db.Client.RunQuery(context.Background(), toPbQuery(query))
+	if err != nil {
+		return nil, err
+	}
+	return fromPbTable(r.GetTable()), fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) Backup(backup *Backup) (bytes.Buffer, error) {
+	log := bytes.Buffer{}
+	r, err := db.Client.Backup(context.Background(), toPbBackup(backup))
+	if err != nil {
+		return log, err
+	}
+	log.WriteString(r.GetLog())
+	// the provider populates the backup metadata
+	if b := fromPbBackup(r.GetBackup()); b != nil {
+		*backup = *b
+	}
+	return log, fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) Restore(backup *Backup) (bytes.Buffer, error) {
+	log := bytes.Buffer{}
+	r, err := db.Client.Restore(context.Background(), toPbBackup(backup))
+	if err != nil {
+		return log, err
+	}
+	log.WriteString(r.GetLog())
+	return log, fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) Lock(lock *Lock) error {
+	r, err := db.Client.Lock(context.Background(), toPbLock(lock))
+	if err != nil {
+		return err
+	}
+	return fromPbError(r.GetError())
+}
+
+func (db *DatabaseProviderGRPC) Unlock(lock *Lock) error {
+	r, err := db.Client.Unlock(context.Background(), toPbLock(lock))
+	if err != nil {
+		return err
+	}
+	return fromPbError(r.GetError())
+}
@@ -0,0 +1,113 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"southwinds.dev/dbman/plugin/pb"
+)
+
+// Database Provider gRPC server
+// errors returned by the plugin are sent in the error field of the responses, so that they do not look like transport failures
+type DatabaseProviderGRPCServer struct {
+	pb.UnimplementedDatabaseProviderServer
+	// Impl Injection
+	Impl DatabasePlugin
+	// the name the plugin is served as
+	Name string
+}
+
+func (s *DatabaseProviderGRPCServer) Setup(ctx context.Context, req *pb.SetupRequest) (*pb.Result, error) {
+	conf, err := NewConf(req.GetConfig())
+	if err == nil {
+		err = s.Impl.Setup(conf)
+	}
+	return &pb.Result{Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) GetPluginInfo(ctx context.Context, req *emptypb.Empty) (*pb.PluginInfo, error) {
+	return toPbPluginInfo(describePlugin(s.Impl, s.Name)), nil
+}
+
+func (s *DatabaseProviderGRPCServer) GetInfo(ctx context.Context, req *emptypb.Empty) (*pb.GetInfoResponse, error) {
+	info, err := s.Impl.GetInfo()
+	return &pb.GetInfoResponse{Info: toPbDbInfo(info), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) GetVersion(ctx context.Context, req *emptypb.Empty) (*pb.GetVersionResponse, error) {
+	version, err := s.Impl.GetVersion()
+	return &pb.GetVersionResponse{Version: toPbVersion(version), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) SetVersion(ctx context.Context, req *pb.Version) (*pb.Result, error) {
+	return &pb.Result{Error: toPbError(s.Impl.SetVersion(fromPbVersion(req)))}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) GetChecksums(ctx context.Context, req *emptypb.Empty) (*pb.GetChecksumsResponse, error) {
+	checksums, err := s.Impl.GetChecksums()
+	return &pb.GetChecksumsResponse{Checksums: toPbChecksums(checksums), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) SetHistory(ctx context.Context, req *pb.HistoryEntry) (*pb.Result, error) {
+	return &pb.Result{Error: toPbError(s.Impl.SetHistory(fromPbHistoryEntry(req)))}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) GetHistory(ctx context.Context, req *pb.HistoryFilter) (*pb.GetHistoryResponse, error) {
+	entries, err := s.Impl.GetHistory(fromPbHistoryFilter(req))
+	resp := &pb.GetHistoryResponse{Error: toPbError(err)}
+	for i := range entries {
+		resp.Entries = append(resp.Entries, toPbHistoryEntry(&entries[i]))
+	}
+	return resp, nil
+}
+
+func (s *DatabaseProviderGRPCServer) SetProgress(ctx context.Context, req *pb.Progress) (*pb.Result, error) {
+	return &pb.Result{Error: toPbError(s.Impl.SetProgress(fromPbProgress(req)))}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) GetProgress(ctx context.Context, req *emptypb.Empty) (*pb.GetProgressResponse, error) {
+	progress, err := s.Impl.GetProgress()
+	return &pb.GetProgressResponse{Progress: toPbProgress(progress), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) RunCommand(ctx context.Context, req *pb.Command) (*pb.RunCommandResponse, error) {
+	log, err := s.Impl.RunCommand(fromPbCommand(req))
+	return &pb.RunCommandResponse{Log: log.String(), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) RunQuery(ctx context.Context, req *pb.Query) (*pb.RunQueryResponse, error) {
+	table, err := s.Impl.RunQuery(fromPbQuery(req))
+	return &pb.RunQueryResponse{Table: toPbTable(table), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) Backup(ctx context.Context, req *pb.BackupInfo) (*pb.BackupResponse, error) {
+	backup := fromPbBackup(req)
+	if backup == nil {
+		backup = &Backup{}
+	}
+	log, err := s.Impl.Backup(backup)
+	return &pb.BackupResponse{Backup: toPbBackup(backup), Log: log.String(), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) Restore(ctx context.Context, req *pb.BackupInfo) (*pb.RestoreResponse, error) {
+	backup := fromPbBackup(req)
+	if backup == nil {
+		backup = &Backup{}
+	}
+	log, err := s.Impl.Restore(backup)
+	return &pb.RestoreResponse{Log: log.String(), Error: toPbError(err)}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) Lock(ctx context.Context, req *pb.LockInfo) (*pb.Result, error) {
+	return &pb.Result{Error: toPbError(s.Impl.Lock(fromPbLock(req)))}, nil
+}
+
+func (s *DatabaseProviderGRPCServer) Unlock(ctx context.Context, req *pb.LockInfo) (*pb.Result, error) {
+	return &pb.Result{Error: toPbError(s.Impl.Unlock(fromPbLock(req)))}, nil
+}
@@ -0,0 +1,334 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"southwinds.dev/dbman/plugin/pb"
+	"time"
+)
+
+// conversions between the plugin types and the messages of the gRPC protocol
+// zero times are sent as unset timestamps and nil messages are converted to nil values, so that missing data never panics
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func fromTimestamp(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func toPbError(err error) *pb.Error {
+	if err == nil {
+		return nil
+	}
+	return &pb.Error{Message: err.Error()}
+}
+
+func fromPbError(e *pb.Error) error {
+	if e == nil {
+		return nil
+	}
+	return errors.New(e.GetMessage())
+}
+
+func toPbPluginInfo(i *PluginInfo) *pb.PluginInfo {
+	return &pb.PluginInfo{Name: i.Name, Version: i.Version, Protocol: int32(i.Protocol), Capabilities: i.Capabilities}
+}
+
+func fromPbPluginInfo(i *pb.PluginInfo) *PluginInfo {
+	if i == nil {
+		return nil
+	}
+	return &PluginInfo{Name: i.GetName(), Version: i.GetVersion(), Protocol: int(i.GetProtocol()), Capabilities: i.GetCapabilities()}
+}
+
+func toPbDbInfo(i *DbInfo) *pb.DbInfo {
+	if i == nil {
+		return nil
+	}
+	return &pb.DbInfo{Database: i.Database, OperatingSystem: i.OperatingSystem, Compiler: i.Compiler, ProcessorBits: i.ProcessorBits}
+}
+
+func fromPbDbInfo(i *pb.DbInfo) *DbInfo {
+	if i == nil {
+		return nil
+	}
+	return &DbInfo{Database: i.GetDatabase(), OperatingSystem: i.GetOperatingSystem(), Compiler: i.GetCompiler(), ProcessorBits: i.GetProcessorBits()}
+}
+
+func toPbChecksums(checksums []ScriptChecksum) []*pb.ScriptChecksum {
+	result := make([]*pb.ScriptChecksum, len(checksums))
+	for i, c := range checksums {
+		result[i] = &pb.ScriptChecksum{
+			AppVersion:   c.AppVersion,
+			DbVersion:    c.DbVersion,
+			Command:      c.Command,
+			Script:       c.Script,
+			File:         c.File,
+			Checksum:     c.Checksum,
+			FileChecksum: c.FileChecksum,
+			Time:         toTimestamp(c.Time),
+		}
+	}
+	return result
+}
+
+func fromPbChecksums(checksums []*pb.ScriptChecksum) []ScriptChecksum {
+	result := make([]ScriptChecksum, 0, len(checksums))
+	for _, c := range checksums {
+		result = append(result, ScriptChecksum{
+			AppVersion:   c.GetAppVersion(),
+			DbVersion:    c.GetDbVersion(),
+			Command:      c.GetCommand(),
+			Script:       c.GetScript(),
+			File:         c.GetFile(),
+			Checksum:     c.GetChecksum(),
+			FileChecksum: c.GetFileChecksum(),
+			Time:         fromTimestamp(c.GetTime()),
+		})
+	}
+	return result
+}
+
+func toPbVersion(v *Version) *pb.Version {
+	if v == nil {
+		return nil
+	}
+	return &pb.Version{
+		AppVersion:  v.AppVersion,
+		DbVersion:   v.DbVersion,
+		Description: v.Description,
+		Source:      v.Source,
+		Time:        toTimestamp(v.Time),
+		Scripts:     toPbChecksums(v.Scripts),
+	}
+}
+
+func fromPbVersion(v *pb.Version) *Version {
+	if v == nil {
+		return nil
+	}
+	version := &Version{
+		AppVersion:  v.GetAppVersion(),
+		DbVersion:   v.GetDbVersion(),
+		Description: v.GetDescription(),
+		Source:      v.GetSource(),
+		Time:        fromTimestamp(v.GetTime()),
+	}
+	if len(v.GetScripts()) > 0 {
+		version.Scripts = fromPbChecksums(v.GetScripts())
+	}
+	return version
+}
+
+func toPbHistoryEntry(h *HistoryEntry) *pb.HistoryEntry {
+	return &pb.HistoryEntry{
+		Id:         h.Id,
+		AppVersion: h.AppVersion,
+		DbVersion:  h.DbVersion,
+		Command:    h.Command,
+		Scripts:    h.Scripts,
+		Start:      toTimestamp(h.Start),
+		End:        toTimestamp(h.End),
+		Duration:   h.Duration,
+		Success:    h.Success,
+		Error:      h.Error,
+		User:       h.User,
+		Host:       h.Host,
+	}
+}
+
+func fromPbHistoryEntry(h *pb.HistoryEntry) *HistoryEntry {
+	return &HistoryEntry{
+		Id:         h.GetId(),
+		AppVersion: h.GetAppVersion(),
+		DbVersion:  h.GetDbVersion(),
+		Command:    h.GetCommand(),
+		Scripts:    h.GetScripts(),
+		Start:      fromTimestamp(h.GetStart()),
+		End:        fromTimestamp(h.GetEnd()),
+		Duration:   h.GetDuration(),
+		Success:    h.GetSuccess(),
+		Error:      h.GetError(),
+		User:       h.GetUser(),
+		Host:       h.GetHost(),
+	}
+}
+
+func toPbHistoryFilter(f *HistoryFilter) *pb.HistoryFilter {
+	return &pb.HistoryFilter{
+		AppVersion: f.AppVersion,
+		Command:    f.Command,
+		Status:     f.Status,
+		Since:      toTimestamp(f.Since),
+		Limit:      int32(f.Limit),
+	}
+}
+
+func fromPbHistoryFilter(f *pb.HistoryFilter) *HistoryFilter {
+	return &HistoryFilter{
+		AppVersion: f.GetAppVersion(),
+		Command:    f.GetCommand(),
+		Status:     f.GetStatus(),
+		Since:      fromTimestamp(f.GetSince()),
+		Limit:      int(f.GetLimit()),
+	}
+}
+
+func toPbProgress(p *Progress) *pb.Progress {
+	if p == nil {
+		return nil
+	}
+	return &pb.Progress{
+		From:    p.From,
+		To:      p.To,
+		Release: p.Release,
+		Stage:   p.Stage,
+		Command: p.Command,
+		Step:    int32(p.Step),
+		Status:  p.Status,
+		Error:   p.Error,
+		Time:    toTimestamp(p.Time),
+	}
+}
+
+func fromPbProgress(p *pb.Progress) *Progress {
+	if p == nil {
+		return nil
+	}
+	return &Progress{
+		From:    p.GetFrom(),
+		To:      p.GetTo(),
+		Release: p.GetRelease(),
+		Stage:   p.GetStage(),
+		Command: p.GetCommand(),
+		Step:    int(p.GetStep()),
+		Status:  p.GetStatus(),
+		Error:   p.GetError(),
+		Time:    fromTimestamp(p.GetTime()),
+	}
+}
+
+func toPbCommand(c *Command) *pb.Command {
+	scripts := make([]*pb.Script, len(c.Scripts))
+	for i, s := range c.Scripts {
+		scripts[i] = &pb.Script{Name: s.Name, File: s.File, Content: s.Content}
+	}
+	return &pb.Command{
+		Name:          c.Name,
+		Description:   c.Description,
+		Transactional: c.Transactional,
+		AsAdmin:       c.AsAdmin,
+		UseDb:         c.UseDb,
+		Scripts:       scripts,
+	}
+}
+
+func fromPbCommand(c *pb.Command) *Command {
+	scripts := make([]Script, 0, len(c.GetScripts()))
+	for _, s := range c.GetScripts() {
+		scripts = append(scripts, Script{Name: s.GetName(), File: s.GetFile(), Content: s.GetContent()})
+	}
+	return &Command{
+		Name:          c.GetName(),
+		Description:   c.GetDescription(),
+		Transactional: c.GetTransactional(),
+		AsAdmin:       c.GetAsAdmin(),
+		UseDb:         c.GetUseDb(),
+		Scripts:       scripts,
+	}
+}
+
+func toPbQuery(q *Query) *pb.Query {
+	args := make([]*pb.QueryArg, len(q.Args))
+	for i, a := range q.Args {
+		args[i] = &pb.QueryArg{Name: a.Name, Type: a.Type, Value: a.Value}
+	}
+	return &pb.Query{Name: q.Name, Content: q.Content, Args: args}
+}
+
+func fromPbQuery(q *pb.Query) *Query {
+	query := &Query{Name: q.GetName(), Content: q.GetContent()}
+	for _, a := range q.GetArgs() {
+		query.Args = append(query.Args, QueryArg{Name: a.GetName(), Type: a.GetType(), Value: a.GetValue()})
+	}
+	return query
+}
+
+func toPbTable(t *Table) *pb.Table {
+	if t == nil {
+		return nil
+	}
+	rows := make([]*pb.Row, len(t.Rows))
+	for i, row := range t.Rows {
+		rows[i] = &pb.Row{Cells: row}
+	}
+	return &pb.Table{Header: t.Header, Rows: rows}
+}
+
+func fromPbTable(t *pb.Table) *Table {
+	if t == nil {
+		return nil
+	}
+	table := &Table{Header: t.GetHeader()}
+	for _, row := range t.GetRows() {
+		table.Rows = append(table.Rows, row.GetCells())
+	}
+	return table
+}
+
+func toPbBackup(b *Backup) *pb.BackupInfo {
+	if b == nil {
+		return nil
+	}
+	return &pb.BackupInfo{
+		Name:       b.Name,
+		Path:       b.Path,
+		Format:     b.Format,
+		Database:   b.Database,
+		Provider:   b.Provider,
+		AppVersion: b.AppVersion,
+		DbVersion:  b.DbVersion,
+		Time:       toTimestamp(b.Time),
+		Size:       b.Size,
+	}
+}
+
+func fromPbBackup(b *pb.BackupInfo) *Backup {
+	if b == nil {
+		return nil
+	}
+	return &Backup{
+		Name:       b.GetName(),
+		Path:       b.GetPath(),
+		Format:     b.GetFormat(),
+		Database:   b.GetDatabase(),
+		Provider:   b.GetProvider(),
+		AppVersion: b.GetAppVersion(),
+		DbVersion:  b.GetDbVersion(),
+		Time:       fromTimestamp(b.GetTime()),
+		Size:       b.GetSize(),
+	}
+}
+
+func toPbLock(l *Lock) *pb.LockInfo {
+	return &pb.LockInfo{Name: l.Name, Owner: l.Owner, Timeout: int32(l.Timeout)}
+}
+
+func fromPbLock(l *pb.LockInfo) *Lock {
+	return &Lock{Name: l.GetName(), Owner: l.GetOwner(), Timeout: int(l.GetTimeout())}
+}
@@ -8,8 +8,11 @@
 package plugin
 
 import (
+	"context"
 	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
 	"net/rpc"
+	"southwinds.dev/dbman/plugin/pb"
 )
 
 // the implementation of the DatabaseProvider plugin
@@ -27,3 +30,24 @@ func (p *DatabaseProviderPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (int
 		Client: c,
 	}, nil
 }
+
+// the implementation of the DatabaseProvider plugin using the gRPC protocol
+type DatabaseProviderGRPCPlugin struct {
+	// the gRPC protocol does not support net/rpc, which is served by DatabaseProviderPlugin
+	plugin.NetRPCUnsupportedPlugin
+	// Impl Injection
+	Impl DatabasePlugin
+	// the name the plugin is served as
+	Name string
+}
+
+func (p *DatabaseProviderGRPCPlugin) GRPCServer(b *plugin.GRPCBroker, s *grpc.Server) error {
+	pb.RegisterDatabaseProviderServer(s, &DatabaseProviderGRPCServer{Impl: p.Impl, Name: p.Name})
+	return nil
+}
+
+func (p *DatabaseProviderGRPCPlugin) GRPCClient(ctx context.Context, b *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &DatabaseProviderGRPC{
+		Client: pb.NewDatabaseProviderClient(c),
+	}, nil
+}
@@ -7,7 +7,12 @@
 
 package plugin
 
-import "net/rpc"
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"strings"
+)
 
 // Database Provider RPC client
 type DatabaseProviderRPC struct {
@@ -52,6 +57,51 @@ func (db *DatabaseProviderRPC) SetVersion(args string) string {
 	return result
 }
 
+func (db *DatabaseProviderRPC) GetChecksums() string {
+	var result string
+	err := db.Client.Call("Plugin.GetChecksums", "", &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) SetHistory(args string) string {
+	var result string
+	err := db.Client.Call("Plugin.SetHistory", args, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) GetHistory(args string) string {
+	var result string
+	err := db.Client.Call("Plugin.GetHistory", args, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) SetProgress(args string) string {
+	var result string
+	err := db.Client.Call("Plugin.SetProgress", args, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) GetProgress() string {
+	var result string
+	err := db.Client.Call("Plugin.GetProgress", "", &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
 func (db *DatabaseProviderRPC) RunQuery(query string) string {
 	var result string
 	err := db.Client.Call("Plugin.RunQuery", query, &result)
@@ -70,7 +120,57 @@ func (db *DatabaseProviderRPC) GetInfo() string {
 	return result
 }
 
+func (db *DatabaseProviderRPC) GetPluginInfo() string {
+	var result string
+	err := db.Client.Call("Plugin.GetPluginInfo", "", &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) Backup(backup string) string {
+	var result string
+	err := db.Client.Call("Plugin.Backup", backup, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) Restore(backup string) string {
+	var result string
+	err := db.Client.Call("Plugin.Restore", backup, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) Lock(lock string) string {
+	var result string
+	err := db.Client.Call("Plugin.Lock", lock, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
+func (db *DatabaseProviderRPC) Unlock(lock string) string {
+	var result string
+	err := db.Client.Call("Plugin.Unlock", lock, &result)
+	if err != nil {
+		return db.errorToString(err)
+	}
+	return result
+}
+
 func (db *DatabaseProviderRPC) errorToString(err error) string {
+	// plugins built for older DbMan versions only implement Setup, GetInfo, GetVersion, SetVersion, RunCommand and RunQuery
+	if method := strings.TrimPrefix(err.Error(), "rpc: can't find method Plugin."); method != err.Error() {
+		err = errors.New(fmt.Sprintf("the database plugin does not support %s as it was built for an older version of DbMan, "+
+			"rebuild the plugin with the current DbMan plugin package to use this feature", method))
+	}
 	output := NewParameter()
 	output.SetError(err)
 	return output.ToString()
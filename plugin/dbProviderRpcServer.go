@@ -24,6 +24,11 @@ func (s *DatabaseProviderRPCServer) GetInfo(args string, resp *string) error {
 	return nil
 }
 
+func (s *DatabaseProviderRPCServer) GetPluginInfo(args string, resp *string) error {
+	*resp = s.Impl.GetPluginInfo()
+	return nil
+}
+
 func (s *DatabaseProviderRPCServer) GetVersion(args string, resp *string) error {
 	*resp = s.Impl.GetVersion()
 	return nil
@@ -34,6 +39,31 @@ func (s *DatabaseProviderRPCServer) SetVersion(args string, resp *string) error
 	return nil
 }
 
+func (s *DatabaseProviderRPCServer) GetChecksums(args string, resp *string) error {
+	*resp = s.Impl.GetChecksums()
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) SetHistory(args string, resp *string) error {
+	*resp = s.Impl.SetHistory(args)
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) GetHistory(args string, resp *string) error {
+	*resp = s.Impl.GetHistory(args)
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) SetProgress(args string, resp *string) error {
+	*resp = s.Impl.SetProgress(args)
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) GetProgress(args string, resp *string) error {
+	*resp = s.Impl.GetProgress()
+	return nil
+}
+
 func (s *DatabaseProviderRPCServer) RunCommand(args string, resp *string) error {
 	*resp = s.Impl.RunCommand(args)
 	return nil
@@ -43,3 +73,23 @@ func (s *DatabaseProviderRPCServer) RunQuery(args string, resp *string) error {
 	*resp = s.Impl.RunQuery(args)
 	return nil
 }
+
+func (s *DatabaseProviderRPCServer) Backup(args string, resp *string) error {
+	*resp = s.Impl.Backup(args)
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) Restore(args string, resp *string) error {
+	*resp = s.Impl.Restore(args)
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) Lock(args string, resp *string) error {
+	*resp = s.Impl.Lock(args)
+	return nil
+}
+
+func (s *DatabaseProviderRPCServer) Unlock(args string, resp *string) error {
+	*resp = s.Impl.Unlock(args)
+	return nil
+}
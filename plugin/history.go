@@ -0,0 +1,86 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HistoryEntry the record of the execution of a release command on the database
+type HistoryEntry struct {
+	// the unique identifier of the entry, set by the database provider
+	Id int64 `json:"id,omitempty"`
+	// the application version of the release the command belongs to
+	AppVersion string `json:"appVersion"`
+	// the database version of the release the command belongs to
+	DbVersion string `json:"dbVersion"`
+	// the name of the command
+	Command string `json:"command"`
+	// the names of the scripts in the command
+	Scripts []string `json:"scripts"`
+	// the time the execution started
+	Start time.Time `json:"start"`
+	// the time the execution ended
+	End time.Time `json:"end"`
+	// the duration of the execution in milliseconds
+	Duration int64 `json:"duration"`
+	// true if the command was executed successfully
+	Success bool `json:"success"`
+	// the error returned by the command if it failed
+	Error string `json:"error,omitempty"`
+	// the operating system user running DbMan
+	User string `json:"user"`
+	// the host running DbMan
+	Host string `json:"host"`
+}
+
+func (h *HistoryEntry) ToString() string {
+	b, e := json.Marshal(h)
+	if e != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// NewHistoryEntry creates a new history entry from a serialised json string
+func NewHistoryEntry(jsonString string) (*HistoryEntry, error) {
+	h := &HistoryEntry{}
+	err := json.Unmarshal([]byte(jsonString), h)
+	return h, err
+}
+
+// HistoryFilter the criteria used to select command execution history entries
+// empty values do not filter
+type HistoryFilter struct {
+	// only entries for the specified application version
+	AppVersion string `json:"appVersion,omitempty"`
+	// only entries for the specified command name
+	Command string `json:"command,omitempty"`
+	// only entries with the specified status, either success or failure
+	Status string `json:"status,omitempty"`
+	// only entries started at or after the specified time
+	Since time.Time `json:"since,omitempty"`
+	// the maximum number of entries to return, most recent first
+	Limit int `json:"limit,omitempty"`
+}
+
+func (f *HistoryFilter) ToString() string {
+	b, e := json.Marshal(f)
+	if e != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// NewHistoryFilter creates a new history filter from a serialised json string
+func NewHistoryFilter(jsonString string) (*HistoryFilter, error) {
+	f := &HistoryFilter{}
+	err := json.Unmarshal([]byte(jsonString), f)
+	return f, err
+}
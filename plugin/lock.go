@@ -0,0 +1,82 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Lock a cluster wide lock preventing DbMan instances from changing the same database concurrently
+type Lock struct {
+	// the name of the locked resource (i.e. the database name)
+	Name string `json:"name"`
+	// the identity of the DbMan instance requesting the lock (e.g. dbman@host:pid)
+	Owner string `json:"owner"`
+	// the number of seconds to wait for the lock to be released by another instance
+	Timeout int `json:"timeout"`
+}
+
+// NewLock creates a new lock from a serialised json string
+func NewLock(jsonString string) (*Lock, error) {
+	l := &Lock{}
+	err := json.Unmarshal([]byte(jsonString), l)
+	return l, err
+}
+
+func (l *Lock) ToString() string {
+	b, e := json.Marshal(l)
+	if e != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// LocalLock serialises the callers of a database provider Lock within the same DbMan process
+// the database locks are not re-entrant, so a caller waits for the other callers in the process to release the lock first
+// the zero value is ready to use
+type LocalLock struct {
+	once sync.Once
+	slot chan struct{}
+}
+
+func (l *LocalLock) init() {
+	l.once.Do(func() {
+		l.slot = make(chan struct{}, 1)
+	})
+}
+
+// Acquire waits until the deadline for the other callers in the process to release the lock
+// returns false if the lock could not be acquired before the deadline
+func (l *LocalLock) Acquire(deadline time.Time) bool {
+	l.init()
+	// acquires the lock straight away if it is free, even if the deadline has elapsed
+	select {
+	case l.slot <- struct{}{}:
+		return true
+	default:
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case l.slot <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Release releases the lock acquired by Acquire
+func (l *LocalLock) Release() {
+	l.init()
+	select {
+	case <-l.slot:
+	default:
+	}
+}
@@ -19,51 +19,56 @@ import (
 // to execute commands and queries
 type Manifest struct {
 	// the database release version
-	DbVersion string `json:"dbVersion"`
+	DbVersion string `json:"dbVersion" yaml:"dbVersion"`
 	// the release description
-	Description string `json:"description,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 	// the path to where the command scripts are (if not specified use the root of the release)
-	CommandsPath string `json:"commandsPath,omitempty"`
+	CommandsPath string `json:"commandsPath,omitempty" yaml:"commandsPath,omitempty"`
 	// the path to where the query scripts are (if not specified use the root of the release)
-	QueriesPath string `json:"queriesPath,omitempty"`
+	QueriesPath string `json:"queriesPath,omitempty" yaml:"queriesPath,omitempty"`
 	// the database provider to use
-	DbProvider string `json:"dbProvider"`
+	DbProvider string `json:"dbProvider" yaml:"dbProvider"`
+	// how the variables are merged with the scripts of the release: template (the default) or replace
+	// a script or query can override it with its own merge mode
+	Merge string `json:"merge,omitempty" yaml:"merge,omitempty"`
 	// the list of commands available to execute
-	Commands []Command `json:"commands"`
+	Commands []Command `json:"commands" yaml:"commands"`
 	// the list of commands required to create the database in the first place
-	Create Action `json:"create"`
+	Create Action `json:"create" yaml:"create"`
 	// the list of commands required to deploy the database objects on an empty database
-	Deploy Action `json:"deploy"`
+	Deploy Action `json:"deploy" yaml:"deploy"`
 	// the list of commands required to upgrade an existing database
-	Upgrade Upgrade `json:"upgrade"`
+	Upgrade Upgrade `json:"upgrade" yaml:"upgrade"`
+	// the list of commands required to roll back an existing database to a previous release
+	Downgrade Downgrade `json:"downgrade,omitempty" yaml:"downgrade,omitempty"`
 	// the list of queries available to execute
-	Queries []Query `json:"queries"`
+	Queries []Query `json:"queries" yaml:"queries"`
 }
 
 // Action a database action containing either other sub-actions or commands
 type Action struct {
 	// the description for the command
-	Description string `json:"description"`
+	Description string `json:"description" yaml:"description"`
 	// the list of actions that comprise the command
-	Actions []string `json:"actions,omitempty"`
+	Actions []string `json:"actions,omitempty" yaml:"actions,omitempty"`
 	// the list of sub commands that comprise this command (if any)
-	Commands []string `json:"commands,omitempty"`
+	Commands []string `json:"commands,omitempty" yaml:"commands,omitempty"`
 }
 
 // Command a set of scripts that must be executed within the same database connection
 type Command struct {
 	// the command identifiable name
-	Name string `json:"name"`
+	Name string `json:"name" yaml:"name"`
 	// the description for the action
-	Description string `json:"description"`
+	Description string `json:"description" yaml:"description"`
 	// whether to run this action within a database transaction
-	Transactional bool `json:"transactional"`
+	Transactional bool `json:"transactional" yaml:"transactional"`
 	// whether to connect to the database as an Admin to execute this action
-	AsAdmin bool `json:"asAdmin"`
+	AsAdmin bool `json:"asAdmin" yaml:"asAdmin"`
 	// whether to connect to the database being managed or simply connect to the server with no specific database
-	UseDb bool `json:"useDb"`
+	UseDb bool `json:"useDb" yaml:"useDb"`
 	// the list of database scripts that will be executed as part of this action
-	Scripts []Script `json:"scripts"`
+	Scripts []Script `json:"scripts" yaml:"scripts"`
 }
 
 // NewCommand creates a new command from a serialised json string
@@ -84,14 +89,21 @@ func (c *Command) ToString() string {
 // Script a database script and zero or more merge variables
 type Script struct {
 	// the script identifiable name
-	Name string `json:"name"`
+	Name string `json:"name" yaml:"name"`
 	// the script file name in the git repository
-	File string `json:"file"`
+	File string `json:"file" yaml:"file"`
 	// a list of variables to be merged with the script prior to execution
-	Vars []Var `json:"vars"`
+	Vars []Var `json:"vars" yaml:"vars"`
+	// how the variables are merged with the script, if not specified use the merge mode of the manifest
+	// template: the script is a Go template; replace: only the {{name}} placeholders of the variables are replaced,
+	// for scripts containing other double braces (e.g. array literals such as '{{1,2},{3,4}}')
+	Merge string `json:"merge,omitempty" yaml:"merge,omitempty"`
 	// the content of the script file
 	// note: it is internal and automatically populated at runtime from the git repository
-	Content string `json:"content,omitempty"`
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+	// the checksum of the script file content before merging the variables
+	// note: it is internal and automatically populated at runtime from the git repository
+	FileChecksum string `json:"-" yaml:"-"`
 }
 
 func (c *Script) All() map[string]interface{} {
@@ -121,6 +133,15 @@ type Var struct {
 	// the name of the input parameter
 	// allows to pass query parameters via command line or query string
 	FromInput string `json:"fromInput,omitempty" yaml:"fromInput,omitempty"`
+	// the value of the variable if no value is found from its source
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	// the type of the value used to validate it: int, bool, date, uuid, text, enum or regex
+	// note: text if omitted
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// the allowed values of an enum variable
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+	// the regular expression the value of a regex variable must match
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
 }
 
 func NewVersion(jsonString string) (*Version, error) {
@@ -141,6 +162,8 @@ type Version struct {
 	Source string `json:"source"`
 	// the time the version was released
 	Time time.Time `json:"time"`
+	// the checksums of the merged scripts executed for the release
+	Scripts []ScriptChecksum `json:"scripts,omitempty"`
 }
 
 func (v *Version) ToString() string {
@@ -161,9 +184,14 @@ type Query struct {
 	File string `json:"file,omitempty" yaml:"file,omitempty"`
 	// a list of variables to merge with the query
 	Vars []Var `json:"vars,omitempty" yaml:"vars,omitempty"`
+	// how the variables are merged with the query (template or replace), if not specified use the merge mode of the manifest
+	Merge string `json:"merge,omitempty" yaml:"merge,omitempty"`
 	// the content of the script file
 	// note: it is internal and automatically populated at runtime from the git repository
 	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+	// the values of the input variables bound to the query parameters ($1, $2, ...) in the content
+	// note: it is internal and automatically populated at runtime from the command line or query string
+	Args []QueryArg `json:"args,omitempty" yaml:"args,omitempty"`
 }
 
 // NewQuery creates a new query from a serialised json string
@@ -183,10 +211,21 @@ func (q *Query) ToString() string {
 
 // Upgrade the commands to run at different stages in an upgrade
 type Upgrade struct {
-	Description string `json:"description"`
-	Prepare     string `json:"prepare"`
-	Alter       string `json:"alter"`
-	Deploy      string `json:"deploy"`
+	Description string `json:"description" yaml:"description"`
+	Prepare     string `json:"prepare" yaml:"prepare"`
+	Alter       string `json:"alter" yaml:"alter"`
+	Deploy      string `json:"deploy" yaml:"deploy"`
+}
+
+// Downgrade the commands to run at different stages in a downgrade
+type Downgrade struct {
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// the command to run on the release being rolled back before any schema changes (e.g. drop database objects)
+	Prepare string `json:"prepare,omitempty" yaml:"prepare,omitempty"`
+	// the command reverting the schema changes introduced by the release
+	Revert string `json:"revert,omitempty" yaml:"revert,omitempty"`
+	// the command to run on the target release to deploy its database objects (if omitted, the upgrade deploy command is used)
+	Deploy string `json:"deploy,omitempty" yaml:"deploy,omitempty"`
 }
 
 // get a JSON bytes reader for the Plan
@@ -204,13 +243,23 @@ func (m *Manifest) bytes() (*[]byte, error) {
 	return &b, err
 }
 
-// Decode get the Plan in the http Response
+// Decode get the Manifest from its content, either in JSON or YAML format
 func (m *Manifest) Decode(content []byte) (*Manifest, error) {
 	result := new(Manifest)
+	if !IsJSON(content) {
+		err := yaml.Unmarshal(content, result)
+		return result, err
+	}
 	err := json.NewDecoder(bytes.NewReader(content)).Decode(result)
 	return result, err
 }
 
+// IsJSON returns true if the content of a plan or manifest is in JSON format, otherwise it is taken as YAML
+func IsJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
 func (m *Manifest) getCommand(cmdName string) *Command {
 	for _, cmd := range m.Commands {
 		if cmdName == cmd.Name {
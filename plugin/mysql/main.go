@@ -0,0 +1,32 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package main
+
+import (
+	. "southwinds.dev/dbman/plugin"
+)
+
+// the entry point for the MySQL database plugin
+// the plugin also works with MariaDB servers
+func main() {
+	// launch the plugin process
+	// the plugin name must not start with "_" as it is reserved for native plugins
+	ServeDbPlugin("mysql", new(MySQLProvider))
+}
+
+// the version of the plugin, can be set at build time using -ldflags "-X main.version=<version>"
+var version = "1.0.0"
+
+// PluginInfo reports the version and capabilities of the plugin to "dbman plugin list"
+func (db *MySQLProvider) PluginInfo() *PluginInfo {
+	return &PluginInfo{
+		Name:         "mysql",
+		Version:      version,
+		Capabilities: []string{"queries", "checksums", "history", "progress", "backup", "restore", "lock"},
+	}
+}
@@ -0,0 +1,826 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"os"
+	"os/exec"
+	. "southwinds.dev/dbman/plugin"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Implementation of DbMan's database provider for MySQL and MariaDB
+// NOTE:
+//   - MySQLProvider implicitly implements the DatabaseProvider interface
+//   - MySQL commits DDL statements (e.g. CREATE TABLE) implicitly, so a transactional command can only roll back
+//     the data changes made since the last DDL statement
+//   - each function opens its own connection and closes it when done
+type MySQLProvider struct {
+	cfg *Conf
+	// serialises the callers of Lock in this instance
+	local LocalLock
+	// guards lockConn and lockDb
+	mu sync.Mutex
+	// the connection holding the named lock, if any
+	lockConn *sql.Conn
+	// the connection pool the lock connection belongs to
+	lockDb *sql.DB
+}
+
+// pass DbMan configuration to the database provider
+// config: configuration passed-in by DbMan to the plugin
+func (db *MySQLProvider) Setup(config *Conf) error {
+	if config != nil {
+		// allocate the parsed object to cfg
+		db.cfg = config
+		// return without error
+		return nil
+	}
+	return errors.New("!!! the MySQL Database plugin was not provided with a valid configuration\n")
+}
+
+// this function retrieves database version information in a Version struct
+// Version: the current database version, nil if the version table is empty
+// error: if failed to retrieve the version from the database
+func (db *MySQLProvider) GetVersion() (*Version, error) {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// query the database version table
+	v := &Version{}
+	err = conn.QueryRow(`
+		SELECT appVersion, dbVersion, COALESCE(description, ''), time, COALESCE(source, '')
+		FROM version
+		ORDER BY time DESC
+		LIMIT 1`).Scan(&v.AppVersion, &v.DbVersion, &v.Description, &v.Time, &v.Source)
+	// no results
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// return the version
+	return v, nil
+}
+
+// this function runs a database command
+// command: the struct containing the information required to run the command
+func (db *MySQLProvider) RunCommand(command *Command) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	// use this instead of writing to stdout
+	log := bytes.Buffer{}
+	// acquires a database connection
+	conn, err := db.newConn(command.AsAdmin, command.UseDb)
+	// if cannot connect to the server return with the error
+	if err != nil {
+		return log, err
+	}
+	defer conn.Close()
+	// log the db connection creation step
+	log.WriteString(fmt.Sprintf("? I am creating a db connection that is %v, %v and %v\n",
+		db.label("transactional", "non-transactional", command.Transactional),
+		db.label("as an admin", "as a user", command.AsAdmin),
+		db.label("to the db", "to the server", command.UseDb)))
+	// if the command is to be run within a database transaction
+	if command.Transactional {
+		// acquires a db transaction
+		tx, err := conn.Begin()
+		// if error then return
+		if err != nil {
+			return log, err
+		}
+		// for each database script in the command
+		for _, script := range command.Scripts {
+			// execute the content of the script
+			_, err = tx.Exec(script.Content)
+			// log the execution step
+			log.WriteString(fmt.Sprintf("? I have executed the script '%s'\n", script.Name))
+			// if we have an error return it
+			if err != nil {
+				// rollback the transaction
+				_ = tx.Rollback()
+				// return the error
+				return log, fmt.Errorf("failed to execute script: %s, %s", script.File, err)
+			}
+		}
+		// all good so commit the transaction
+		return log, tx.Commit()
+	}
+	// for each database script in the command
+	for _, script := range command.Scripts {
+		// execute the content of the script
+		if _, err = conn.Exec(script.Content); err != nil {
+			return log, fmt.Errorf("failed to execute script: %s, %s", script.File, err)
+		}
+	}
+	// return the execution log
+	return log, nil
+}
+
+// this function runs a database query
+// query: the struct containing the information required to run the query
+func (db *MySQLProvider) RunQuery(query *Query) (*Table, error) {
+	// acquires a database connection
+	conn, err := db.newConn(false, true)
+	// if cannot connect to the server return with the error
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// get the values bound to the query parameters ($1, $2, ...)
+	bound, err := query.BoundArgs()
+	if err != nil {
+		return nil, err
+	}
+	// MySQL parameters are positional (?), so the values are passed once for each parameter in the query
+	// a backslash escapes the next character in MySQL strings, unless the NO_BACKSLASH_ESCAPES mode is set
+	var args []interface{}
+	content := RewriteParams(query.Content, func(n int) string {
+		if n < 1 || n > len(bound) {
+			return fmt.Sprintf("$%d", n)
+		}
+		args = append(args, bound[n-1])
+		return "?"
+	}, true)
+	// execute the query content
+	rows, err := conn.Query(content, args...)
+	// if error then return it
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// puts together a generic table result
+	header, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{Header: header, Rows: make([]Row, 0)}
+	// scans each value into an interface so that any data type can be read
+	values := make([]interface{}, len(header))
+	pointers := make([]interface{}, len(header))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	// for each row in the result
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		// create a new row
+		row := make(Row, 0, len(header))
+		// populate the row with returned values from the query
+		for _, value := range values {
+			switch v := value.(type) {
+			case nil:
+				row = append(row, "")
+			// text, decimals and dates without parseTime are returned as bytes
+			case []byte:
+				row = append(row, string(v))
+			case time.Time:
+				row = append(row, v.String())
+			case float32:
+				row = append(row, strconv.FormatFloat(float64(v), 'f', -1, 32))
+			case float64:
+				row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				row = append(row, fmt.Sprintf("%v", v))
+			}
+		}
+		// add the row to the row set
+		table.Rows = append(table.Rows, row)
+	}
+	// return an instance of the generic table populated with the header and rows
+	return table, rows.Err()
+}
+
+// this function sets the version in the database
+// version: struct containing version information to persist in the database
+func (db *MySQLProvider) SetVersion(version *Version) error {
+	// create a db connection
+	conn, err := db.newConn(false, true)
+	// if error then return it
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// create the version table if it does not exist
+	// the table structure is defined by the specific database provider so that it contains the information
+	// in the Version struct
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS version
+            (
+                appVersion  VARCHAR(25) NOT NULL,
+                dbVersion   VARCHAR(25) NOT NULL,
+                description TEXT,
+                time        TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                source      VARCHAR(250),
+                CONSTRAINT version_app_version_db_release_pk PRIMARY KEY (appVersion, dbVersion)
+            )`)
+	// if error return it
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the version table: %v\n", err))
+	}
+	// insert a entry in the version table
+	// if the version was already there (e.g. after a downgrade), update the entry so that it becomes the latest version
+	_, err = conn.Exec(`INSERT INTO version(appVersion, dbVersion, description, source) VALUES(?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE description = VALUES(description), source = VALUES(source), time = CURRENT_TIMESTAMP(6)`,
+		version.AppVersion, version.DbVersion, version.Description, version.Source)
+	// if error return it
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the version table: %v\n", err))
+	}
+	// record the checksums of the scripts executed for the release
+	return db.setChecksums(conn, version.Scripts)
+}
+
+// this function retrieves the checksums of the scripts executed for all applied releases
+func (db *MySQLProvider) GetChecksums() ([]ScriptChecksum, error) {
+	checksums := make([]ScriptChecksum, 0)
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// if no checksums have been recorded yet, there is nothing to return
+	exists, err := db.tableExists(conn, "version_script")
+	if err != nil || !exists {
+		return checksums, err
+	}
+	rows, err := conn.Query(`
+		SELECT appVersion, dbVersion, command, script, file, checksum, fileChecksum, time
+		FROM version_script
+		ORDER BY time, appVersion, command, script`)
+	// if the query failed return the error
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the version_script table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := ScriptChecksum{}
+		err = rows.Scan(&c.AppVersion, &c.DbVersion, &c.Command, &c.Script, &c.File, &c.Checksum, &c.FileChecksum, &c.Time)
+		if err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, c)
+	}
+	return checksums, rows.Err()
+}
+
+// query information about the database server and returns it as a DbInfo struct
+func (db *MySQLProvider) GetInfo() (*DbInfo, error) {
+	// acquires a connection to the server, as the database might not exist yet
+	conn, err := db.newConn(true, false)
+	// if error returns it
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// query database server information
+	var version, comment, osName, machine string
+	err = conn.QueryRow(`SELECT VERSION(), @@version_comment, @@version_compile_os, @@version_compile_machine`).Scan(&version, &comment, &osName, &machine)
+	// if error returns it
+	if err != nil {
+		return nil, err
+	}
+	// MySQL does not report the compiler, so the processor bits are worked out from the target machine
+	bits := "32-bit"
+	if strings.Contains(machine, "64") {
+		bits = "64-bit"
+	}
+	// return the dbinfo struct
+	return &DbInfo{
+		Database:        fmt.Sprintf("%s %s", comment, version),
+		OperatingSystem: fmt.Sprintf("%s %s", osName, machine),
+		Compiler:        "unknown",
+		ProcessorBits:   bits,
+	}, nil
+}
+
+// this function takes a logical backup of the database using mysqldump
+// backup: the backup metadata, populated with the database version information and size of the dump
+func (db *MySQLProvider) Backup(backup *Backup) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	log := bytes.Buffer{}
+	// mysqldump must be installed in the host running DbMan
+	if _, err := exec.LookPath("mysqldump"); err != nil {
+		return log, errors.New("!!! I cannot find mysqldump in the path, ensure the MySQL client tools are installed\n")
+	}
+	// mysqldump only writes sql files
+	switch strings.ToLower(backup.Format) {
+	case "", "file":
+		backup.Format = "file"
+	default:
+		return log, errors.New(fmt.Sprintf("!!! I do not support backup format '%s', try file\n", backup.Format))
+	}
+	// the dump is taken using the admin credentials
+	options, err := db.optionsFile()
+	if err != nil {
+		return log, err
+	}
+	defer os.Remove(options)
+	// record the version of the database being backed up
+	version, err := db.GetVersion()
+	if err != nil || version == nil {
+		log.WriteString(fmt.Sprintf("! I cannot find any version information for the database, the backup will not record a version\n"))
+	} else {
+		backup.AppVersion = version.AppVersion
+		backup.DbVersion = version.DbVersion
+	}
+	backup.Database, _ = db.get("Db.Name")
+	log.WriteString(fmt.Sprintf("? I am dumping database '%s' in %s format to '%s'\n", backup.Database, backup.Format, backup.Path))
+	// execute mysqldump, taking a consistent snapshot of InnoDB tables without locking them
+	cmd := exec.Command("mysqldump", fmt.Sprintf("--defaults-extra-file=%s", options),
+		"--single-transaction", "--routines", "--triggers", "--events", fmt.Sprintf("--result-file=%s", backup.Path), backup.Database)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Write(out)
+	}
+	if err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot dump the database: %v\n", err))
+	}
+	// work out the size of the dump
+	info, err := os.Stat(backup.Path)
+	if err != nil {
+		return log, err
+	}
+	backup.Size = info.Size()
+	return log, nil
+}
+
+// this function restores the database from a logical backup taken by mysqldump
+// existing tables are dropped before being recreated from the backup
+// backup: the metadata of the backup to restore
+func (db *MySQLProvider) Restore(backup *Backup) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	log := bytes.Buffer{}
+	// the mysql client must be installed in the host running DbMan
+	if _, err := exec.LookPath("mysql"); err != nil {
+		return log, errors.New("!!! I cannot find mysql in the path, ensure the MySQL client tools are installed\n")
+	}
+	// check the dump is still there
+	dump, err := os.Open(backup.Path)
+	if err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot find the database dump for backup '%s': %v\n", backup.Name, err))
+	}
+	defer dump.Close()
+	// the restore is performed using the admin credentials
+	options, err := db.optionsFile()
+	if err != nil {
+		return log, err
+	}
+	defer os.Remove(options)
+	dbName, _ := db.get("Db.Name")
+	log.WriteString(fmt.Sprintf("? I am restoring backup '%s' from '%s'\n", backup.Name, backup.Path))
+	// execute the dump with the mysql client, the dump drops each table before recreating it
+	cmd := exec.Command("mysql", fmt.Sprintf("--defaults-extra-file=%s", options), dbName)
+	cmd.Stdin = dump
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Write(out)
+	}
+	if err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot restore the database: %v\n", err))
+	}
+	return log, nil
+}
+
+// this function acquires a MySQL named lock preventing other DbMan instances from changing the database
+// the lock is held by a dedicated server connection until Unlock is called or the connection is closed
+// lock: the lock information, if held by another instance the function waits up to the lock timeout
+func (db *MySQLProvider) Lock(lock *Lock) error {
+	deadline := time.Now().Add(time.Duration(lock.Timeout) * time.Second)
+	// the lock is not re-entrant, wait for other callers in this instance to release it
+	if !db.local.Acquire(deadline) {
+		return errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by another operation of this instance\n", lock.Name, lock.Timeout))
+	}
+	conn, pool, err := db.lockSession(lock, deadline)
+	if err != nil {
+		db.local.Release()
+		return err
+	}
+	db.mu.Lock()
+	db.lockConn, db.lockDb = conn, pool
+	db.mu.Unlock()
+	return nil
+}
+
+// acquires the named lock in a new session, waiting until the deadline if another session holds it
+func (db *MySQLProvider) lockSession(lock *Lock, deadline time.Time) (*sql.Conn, *sql.DB, error) {
+	// connects to the server rather than the database, as the database might not exist yet
+	pool, err := db.newConn(true, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	// named locks belong to the session, so the same connection must be used to acquire and release the lock
+	conn, err := pool.Conn(context.Background())
+	if err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+	name := db.lockName(lock.Name)
+	for {
+		var acquired sql.NullInt64
+		err = conn.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, 0)", name).Scan(&acquired)
+		if err != nil {
+			conn.Close()
+			pool.Close()
+			return nil, nil, errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s': %v\n", lock.Name, err))
+		}
+		if acquired.Valid && acquired.Int64 == 1 {
+			return conn, pool, nil
+		}
+		// if the lock timeout has elapsed, find out who is holding the lock
+		if time.Now().After(deadline) {
+			holder := db.lockHolder(conn, name)
+			conn.Close()
+			pool.Close()
+			return nil, nil, errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by %s\n", lock.Name, lock.Timeout, holder))
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// this function releases the MySQL named lock acquired by Lock
+func (db *MySQLProvider) Unlock(lock *Lock) error {
+	db.mu.Lock()
+	conn, pool := db.lockConn, db.lockDb
+	db.lockConn, db.lockDb = nil, nil
+	db.mu.Unlock()
+	// nothing to release
+	if conn == nil {
+		return nil
+	}
+	// let the next caller in this instance acquire the lock
+	defer db.local.Release()
+	_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", db.lockName(lock.Name))
+	// closing the session releases the lock even if the release failed
+	conn.Close()
+	pool.Close()
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot release the lock on database '%s': %v\n", lock.Name, err))
+	}
+	return nil
+}
+
+// this function records the execution of a release command in the command_history table
+func (db *MySQLProvider) SetHistory(entry *HistoryEntry) error {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS command_history
+            (
+                id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+                appVersion VARCHAR(25) NOT NULL,
+                dbVersion  VARCHAR(25) NOT NULL,
+                command    VARCHAR(100) NOT NULL,
+                scripts    TEXT,
+                start_time TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                end_time   TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                duration   BIGINT NOT NULL,
+                success    BOOLEAN NOT NULL,
+                error      TEXT,
+                username   VARCHAR(100),
+                host       VARCHAR(250)
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the command_history table: %v\n", err))
+	}
+	// MySQL does not have arrays so the script names are stored as a JSON array
+	scripts, err := json.Marshal(entry.Scripts)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(
+		`INSERT INTO command_history(appVersion, dbVersion, command, scripts, start_time, end_time, duration, success, error, username, host)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.AppVersion, entry.DbVersion, entry.Command, string(scripts), entry.Start.UTC(), entry.End.UTC(), entry.Duration, entry.Success, entry.Error, entry.User, entry.Host)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the command_history table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the command execution history entries matching the filter, most recent first
+func (db *MySQLProvider) GetHistory(filter *HistoryFilter) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// if no command has been recorded yet, there is nothing to return
+	exists, err := db.tableExists(conn, "command_history")
+	if err != nil || !exists {
+		return entries, err
+	}
+	// build the where clause from the filter
+	var (
+		where []string
+		args  []interface{}
+	)
+	if len(filter.AppVersion) > 0 {
+		where, args = append(where, "appVersion = ?"), append(args, filter.AppVersion)
+	}
+	if len(filter.Command) > 0 {
+		where, args = append(where, "command = ?"), append(args, filter.Command)
+	}
+	if len(filter.Status) > 0 {
+		where, args = append(where, "success = ?"), append(args, strings.EqualFold(filter.Status, "success"))
+	}
+	if !filter.Since.IsZero() {
+		where, args = append(where, "start_time >= ?"), append(args, filter.Since.UTC())
+	}
+	query := `SELECT id, appVersion, dbVersion, command, COALESCE(scripts, '[]'), start_time, end_time, duration, success, COALESCE(error, ''), COALESCE(username, ''), COALESCE(host, '')
+		FROM command_history`
+	if len(where) > 0 {
+		query += fmt.Sprintf(" WHERE %s", strings.Join(where, " AND "))
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the command_history table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			e       = HistoryEntry{}
+			scripts string
+		)
+		err = rows.Scan(&e.Id, &e.AppVersion, &e.DbVersion, &e.Command, &scripts, &e.Start, &e.End, &e.Duration, &e.Success, &e.Error, &e.User, &e.Host)
+		if err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(scripts), &e.Scripts)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// this function records the progress of an upgrade in the upgrade_progress table
+// the table holds a single row with the progress of the last upgrade
+func (db *MySQLProvider) SetProgress(progress *Progress) error {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS upgrade_progress
+            (
+                id       INTEGER PRIMARY KEY CHECK (id = 1),
+                progress TEXT NOT NULL,
+                time     TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the upgrade_progress table: %v\n", err))
+	}
+	_, err = conn.Exec(`INSERT INTO upgrade_progress(id, progress, time) VALUES(1, ?, CURRENT_TIMESTAMP(6))
+		ON DUPLICATE KEY UPDATE progress = VALUES(progress), time = VALUES(time)`, progress.ToString())
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the upgrade_progress table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the progress of the last upgrade, nil if no upgrade has been recorded
+func (db *MySQLProvider) GetProgress() (*Progress, error) {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	exists, err := db.tableExists(conn, "upgrade_progress")
+	if err != nil || !exists {
+		return nil, err
+	}
+	var progress string
+	err = conn.QueryRow(`SELECT progress FROM upgrade_progress WHERE id = 1`).Scan(&progress)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the upgrade_progress table: %v\n", err))
+	}
+	return NewProgress(progress)
+}
+
+// =========================================================================
+// UTILITY FUNCTIONS
+// =========================================================================
+
+// records the checksums of the scripts executed for a release in the version_script table
+// if a script is applied again (e.g. after a downgrade), its checksum is replaced
+func (db *MySQLProvider) setChecksums(conn *sql.DB, scripts []ScriptChecksum) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+	_, err := conn.Exec(`CREATE TABLE IF NOT EXISTS version_script
+            (
+                appVersion   VARCHAR(25) NOT NULL,
+                dbVersion    VARCHAR(25) NOT NULL,
+                command      VARCHAR(100) NOT NULL,
+                script       VARCHAR(100) NOT NULL,
+                file         VARCHAR(250) NOT NULL,
+                checksum     CHAR(64) NOT NULL,
+                fileChecksum CHAR(64) NOT NULL,
+                time         TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                CONSTRAINT version_script_pk PRIMARY KEY (appVersion, command, script)
+            )`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the version_script table: %v\n", err))
+	}
+	for _, s := range scripts {
+		_, err = conn.Exec(`INSERT INTO version_script(appVersion, dbVersion, command, script, file, checksum, fileChecksum, time) VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE dbVersion = VALUES(dbVersion), file = VALUES(file), checksum = VALUES(checksum), fileChecksum = VALUES(fileChecksum), time = VALUES(time)`,
+			s.AppVersion, s.DbVersion, s.Command, s.Script, s.File, s.Checksum, s.FileChecksum, s.Time.UTC())
+		if err != nil {
+			return errors.New(fmt.Sprintf("!!! I cannot record the checksum of script '%s': %v\n", s.Script, err))
+		}
+	}
+	return nil
+}
+
+// checks if a table exists in the connected database
+func (db *MySQLProvider) tableExists(conn *sql.DB, name string) (bool, error) {
+	var count int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`, name).Scan(&count)
+	return count > 0, err
+}
+
+// return the user and password to connect with
+// admin:
+//   - if true, the admin user is returned
+//   - if false, the database user is returned
+func (db *MySQLProvider) credentials(admin bool) (string, string, error) {
+	userKey, pwdKey := "Db.Username", "Db.Password"
+	if admin {
+		userKey, pwdKey = "Db.AdminUsername", "Db.AdminPassword"
+	}
+	user, found := db.get(userKey)
+	if !found {
+		return "", "", errors.New(fmt.Sprintf("!!! could not find %s config value\n", userKey))
+	}
+	pwd, found := db.get(pwdKey)
+	if !found {
+		return "", "", errors.New(fmt.Sprintf("!!! could not find %s config value\n", pwdKey))
+	}
+	return user, pwd, nil
+}
+
+// return the data source name used by the MySQL driver
+// admin:
+//   - if true, a connection using the admin user is returned
+//   - if false, a connection using the database user is returned
+//
+// database:
+//   - if true, connects to the database, otherwise to the server
+func (db *MySQLProvider) dsn(admin bool, database bool) (string, error) {
+	host, found := db.get("Db.Host")
+	if !found {
+		return "", errors.New(fmt.Sprint("!!! could not find Db.Host config value\n"))
+	}
+	port, found := db.get("Db.Port")
+	if !found {
+		return "", errors.New(fmt.Sprint("!!! could not find Db.Port config value\n"))
+	}
+	user, pwd, err := db.credentials(admin)
+	if err != nil {
+		return "", err
+	}
+	cfg := mysql.NewConfig()
+	cfg.User = user
+	cfg.Passwd = pwd
+	cfg.Net = "tcp"
+	cfg.Addr = fmt.Sprintf("%s:%s", host, port)
+	// times are stored and read in UTC
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	// scripts usually contain more than one statement
+	cfg.MultiStatements = true
+	// fails if the server cannot be reached within 4 seconds
+	cfg.Timeout = 4 * time.Second
+	cfg.Params = map[string]string{"time_zone": "'+00:00'"}
+	if database {
+		dbName, found := db.get("Db.Name")
+		if !found {
+			return "", errors.New(fmt.Sprint("!!! could not find Db.Name config value\n"))
+		}
+		cfg.DBName = dbName
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// create a new database connection
+// if it cannot connect within 4 seconds, it returns an error
+func (db *MySQLProvider) newConn(admin bool, database bool) (*sql.DB, error) {
+	dsn, err := db.dsn(admin, database)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection so that the scripts of a command share session variables and temporary tables
+	conn.SetMaxOpenConns(1)
+	// sql.Open does not connect, so checks the connection before returning it
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return nil, errors.New(fmt.Sprintf("!!! I cannot connect to the database: %v\n", err))
+	}
+	return conn, nil
+}
+
+// writes the admin credentials to a temporary options file read by the MySQL client tools
+// so that the password is not passed in the command line; the caller must remove the file
+func (db *MySQLProvider) optionsFile() (string, error) {
+	host, _ := db.get("Db.Host")
+	port, _ := db.get("Db.Port")
+	user, pwd, err := db.credentials(true)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "dbman-mysql-*.cnf")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	// the file is created with 0600 permissions
+	_, err = f.WriteString(fmt.Sprintf("[client]\nhost=%s\nport=%s\nuser=%s\npassword=\"%s\"\n", host, port, user, strings.ReplaceAll(pwd, `"`, `\"`)))
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (db *MySQLProvider) label(textTrue string, textFalse string, use bool) string {
+	if use {
+		return textTrue
+	} else {
+		return textFalse
+	}
+}
+
+func (db *MySQLProvider) get(key string) (string, bool) {
+	return db.cfg.GetString(key)
+}
+
+// return the name of the MySQL named lock for the specified database name
+// lock names are limited to 64 characters
+func (db *MySQLProvider) lockName(name string) string {
+	name = fmt.Sprintf("dbman:%s", name)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// return a description of the session holding the named lock
+func (db *MySQLProvider) lockHolder(conn *sql.Conn, name string) string {
+	var (
+		user, host string
+		id, secs   int64
+	)
+	err := conn.QueryRowContext(context.Background(), `
+		SELECT p.id, p.user, p.host, p.time
+		FROM information_schema.processlist p
+		WHERE p.id = IS_USED_LOCK(?)`, name).Scan(&id, &user, &host, &secs)
+	if err != nil {
+		return "an unknown session"
+	}
+	return fmt.Sprintf("connection %d (user %s from %s, in its current state for %d seconds)", id, user, host, secs)
+}
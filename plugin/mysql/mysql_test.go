@@ -0,0 +1,153 @@
+//go:build integration
+
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	. "southwinds.dev/dbman/plugin"
+	"testing"
+)
+
+// integration tests against a local mysqld, configured using the DbMan environment variables, e.g.:
+//
+//	docker run -d -p 3306:3306 -e MYSQL_ROOT_PASSWORD=my5ql mysql:8
+//	OX_DBM_DB_ADMINPASSWORD=my5ql go test -tags integration
+//
+// the tests only build with the integration tag, and are skipped if the server cannot be reached
+
+// creates a provider connecting to the local mysqld and to a test database created by the test
+func newTestProvider(t *testing.T) *MySQLProvider {
+	env := func(key string, value string) string {
+		if v, ok := os.LookupEnv(fmt.Sprintf("OX_DBM_DB_%s", key)); ok {
+			return v
+		}
+		return value
+	}
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"db": map[string]string{
+			"name":          env("NAME", "dbman_test"),
+			"host":          env("HOST", "localhost"),
+			"port":          env("PORT", "3306"),
+			"username":      env("USERNAME", "dbman"),
+			"password":      env("PASSWORD", "dbman"),
+			"adminusername": env("ADMINUSERNAME", "root"),
+			"adminpassword": env("ADMINPASSWORD", ""),
+		},
+	})
+	conf, _ := NewConf(string(cfg))
+	dbProvider := &MySQLProvider{}
+	if err := dbProvider.Setup(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbProvider.GetInfo(); err != nil {
+		t.Skipf("mysqld is not available: %v", err)
+	}
+	// creates the test database and user
+	name, _ := conf.GetString("Db.Name")
+	user, _ := conf.GetString("Db.Username")
+	pwd, _ := conf.GetString("Db.Password")
+	_, err := dbProvider.RunCommand(&Command{
+		Name:    "create-db",
+		AsAdmin: true,
+		UseDb:   false,
+		Scripts: []Script{{
+			Name: "create-db",
+			Content: fmt.Sprintf(`DROP DATABASE IF EXISTS %[1]s;
+				CREATE DATABASE %[1]s;
+				CREATE USER IF NOT EXISTS '%[2]s'@'%%' IDENTIFIED BY '%[3]s';
+				GRANT ALL PRIVILEGES ON %[1]s.* TO '%[2]s'@'%%';`, name, user, pwd),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dbProvider
+}
+
+func TestMySQLProvider_GetDbInfo(t *testing.T) {
+	dbProvider := newTestProvider(t)
+	i, err := dbProvider.GetInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(i)
+}
+
+func TestMySQLProvider_SetVersion(t *testing.T) {
+	dbProvider := newTestProvider(t)
+	// an empty database does not have a version table
+	if _, err := dbProvider.GetVersion(); err == nil {
+		t.Fatal("expected an error as the version table does not exist")
+	}
+	for _, appVersion := range []string{"1.0.0", "1.1.0"} {
+		err := dbProvider.SetVersion(&Version{AppVersion: appVersion, DbVersion: "1.0", Description: "test", Source: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := dbProvider.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == nil || v.AppVersion != "1.1.0" {
+		t.Fatalf("expected version 1.1.0, got %v", v)
+	}
+}
+
+func TestMySQLProvider_RunCommand(t *testing.T) {
+	dbProvider := newTestProvider(t)
+	command := &Command{
+		Name:          "create-table",
+		Transactional: true,
+		UseDb:         true,
+		Scripts: []Script{
+			{Name: "create", Content: "CREATE TABLE item(id INT PRIMARY KEY, name VARCHAR(50), price DECIMAL(5,2), created DATETIME);"},
+			{Name: "insert", Content: "INSERT INTO item VALUES(1, 'bolt', 0.25, NOW()); INSERT INTO item VALUES(2, 'it''s a nut', NULL, NOW());"},
+		},
+	}
+	if log, err := dbProvider.RunCommand(command); err != nil {
+		t.Fatalf("%v\n%s", err, log.String())
+	}
+	// a failed transactional command rolls back its data changes
+	command.Scripts = []Script{
+		{Name: "insert", Content: "INSERT INTO item VALUES(3, 'washer', 0.05, NOW());"},
+		{Name: "fail", Content: "INSERT INTO item VALUES(1, 'duplicate', 0, NOW());"},
+	}
+	if _, err := dbProvider.RunCommand(command); err == nil {
+		t.Fatal("expected a duplicate key error")
+	}
+	table, err := dbProvider.RunQuery(&Query{
+		Content: "SELECT id, name, price FROM item WHERE id >= $1 OR name = $1 ORDER BY id",
+		Args:    []QueryArg{{Name: "id", Type: "int", Value: "1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 2 || table.Rows[1][1] != "it's a nut" || table.Rows[0][2] != "0.25" || table.Rows[1][2] != "" {
+		t.Fatalf("unexpected query result %v", table.Rows)
+	}
+}
+
+func TestMySQLProvider_Lock(t *testing.T) {
+	dbProvider := newTestProvider(t)
+	lock := &Lock{Name: "dbman_test", Owner: "test", Timeout: 1}
+	if err := dbProvider.Lock(lock); err != nil {
+		t.Fatal(err)
+	}
+	defer dbProvider.Unlock(lock)
+	// another instance cannot acquire the lock
+	other := &MySQLProvider{cfg: dbProvider.cfg}
+	if err := other.Lock(lock); err == nil {
+		other.Unlock(lock)
+		t.Fatal("expected the lock to be held by the first instance")
+	}
+}
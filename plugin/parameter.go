@@ -16,7 +16,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
 type Parameter struct {
@@ -40,8 +39,12 @@ func NewParameterFromJSON(jsonString string) *Parameter {
 	return r
 }
 
+// GetString returns the value of the key as a string, or an empty string if the key is missing or is not a string
 func (r *Parameter) GetString(key string) string {
-	return r.value[key].(string)
+	if v, ok := r.value[key].(string); ok {
+		return v
+	}
+	return ""
 }
 
 func (r *Parameter) Get(key string) interface{} {
@@ -72,26 +75,83 @@ func (r *Parameter) GetTable() *Table {
 	return nil
 }
 
+func (r *Parameter) GetBackup() *Backup {
+	if r.value["result"] != nil {
+		if m, ok := r.value["result"].(map[string]interface{}); ok {
+			// new backup
+			b := &Backup{}
+			// marshal the map to json
+			bytes, _ := json.Marshal(m)
+			// unmarshal the json to Backup
+			json.Unmarshal(bytes, &b)
+			// return
+			return b
+		}
+	}
+	return nil
+}
+
+func (r *Parameter) GetChecksums() []ScriptChecksum {
+	checksums := make([]ScriptChecksum, 0)
+	if r.value["result"] != nil {
+		if s, ok := r.value["result"].([]interface{}); ok {
+			// marshal the slice to json
+			bytes, _ := json.Marshal(s)
+			// unmarshal the json to a slice of checksums
+			json.Unmarshal(bytes, &checksums)
+		}
+	}
+	return checksums
+}
+
+func (r *Parameter) GetHistory() []HistoryEntry {
+	entries := make([]HistoryEntry, 0)
+	if r.value["result"] != nil {
+		if s, ok := r.value["result"].([]interface{}); ok {
+			// marshal the slice to json
+			bytes, _ := json.Marshal(s)
+			// unmarshal the json to a slice of history entries
+			json.Unmarshal(bytes, &entries)
+		}
+	}
+	return entries
+}
+
+func (r *Parameter) GetProgress() *Progress {
+	if r.value["result"] != nil {
+		if m, ok := r.value["result"].(map[string]interface{}); ok {
+			// new progress
+			p := &Progress{}
+			// marshal the map to json
+			bytes, _ := json.Marshal(m)
+			// unmarshal the json to Progress
+			json.Unmarshal(bytes, &p)
+			// return
+			return p
+		}
+	}
+	return nil
+}
+
 func (r *Parameter) GetVersion() *Version {
-	v := r.value["result"].(map[string]interface{})
-	if v != nil {
-		t, _ := time.Parse("2006-01-02T15:04:05.000Z", v["time"].(string))
-		// the result is a map so turn it into a version object
-		version := &Version{
-			AppVersion:  v["appVersion"].(string),
-			DbVersion:   v["dbVersion"].(string),
-			Description: v["description"].(string),
-			Source:      v["source"].(string),
-			Time:        t,
+	if m, ok := r.value["result"].(map[string]interface{}); ok && len(m) > 0 {
+		// new version
+		v := &Version{}
+		// marshal the map to json
+		bytes, _ := json.Marshal(m)
+		// unmarshal the json to Version, the time is in RFC 3339 format
+		if err := json.Unmarshal(bytes, &v); err != nil {
+			return nil
 		}
-		return version
+		// return
+		return v
 	}
 	return nil
 }
 
 func (r *Parameter) GetDbInfo() *DbInfo {
 	if r.value["result"] != nil {
-		if m, ok := r.value["result"].(DbInfo); ok {
+		if m, ok := r.value["result"].(map[string]interface{}); ok {
 			// new table
 			info := &DbInfo{}
 			// marshal the map to json
@@ -150,7 +210,7 @@ func (r *Parameter) HasError() bool {
 func (r *Parameter) Error() error {
 	errMsg := r.value["error"]
 	if errMsg != nil {
-		return errors.New(r.value["error"].(string))
+		return errors.New(fmt.Sprintf("%v", errMsg))
 	}
 	return nil
 }
@@ -163,8 +223,8 @@ func (r *Parameter) PrintLog() {
 
 func (r *Parameter) GetLog() string {
 	var log string = ""
-	if r.value["log"] != nil {
-		log = r.value["log"].(string)
+	if l, ok := r.value["log"].(string); ok {
+		log = l
 		if strings.HasSuffix(log, "\n\n") {
 			log = strings.TrimSuffix(log, "\n")
 		}
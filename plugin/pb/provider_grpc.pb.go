@@ -0,0 +1,738 @@
+// DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+// Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+// Contributors to this project, hereby assign copyright in this code to the project,
+// to be licensed under the same terms as the rest of the code.
+
+// the protocol used by DbMan to talk to database provider plugins, negotiated as version 2 of the plugin handshake
+// plugins written in any language supported by gRPC can implement the DatabaseProvider service, see readme.md
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: provider.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DatabaseProvider_Setup_FullMethodName         = "/dbman.provider.v1.DatabaseProvider/Setup"
+	DatabaseProvider_GetPluginInfo_FullMethodName = "/dbman.provider.v1.DatabaseProvider/GetPluginInfo"
+	DatabaseProvider_GetInfo_FullMethodName       = "/dbman.provider.v1.DatabaseProvider/GetInfo"
+	DatabaseProvider_GetVersion_FullMethodName    = "/dbman.provider.v1.DatabaseProvider/GetVersion"
+	DatabaseProvider_SetVersion_FullMethodName    = "/dbman.provider.v1.DatabaseProvider/SetVersion"
+	DatabaseProvider_GetChecksums_FullMethodName  = "/dbman.provider.v1.DatabaseProvider/GetChecksums"
+	DatabaseProvider_SetHistory_FullMethodName    = "/dbman.provider.v1.DatabaseProvider/SetHistory"
+	DatabaseProvider_GetHistory_FullMethodName    = "/dbman.provider.v1.DatabaseProvider/GetHistory"
+	DatabaseProvider_SetProgress_FullMethodName   = "/dbman.provider.v1.DatabaseProvider/SetProgress"
+	DatabaseProvider_GetProgress_FullMethodName   = "/dbman.provider.v1.DatabaseProvider/GetProgress"
+	DatabaseProvider_RunCommand_FullMethodName    = "/dbman.provider.v1.DatabaseProvider/RunCommand"
+	DatabaseProvider_RunQuery_FullMethodName      = "/dbman.provider.v1.DatabaseProvider/RunQuery"
+	DatabaseProvider_Backup_FullMethodName        = "/dbman.provider.v1.DatabaseProvider/Backup"
+	DatabaseProvider_Restore_FullMethodName       = "/dbman.provider.v1.DatabaseProvider/Restore"
+	DatabaseProvider_Lock_FullMethodName          = "/dbman.provider.v1.DatabaseProvider/Lock"
+	DatabaseProvider_Unlock_FullMethodName        = "/dbman.provider.v1.DatabaseProvider/Unlock"
+)
+
+// DatabaseProviderClient is the client API for DatabaseProvider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// the service implemented by database provider plugins
+// errors raised by the database are returned in the error field of the responses, gRPC errors are only used for transport failures
+type DatabaseProviderClient interface {
+	// setup the provider with the DbMan configuration
+	Setup(ctx context.Context, in *SetupRequest, opts ...grpc.CallOption) (*Result, error)
+	// get the name, version and capabilities of the plugin
+	GetPluginInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PluginInfo, error)
+	// get database server general information
+	GetInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetInfoResponse, error)
+	// get database release version information
+	GetVersion(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetVersionResponse, error)
+	// set database release version information and record the checksums of the scripts executed for it
+	SetVersion(ctx context.Context, in *Version, opts ...grpc.CallOption) (*Result, error)
+	// get the checksums of the scripts executed for all applied releases
+	GetChecksums(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetChecksumsResponse, error)
+	// record the execution of a release command
+	SetHistory(ctx context.Context, in *HistoryEntry, opts ...grpc.CallOption) (*Result, error)
+	// get the command execution history entries matching the filter
+	GetHistory(ctx context.Context, in *HistoryFilter, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// record the progress of an upgrade
+	SetProgress(ctx context.Context, in *Progress, opts ...grpc.CallOption) (*Result, error)
+	// get the progress of the last upgrade
+	GetProgress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetProgressResponse, error)
+	// execute the scripts of a command
+	RunCommand(ctx context.Context, in *Command, opts ...grpc.CallOption) (*RunCommandResponse, error)
+	// execute a query
+	RunQuery(ctx context.Context, in *Query, opts ...grpc.CallOption) (*RunQueryResponse, error)
+	// take a logical backup of the database
+	Backup(ctx context.Context, in *BackupInfo, opts ...grpc.CallOption) (*BackupResponse, error)
+	// restore the database from a logical backup
+	Restore(ctx context.Context, in *BackupInfo, opts ...grpc.CallOption) (*RestoreResponse, error)
+	// acquire a cluster wide lock on the database, waiting up to the lock timeout if another instance holds it
+	Lock(ctx context.Context, in *LockInfo, opts ...grpc.CallOption) (*Result, error)
+	// release the cluster wide lock on the database
+	Unlock(ctx context.Context, in *LockInfo, opts ...grpc.CallOption) (*Result, error)
+}
+
+type databaseProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDatabaseProviderClient(cc grpc.ClientConnInterface) DatabaseProviderClient {
+	return &databaseProviderClient{cc}
+}
+
+func (c *databaseProviderClient) Setup(ctx context.Context, in *SetupRequest, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, DatabaseProvider_Setup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) GetPluginInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PluginInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PluginInfo)
+	err := c.cc.Invoke(ctx, DatabaseProvider_GetPluginInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) GetInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInfoResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_GetInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) GetVersion(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetVersionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVersionResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_GetVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) SetVersion(ctx context.Context, in *Version, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, DatabaseProvider_SetVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) GetChecksums(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetChecksumsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetChecksumsResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_GetChecksums_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) SetHistory(ctx context.Context, in *HistoryEntry, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, DatabaseProvider_SetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) GetHistory(ctx context.Context, in *HistoryFilter, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) SetProgress(ctx context.Context, in *Progress, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, DatabaseProvider_SetProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) GetProgress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetProgressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetProgressResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_GetProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) RunCommand(ctx context.Context, in *Command, opts ...grpc.CallOption) (*RunCommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunCommandResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_RunCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) RunQuery(ctx context.Context, in *Query, opts ...grpc.CallOption) (*RunQueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunQueryResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_RunQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) Backup(ctx context.Context, in *BackupInfo, opts ...grpc.CallOption) (*BackupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BackupResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_Backup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) Restore(ctx context.Context, in *BackupInfo, opts ...grpc.CallOption) (*RestoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreResponse)
+	err := c.cc.Invoke(ctx, DatabaseProvider_Restore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) Lock(ctx context.Context, in *LockInfo, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, DatabaseProvider_Lock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseProviderClient) Unlock(ctx context.Context, in *LockInfo, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, DatabaseProvider_Unlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DatabaseProviderServer is the server API for DatabaseProvider service.
+// All implementations must embed UnimplementedDatabaseProviderServer
+// for forward compatibility.
+//
+// the service implemented by database provider plugins
+// errors raised by the database are returned in the error field of the responses, gRPC errors are only used for transport failures
+type DatabaseProviderServer interface {
+	// setup the provider with the DbMan configuration
+	Setup(context.Context, *SetupRequest) (*Result, error)
+	// get the name, version and capabilities of the plugin
+	GetPluginInfo(context.Context, *emptypb.Empty) (*PluginInfo, error)
+	// get database server general information
+	GetInfo(context.Context, *emptypb.Empty) (*GetInfoResponse, error)
+	// get database release version information
+	GetVersion(context.Context, *emptypb.Empty) (*GetVersionResponse, error)
+	// set database release version information and record the checksums of the scripts executed for it
+	SetVersion(context.Context, *Version) (*Result, error)
+	// get the checksums of the scripts executed for all applied releases
+	GetChecksums(context.Context, *emptypb.Empty) (*GetChecksumsResponse, error)
+	// record the execution of a release command
+	SetHistory(context.Context, *HistoryEntry) (*Result, error)
+	// get the command execution history entries matching the filter
+	GetHistory(context.Context, *HistoryFilter) (*GetHistoryResponse, error)
+	// record the progress of an upgrade
+	SetProgress(context.Context, *Progress) (*Result, error)
+	// get the progress of the last upgrade
+	GetProgress(context.Context, *emptypb.Empty) (*GetProgressResponse, error)
+	// execute the scripts of a command
+	RunCommand(context.Context, *Command) (*RunCommandResponse, error)
+	// execute a query
+	RunQuery(context.Context, *Query) (*RunQueryResponse, error)
+	// take a logical backup of the database
+	Backup(context.Context, *BackupInfo) (*BackupResponse, error)
+	// restore the database from a logical backup
+	Restore(context.Context, *BackupInfo) (*RestoreResponse, error)
+	// acquire a cluster wide lock on the database, waiting up to the lock timeout if another instance holds it
+	Lock(context.Context, *LockInfo) (*Result, error)
+	// release the cluster wide lock on the database
+	Unlock(context.Context, *LockInfo) (*Result, error)
+	mustEmbedUnimplementedDatabaseProviderServer()
+}
+
+// UnimplementedDatabaseProviderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDatabaseProviderServer struct{}
+
+func (UnimplementedDatabaseProviderServer) Setup(context.Context, *SetupRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Setup not implemented")
+}
+func (UnimplementedDatabaseProviderServer) GetPluginInfo(context.Context, *emptypb.Empty) (*PluginInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPluginInfo not implemented")
+}
+func (UnimplementedDatabaseProviderServer) GetInfo(context.Context, *emptypb.Empty) (*GetInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInfo not implemented")
+}
+func (UnimplementedDatabaseProviderServer) GetVersion(context.Context, *emptypb.Empty) (*GetVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (UnimplementedDatabaseProviderServer) SetVersion(context.Context, *Version) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetVersion not implemented")
+}
+func (UnimplementedDatabaseProviderServer) GetChecksums(context.Context, *emptypb.Empty) (*GetChecksumsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChecksums not implemented")
+}
+func (UnimplementedDatabaseProviderServer) SetHistory(context.Context, *HistoryEntry) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetHistory not implemented")
+}
+func (UnimplementedDatabaseProviderServer) GetHistory(context.Context, *HistoryFilter) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedDatabaseProviderServer) SetProgress(context.Context, *Progress) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProgress not implemented")
+}
+func (UnimplementedDatabaseProviderServer) GetProgress(context.Context, *emptypb.Empty) (*GetProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProgress not implemented")
+}
+func (UnimplementedDatabaseProviderServer) RunCommand(context.Context, *Command) (*RunCommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunCommand not implemented")
+}
+func (UnimplementedDatabaseProviderServer) RunQuery(context.Context, *Query) (*RunQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunQuery not implemented")
+}
+func (UnimplementedDatabaseProviderServer) Backup(context.Context, *BackupInfo) (*BackupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Backup not implemented")
+}
+func (UnimplementedDatabaseProviderServer) Restore(context.Context, *BackupInfo) (*RestoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedDatabaseProviderServer) Lock(context.Context, *LockInfo) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lock not implemented")
+}
+func (UnimplementedDatabaseProviderServer) Unlock(context.Context, *LockInfo) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (UnimplementedDatabaseProviderServer) mustEmbedUnimplementedDatabaseProviderServer() {}
+func (UnimplementedDatabaseProviderServer) testEmbeddedByValue()                          {}
+
+// UnsafeDatabaseProviderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DatabaseProviderServer will
+// result in compilation errors.
+type UnsafeDatabaseProviderServer interface {
+	mustEmbedUnimplementedDatabaseProviderServer()
+}
+
+func RegisterDatabaseProviderServer(s grpc.ServiceRegistrar, srv DatabaseProviderServer) {
+	// If the following call pancis, it indicates UnimplementedDatabaseProviderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DatabaseProvider_ServiceDesc, srv)
+}
+
+func _DatabaseProvider_Setup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).Setup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_Setup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).Setup(ctx, req.(*SetupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_GetPluginInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).GetPluginInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_GetPluginInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).GetPluginInfo(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_GetInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).GetInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_GetInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).GetInfo(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).GetVersion(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_SetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Version)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).SetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_SetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).SetVersion(ctx, req.(*Version))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_GetChecksums_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).GetChecksums(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_GetChecksums_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).GetChecksums(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_SetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).SetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_SetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).SetHistory(ctx, req.(*HistoryEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).GetHistory(ctx, req.(*HistoryFilter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_SetProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Progress)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).SetProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_SetProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).SetProgress(ctx, req.(*Progress))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_GetProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).GetProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_GetProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).GetProgress(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_RunCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Command)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).RunCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_RunCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).RunCommand(ctx, req.(*Command))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_RunQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Query)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).RunQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_RunQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).RunQuery(ctx, req.(*Query))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_Backup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BackupInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).Backup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_Backup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).Backup(ctx, req.(*BackupInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BackupInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_Restore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).Restore(ctx, req.(*BackupInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_Lock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).Lock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_Lock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).Lock(ctx, req.(*LockInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DatabaseProvider_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseProviderServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DatabaseProvider_Unlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseProviderServer).Unlock(ctx, req.(*LockInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DatabaseProvider_ServiceDesc is the grpc.ServiceDesc for DatabaseProvider service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DatabaseProvider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dbman.provider.v1.DatabaseProvider",
+	HandlerType: (*DatabaseProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Setup",
+			Handler:    _DatabaseProvider_Setup_Handler,
+		},
+		{
+			MethodName: "GetPluginInfo",
+			Handler:    _DatabaseProvider_GetPluginInfo_Handler,
+		},
+		{
+			MethodName: "GetInfo",
+			Handler:    _DatabaseProvider_GetInfo_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _DatabaseProvider_GetVersion_Handler,
+		},
+		{
+			MethodName: "SetVersion",
+			Handler:    _DatabaseProvider_SetVersion_Handler,
+		},
+		{
+			MethodName: "GetChecksums",
+			Handler:    _DatabaseProvider_GetChecksums_Handler,
+		},
+		{
+			MethodName: "SetHistory",
+			Handler:    _DatabaseProvider_SetHistory_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _DatabaseProvider_GetHistory_Handler,
+		},
+		{
+			MethodName: "SetProgress",
+			Handler:    _DatabaseProvider_SetProgress_Handler,
+		},
+		{
+			MethodName: "GetProgress",
+			Handler:    _DatabaseProvider_GetProgress_Handler,
+		},
+		{
+			MethodName: "RunCommand",
+			Handler:    _DatabaseProvider_RunCommand_Handler,
+		},
+		{
+			MethodName: "RunQuery",
+			Handler:    _DatabaseProvider_RunQuery_Handler,
+		},
+		{
+			MethodName: "Backup",
+			Handler:    _DatabaseProvider_Backup_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _DatabaseProvider_Restore_Handler,
+		},
+		{
+			MethodName: "Lock",
+			Handler:    _DatabaseProvider_Lock_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _DatabaseProvider_Unlock_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "provider.proto",
+}
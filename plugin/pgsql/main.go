@@ -20,3 +20,15 @@ func main() {
 	// the plugin name must not start with "_" as it is reserved for native plugins
 	ServeDbPlugin("pgsql", new(PgSQLProvider))
 }
+
+// the version of the plugin, can be set at build time using -ldflags "-X main.version=<version>"
+var version = "1.0.0"
+
+// PluginInfo reports the version and capabilities of the plugin to "dbman plugin list"
+func (db *PgSQLProvider) PluginInfo() *PluginInfo {
+	return &PluginInfo{
+		Name:         "pgsql",
+		Version:      version,
+		Capabilities: []string{"queries", "checksums", "history", "progress", "backup", "restore", "lock"},
+	}
+}
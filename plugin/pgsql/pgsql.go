@@ -14,20 +14,32 @@ import (
 	"fmt"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"hash/crc32"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
 	. "southwinds.dev/dbman/plugin"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Implementation of DbMan's database provider for PostgreSQL
 // NOTE:
 //   - PgSQLProvider implicitly implements the DatabaseProvider interface
-//   - the provider uses connection pooling so should not call conn.Close()
+//   - newConn creates a connection pool per call, which the caller must close when done
 type PgSQLProvider struct {
 	cfg *Conf
+	// serialises the callers of Lock in this process
+	local LocalLock
+	// guards lockConn
+	mu sync.Mutex
+	// the connection holding the advisory lock, if any
+	lockConn *pgx.Conn
 }
 
 // pass DbMan configuration to the database provider
@@ -52,6 +64,8 @@ func (db *PgSQLProvider) GetVersion() (*Version, error) {
 	if err != nil {
 		return nil, err
 	}
+	// release the connections of the pool when done
+	defer conn.Close()
 	// query the database version table
 	rows, err := conn.Query(context.Background(), `
 		SELECT appVersion, dbVersion, description, time, source
@@ -99,6 +113,8 @@ func (db *PgSQLProvider) RunCommand(command *Command) (bytes.Buffer, error) {
 	if err != nil {
 		return log, err
 	}
+	// release the connections of the pool when done
+	defer conn.Close()
 	// if the command is to be run within a database transaction
 	if command.Transactional {
 		// log the db connection creation step
@@ -157,12 +173,21 @@ func (db *PgSQLProvider) RunQuery(query *Query) (*Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	// release the connections of the pool when done
+	defer conn.Close()
+	// get the values bound to the query parameters ($1, $2, ...)
+	args, err := query.BoundArgs()
+	if err != nil {
+		return nil, err
+	}
 	// execute the query content
-	result, err := conn.Query(context.Background(), query.Content)
+	result, err := conn.Query(context.Background(), query.Content, args...)
 	// if error then return it
 	if err != nil {
 		return nil, err
 	}
+	// closes the result set, which otherwise holds a connection of the pool
+	defer result.Close()
 	// puts together a generic table result
 	header := make(Row, 0) // the table header
 	rows := make([]Row, 0) // a slice of table rows
@@ -219,8 +244,6 @@ func (db *PgSQLProvider) RunQuery(query *Query) (*Table, error) {
 		// add the row to the row set
 		rows = append(rows, row)
 	}
-	// closes the result set
-	result.Close()
 	// return an instance of the generic table populated with the header and rows
 	return &Table{
 		Header: header,
@@ -237,10 +260,15 @@ func (db *PgSQLProvider) SetVersion(version *Version) error {
 	if err != nil {
 		return err
 	}
+	// release the connections of the pool when done
+	defer conn.Close()
 	// find out if the version table exists in the database
-	_, err = conn.Query(context.Background(), "SELECT * FROM version")
-	// an error indicates the table does not exist, and therefore attempts to create it
+	exists, err := db.tableExists(conn, "version")
 	if err != nil {
+		return err
+	}
+	// if the table does not exist, attempts to create it
+	if !exists {
 		// create the version table
 		err2 := db.createVersionTable(conn)
 		// if error return it
@@ -249,14 +277,54 @@ func (db *PgSQLProvider) SetVersion(version *Version) error {
 		}
 	}
 	// insert a entry in the version table
+	// if the version was already there (e.g. after a downgrade), update the entry so that it becomes the latest version
 	_, err = conn.Exec(context.Background(),
-		fmt.Sprintf(`INSERT INTO "version"(appVersion, dbVersion, description, source) VALUES('%s', '%s', '%s', '%s');`,
+		fmt.Sprintf(`INSERT INTO "version"(appVersion, dbVersion, description, source) VALUES('%s', '%s', '%s', '%s')
+			ON CONFLICT (appVersion, dbVersion) DO UPDATE
+			SET description = EXCLUDED.description, source = EXCLUDED.source, time = CURRENT_TIMESTAMP(6);`,
 			version.AppVersion, version.DbVersion, version.Description, version.Source))
 	// if error return it
 	if err != nil {
 		return errors.New(fmt.Sprintf("!!! I cannot update the version table: %v\n", err))
 	}
-	return err
+	// record the checksums of the scripts executed for the release
+	return db.setChecksums(conn, version.Scripts)
+}
+
+// this function retrieves the checksums of the scripts executed for all applied releases
+func (db *PgSQLProvider) GetChecksums() ([]ScriptChecksum, error) {
+	checksums := make([]ScriptChecksum, 0)
+	// connect to the database server
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	// release the connections of the pool when done
+	defer conn.Close()
+	// if no checksums have been recorded yet, there is nothing to return
+	exists, err := db.tableExists(conn, "version_script")
+	if err != nil || !exists {
+		return checksums, err
+	}
+	rows, err := conn.Query(context.Background(), `
+		SELECT appVersion, dbVersion, command, script, file, checksum, fileChecksum, time
+		FROM version_script
+		ORDER BY time, appVersion, command, script`)
+	// if the query failed return the error
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the version_script table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := ScriptChecksum{}
+		err = rows.Scan(&c.AppVersion, &c.DbVersion, &c.Command, &c.Script, &c.File, &c.Checksum, &c.FileChecksum, &c.Time)
+		if err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, c)
+	}
+	return checksums, rows.Err()
 }
 
 // query information about the database server and returns it as a DbInfo struct
@@ -267,6 +335,8 @@ func (db *PgSQLProvider) GetInfo() (*DbInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	// release the connections of the pool when done
+	defer conn.Close()
 	// query database server information
 	rows, err := conn.Query(context.Background(), `SELECT version()`)
 	// if error returns it
@@ -298,6 +368,173 @@ func (db *PgSQLProvider) GetInfo() (*DbInfo, error) {
 	}, nil
 }
 
+// this function takes a logical backup of the database using pg_dump
+// backup: the backup metadata, populated with the database version information and size of the dump
+func (db *PgSQLProvider) Backup(backup *Backup) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	log := bytes.Buffer{}
+	// pg_dump must be installed in the host running DbMan
+	if _, err := exec.LookPath("pg_dump"); err != nil {
+		return log, errors.New("!!! I cannot find pg_dump in the path, ensure the PostgreSQL client tools are installed\n")
+	}
+	// work out the pg_dump output format
+	var format string
+	switch strings.ToLower(backup.Format) {
+	case "", "file":
+		backup.Format = "file"
+		format = "--format=custom"
+	case "directory":
+		format = "--format=directory"
+	default:
+		return log, errors.New(fmt.Sprintf("!!! I do not support backup format '%s', try file or directory\n", backup.Format))
+	}
+	// the dump is taken using the admin credentials
+	connArgs, env, err := db.toolConn()
+	if err != nil {
+		return log, err
+	}
+	// record the version of the database being backed up
+	version, err := db.GetVersion()
+	if err != nil || version == nil {
+		log.WriteString(fmt.Sprintf("! I cannot find any version information for the database, the backup will not record a version\n"))
+	} else {
+		backup.AppVersion = version.AppVersion
+		backup.DbVersion = version.DbVersion
+	}
+	backup.Database, _ = db.get("Db.Name")
+	log.WriteString(fmt.Sprintf("? I am dumping database '%s' in %s format to '%s'\n", backup.Database, backup.Format, backup.Path))
+	// execute pg_dump
+	cmd := exec.Command("pg_dump", append(connArgs, format, fmt.Sprintf("--file=%s", backup.Path), "--no-password")...)
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Write(out)
+	}
+	if err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot dump the database: %v\n", err))
+	}
+	// work out the size of the dump
+	backup.Size, err = db.size(backup.Path)
+	if err != nil {
+		return log, err
+	}
+	return log, nil
+}
+
+// this function restores the database from a logical backup taken by pg_dump
+// existing database objects are dropped before being recreated from the backup
+// backup: the metadata of the backup to restore
+func (db *PgSQLProvider) Restore(backup *Backup) (bytes.Buffer, error) {
+	// create a buffer to write execution output to be passed back to DbMan
+	log := bytes.Buffer{}
+	// pg_restore must be installed in the host running DbMan
+	if _, err := exec.LookPath("pg_restore"); err != nil {
+		return log, errors.New("!!! I cannot find pg_restore in the path, ensure the PostgreSQL client tools are installed\n")
+	}
+	// check the dump is still there
+	if _, err := os.Stat(backup.Path); err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot find the database dump for backup '%s': %v\n", backup.Name, err))
+	}
+	// the restore is performed using the admin credentials
+	connArgs, env, err := db.toolConn()
+	if err != nil {
+		return log, err
+	}
+	log.WriteString(fmt.Sprintf("? I am restoring backup '%s' from '%s'\n", backup.Name, backup.Path))
+	// execute pg_restore, pg_restore detects whether the dump is a file or a directory
+	cmd := exec.Command("pg_restore", append(connArgs, "--clean", "--if-exists", "--single-transaction", "--no-password", backup.Path)...)
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Write(out)
+	}
+	if err != nil {
+		return log, errors.New(fmt.Sprintf("!!! I cannot restore the database: %v\n", err))
+	}
+	return log, nil
+}
+
+// this function acquires a PostgreSQL advisory lock preventing other DbMan instances from changing the database
+// the lock is held by a dedicated server connection until Unlock is called or the connection is closed
+// lock: the lock information, if held by another instance the function waits up to the lock timeout
+func (db *PgSQLProvider) Lock(lock *Lock) error {
+	deadline := time.Now().Add(time.Duration(lock.Timeout) * time.Second)
+	// the lock is not re-entrant, wait for other callers in this instance to release it
+	if !db.local.Acquire(deadline) {
+		return errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by another operation of this instance\n", lock.Name, lock.Timeout))
+	}
+	conn, err := db.lockSession(lock, deadline)
+	if err != nil {
+		db.local.Release()
+		return err
+	}
+	db.mu.Lock()
+	db.lockConn = conn
+	db.mu.Unlock()
+	return nil
+}
+
+// opens a session and acquires the advisory lock in it, waiting until the deadline if another instance holds it
+func (db *PgSQLProvider) lockSession(lock *Lock, deadline time.Time) (*pgx.Conn, error) {
+	// connects to the server rather than the database, as the database might not exist yet
+	connStr, err := db.connString(true, false)
+	if err != nil {
+		return nil, err
+	}
+	config, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	// identify the connection so that other instances can tell who holds the lock
+	config.RuntimeParams["application_name"] = lock.Owner
+	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+	defer cancel()
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	key := db.lockKey(lock.Name)
+	for {
+		var acquired bool
+		err = conn.QueryRow(context.Background(), "SELECT pg_try_advisory_lock($1, $2)", lockClass, key).Scan(&acquired)
+		if err != nil {
+			conn.Close(context.Background())
+			return nil, errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s': %v\n", lock.Name, err))
+		}
+		if acquired {
+			return conn, nil
+		}
+		// if the lock timeout has elapsed, find out who is holding the lock
+		if time.Now().After(deadline) {
+			holder := db.lockHolder(conn, key)
+			conn.Close(context.Background())
+			return nil, errors.New(fmt.Sprintf("!!! I cannot acquire the lock on database '%s' within %d seconds as it is held by %s\n", lock.Name, lock.Timeout, holder))
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// this function releases the PostgreSQL advisory lock acquired by Lock
+func (db *PgSQLProvider) Unlock(lock *Lock) error {
+	db.mu.Lock()
+	conn := db.lockConn
+	db.lockConn = nil
+	db.mu.Unlock()
+	// nothing to release
+	if conn == nil {
+		return nil
+	}
+	// let the next caller in this instance acquire the lock
+	defer db.local.Release()
+	_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1, $2)", lockClass, db.lockKey(lock.Name))
+	// closing the session releases the lock even if the unlock failed
+	conn.Close(context.Background())
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot release the lock on database '%s': %v\n", lock.Name, err))
+	}
+	return nil
+}
+
 // =========================================================================
 // UTILITY FUNCTIONS
 // =========================================================================
@@ -331,6 +568,205 @@ func (db *PgSQLProvider) createVersionTable(conn *pgxpool.Pool) error {
 	return nil
 }
 
+// this function records the execution of a release command in the command_history table
+func (db *PgSQLProvider) SetHistory(entry *HistoryEntry) error {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return err
+	}
+	// release the connections of the pool when done
+	defer conn.Close()
+	// the table is owned by the admin user so that the database user cannot tamper with the history
+	_, err = conn.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS command_history
+            (
+                id         BIGSERIAL PRIMARY KEY,
+                appVersion CHARACTER VARYING(25) NOT NULL COLLATE pg_catalog."default",
+                dbVersion  CHARACTER VARYING(25) NOT NULL COLLATE pg_catalog."default",
+                command    CHARACTER VARYING(100) NOT NULL COLLATE pg_catalog."default",
+                scripts    TEXT[],
+                start_time TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+                end_time   TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+                duration   BIGINT NOT NULL,
+                success    BOOLEAN NOT NULL,
+                error      TEXT,
+                username   CHARACTER VARYING(100),
+                host       CHARACTER VARYING(250)
+            ) WITH (OIDS = FALSE) TABLESPACE pg_default;`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the command_history table: %v\n", err))
+	}
+	_, err = conn.Exec(context.Background(),
+		`INSERT INTO command_history(appVersion, dbVersion, command, scripts, start_time, end_time, duration, success, error, username, host)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		entry.AppVersion, entry.DbVersion, entry.Command, entry.Scripts, entry.Start, entry.End, entry.Duration, entry.Success, entry.Error, entry.User, entry.Host)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the command_history table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the command execution history entries matching the filter, most recent first
+func (db *PgSQLProvider) GetHistory(filter *HistoryFilter) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	// release the connections of the pool when done
+	defer conn.Close()
+	// if no command has been recorded yet, there is nothing to return
+	exists, err := db.tableExists(conn, "command_history")
+	if err != nil || !exists {
+		return entries, err
+	}
+	// build the where clause from the filter
+	var (
+		where []string
+		args  []interface{}
+	)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if len(filter.AppVersion) > 0 {
+		addCondition("appVersion = $%d", filter.AppVersion)
+	}
+	if len(filter.Command) > 0 {
+		addCondition("command = $%d", filter.Command)
+	}
+	if len(filter.Status) > 0 {
+		addCondition("success = $%d", strings.EqualFold(filter.Status, "success"))
+	}
+	if !filter.Since.IsZero() {
+		addCondition("start_time >= $%d", filter.Since)
+	}
+	query := `SELECT id, appVersion, dbVersion, command, scripts, start_time, end_time, duration, success, COALESCE(error, ''), COALESCE(username, ''), COALESCE(host, '')
+		FROM command_history`
+	if len(where) > 0 {
+		query += fmt.Sprintf(" WHERE %s", strings.Join(where, " AND "))
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	rows, err := conn.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the command_history table: %v\n", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := HistoryEntry{}
+		err = rows.Scan(&e.Id, &e.AppVersion, &e.DbVersion, &e.Command, &e.Scripts, &e.Start, &e.End, &e.Duration, &e.Success, &e.Error, &e.User, &e.Host)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// this function records the progress of an upgrade in the upgrade_progress table
+// the table holds a single row with the progress of the last upgrade
+func (db *PgSQLProvider) SetProgress(progress *Progress) error {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return err
+	}
+	// release the connections of the pool when done
+	defer conn.Close()
+	// the table is owned by the admin user so that the database user cannot tamper with the progress
+	_, err = conn.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS upgrade_progress
+            (
+                id       INTEGER PRIMARY KEY CHECK (id = 1),
+                progress JSONB NOT NULL,
+                time     TIMESTAMP(6) WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP(6)
+            ) WITH (OIDS = FALSE) TABLESPACE pg_default;`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the upgrade_progress table: %v\n", err))
+	}
+	_, err = conn.Exec(context.Background(),
+		`INSERT INTO upgrade_progress(id, progress, time) VALUES(1, $1, CURRENT_TIMESTAMP(6))
+		ON CONFLICT (id) DO UPDATE SET progress = EXCLUDED.progress, time = EXCLUDED.time`, progress.ToString())
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot update the upgrade_progress table: %v\n", err))
+	}
+	return nil
+}
+
+// this function retrieves the progress of the last upgrade, nil if no upgrade has been recorded
+func (db *PgSQLProvider) GetProgress() (*Progress, error) {
+	// connect to the database
+	conn, err := db.newConn(true, true)
+	// if the connection failed return the error
+	if err != nil {
+		return nil, err
+	}
+	// release the connections of the pool when done
+	defer conn.Close()
+	exists, err := db.tableExists(conn, "upgrade_progress")
+	if err != nil || !exists {
+		return nil, err
+	}
+	var progress string
+	err = conn.QueryRow(context.Background(), `SELECT progress::TEXT FROM upgrade_progress WHERE id = 1`).Scan(&progress)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("!!! I cannot query the upgrade_progress table: %v\n", err))
+	}
+	return NewProgress(progress)
+}
+
+// records the checksums of the scripts executed for a release in the version_script table
+// if a script is applied again (e.g. after a downgrade), its checksum is replaced
+func (db *PgSQLProvider) setChecksums(conn *pgxpool.Pool, scripts []ScriptChecksum) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+	// the table is owned by the admin user so that the database user cannot tamper with the recorded checksums
+	_, err := conn.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS version_script
+            (
+                appVersion   CHARACTER VARYING(25) NOT NULL COLLATE pg_catalog."default",
+                dbVersion    CHARACTER VARYING(25) NOT NULL COLLATE pg_catalog."default",
+                command      CHARACTER VARYING(100) NOT NULL COLLATE pg_catalog."default",
+                script       CHARACTER VARYING(100) NOT NULL COLLATE pg_catalog."default",
+                file         CHARACTER VARYING(250) NOT NULL COLLATE pg_catalog."default",
+                checksum     CHARACTER(64) NOT NULL,
+                fileChecksum CHARACTER(64) NOT NULL,
+                time         TIMESTAMP(6) WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP(6),
+                CONSTRAINT version_script_pk PRIMARY KEY (appVersion, command, script)
+            ) WITH (OIDS = FALSE) TABLESPACE pg_default;`)
+	if err != nil {
+		return errors.New(fmt.Sprintf("!!! I cannot create the version_script table: %v\n", err))
+	}
+	for _, s := range scripts {
+		_, err = conn.Exec(context.Background(),
+			`INSERT INTO version_script(appVersion, dbVersion, command, script, file, checksum, fileChecksum, time) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (appVersion, command, script) DO UPDATE
+			SET dbVersion = EXCLUDED.dbVersion, file = EXCLUDED.file, checksum = EXCLUDED.checksum, fileChecksum = EXCLUDED.fileChecksum, time = EXCLUDED.time;`,
+			s.AppVersion, s.DbVersion, s.Command, s.Script, s.File, s.Checksum, s.FileChecksum, s.Time)
+		if err != nil {
+			return errors.New(fmt.Sprintf("!!! I cannot record the checksum of script '%s': %v\n", s.Script, err))
+		}
+	}
+	return nil
+}
+
+// checks if a table exists in the public schema of the connected database
+func (db *PgSQLProvider) tableExists(conn *pgxpool.Pool, name string) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = $1)`, name).Scan(&exists)
+	return exists, err
+}
+
 // return the connection string
 // admin:
 //   - if true, a connection using the postgres user is returned
@@ -379,6 +815,26 @@ func (db *PgSQLProvider) connString(admin bool, database bool) (string, error) {
 	return connStr, nil
 }
 
+// returns the connection arguments and environment used to run the PostgreSQL client tools with the admin credentials
+// the password is passed in the PGPASSWORD variable, so that it is not visible in the process list
+func (db *PgSQLProvider) toolConn() ([]string, []string, error) {
+	var values []string
+	for _, key := range []string{"Db.Host", "Db.Port", "Db.AdminUsername", "Db.AdminPassword", "Db.Name"} {
+		value, found := db.get(key)
+		if !found {
+			return nil, nil, errors.New(fmt.Sprintf("!!! could not find %s config value", key))
+		}
+		values = append(values, value)
+	}
+	args := []string{
+		fmt.Sprintf("--host=%s", values[0]),
+		fmt.Sprintf("--port=%s", values[1]),
+		fmt.Sprintf("--username=%s", values[2]),
+		fmt.Sprintf("--dbname=%s", values[4]),
+	}
+	return args, append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", values[3])), nil
+}
+
 // this type carries either a connection or an error
 // used in a channel used by the connection go routine
 type conn struct {
@@ -401,6 +857,7 @@ func (db *PgSQLProvider) newConn(admin bool, database bool) (*pgxpool.Pool, erro
 		if e != nil {
 			// send the error through the channel
 			connect <- conn{conn: nil, err: e}
+			return
 		}
 		// connects to the database
 		c, e := pgxpool.Connect(context.Background(), connStr)
@@ -426,6 +883,12 @@ func (db *PgSQLProvider) newConn(admin bool, database bool) (*pgxpool.Pool, erro
 	// the connection has not yet returned when the timeout happens
 	case <-timeout:
 		{
+			// close the pool if the connection is established after the timeout
+			go func() {
+				if connection := <-connect; connection.conn != nil {
+					connection.conn.Close()
+				}
+			}()
 			return nil, errors.New("!!! I cannot connect to the database, the timed out period has elapsed\n")
 		}
 	}
@@ -470,3 +933,47 @@ func (db *PgSQLProvider) toTime(microseconds int64) string {
 	hours := ((((((microseconds / 1000) - milliseconds) / 1000) - seconds) / 60) - minutes) / 60
 	return fmt.Sprintf("%02v:%02v:%02v.%03v", hours, minutes, seconds, milliseconds)
 }
+
+// return the size in bytes of a file or of all the files in a directory
+func (db *PgSQLProvider) size(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// the advisory lock key identifying DbMan locks
+const lockClass int32 = 0x44624d6e
+
+// return the advisory lock key for the specified database name
+func (db *PgSQLProvider) lockKey(name string) int32 {
+	return int32(crc32.ChecksumIEEE([]byte(name)) & 0x7fffffff)
+}
+
+// return a description of the session holding the advisory lock
+func (db *PgSQLProvider) lockHolder(conn *pgx.Conn, key int32) string {
+	var (
+		application, client string
+		pid                 int32
+		since               time.Time
+	)
+	err := conn.QueryRow(context.Background(), `
+		SELECT a.application_name, COALESCE(host(a.client_addr), 'local'), a.pid, a.backend_start
+		FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+		  AND l.classid::bigint = $1 AND l.objid::bigint = $2 AND l.objsubid = 2`, lockClass, key).Scan(&application, &client, &pid, &since)
+	if err != nil {
+		return "an unknown session"
+	}
+	if len(application) == 0 {
+		application = "an unnamed session"
+	}
+	return fmt.Sprintf("%s (client %s, backend pid %d, connected since %s)", application, client, pid, since.Format(time.RFC3339))
+}
@@ -0,0 +1,74 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// the version of the protocol used by DbMan to talk to database plugins: typed gRPC messages defined in pb/provider.proto
+	// DbMan refuses to load plugins that support neither this nor the legacy protocol version
+	ProtocolVersion = 2
+	// the version of the legacy protocol: JSON strings over net/rpc, still served by plugins for older DbMan versions
+	LegacyProtocolVersion = 1
+	// the key of the magic cookie checked by the plugins, so that they are not executed by mistake
+	MagicCookieKey = "dbman-db-provider"
+	// the prefix of the plugin executable names, followed by the name of the plugin
+	PluginPrefix = "dbman-db-"
+)
+
+// MagicCookieValue returns the value of the magic cookie for the plugin with the specified name
+// a plugin only completes the handshake if DbMan launches it for the name it was built for
+func MagicCookieValue(pluginName string) string {
+	return fmt.Sprintf("%s%s", PluginPrefix, pluginName)
+}
+
+// PluginInfo describes a database plugin
+type PluginInfo struct {
+	// the name of the plugin, as used in Db.Provider
+	Name string `json:"name"`
+	// the version of the plugin
+	Version string `json:"version"`
+	// the protocol version the plugin was built for
+	Protocol int `json:"protocol"`
+	// the optional features supported by the plugin (e.g. backup, lock), empty if not known
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// PluginDescriptor is optionally implemented by database plugins to report their version and capabilities
+type PluginDescriptor interface {
+	PluginInfo() *PluginInfo
+}
+
+// NewPluginInfoFromMap creates the plugin information from the result of a plugin call
+func NewPluginInfoFromMap(m map[string]interface{}) (*PluginInfo, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	info := &PluginInfo{}
+	err = json.Unmarshal(b, info)
+	return info, err
+}
+
+// returns the information of a plugin, plugins not implementing PluginDescriptor are reported with an unknown version
+func describePlugin(impl DatabasePlugin, name string) *PluginInfo {
+	info := &PluginInfo{Version: "unknown"}
+	if descriptor, ok := impl.(PluginDescriptor); ok {
+		if i := descriptor.PluginInfo(); i != nil {
+			info = i
+		}
+	}
+	if len(info.Name) == 0 {
+		info.Name = name
+	}
+	info.Protocol = ProtocolVersion
+	return info
+}
@@ -0,0 +1,50 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Progress the progress of the last upgrade run on the database, used to resume a failed upgrade
+type Progress struct {
+	// the application version the upgrade started from
+	From string `json:"from"`
+	// the application version the upgrade is to
+	To string `json:"to"`
+	// the application version of the release being applied
+	Release string `json:"release"`
+	// the stage of the release being applied: prepare, alter, deploy or version
+	Stage string `json:"stage"`
+	// the name of the command being run, empty for the version stage
+	Command string `json:"command,omitempty"`
+	// the index of the step in the upgrade path
+	Step int `json:"step"`
+	// the status of the upgrade: running, failed or completed
+	Status string `json:"status"`
+	// the error that caused the upgrade to fail
+	Error string `json:"error,omitempty"`
+	// the time the progress was recorded
+	Time time.Time `json:"time"`
+}
+
+// NewProgress creates a new progress from a serialised json string
+func NewProgress(jsonString string) (*Progress, error) {
+	p := &Progress{}
+	err := json.Unmarshal([]byte(jsonString), p)
+	return p, err
+}
+
+func (p *Progress) ToString() string {
+	b, e := json.Marshal(p)
+	if e != nil {
+		return ""
+	}
+	return string(b)
+}
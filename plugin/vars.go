@@ -0,0 +1,166 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the format of the values of date variables
+const dateFormat = "2006-01-02"
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// QueryArg the value of an input variable bound to a query parameter
+type QueryArg struct {
+	// the name of the variable
+	Name string `json:"name" yaml:"name"`
+	// the type of the variable
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// the value passed-in from the command line or query string
+	Value string `json:"value" yaml:"value"`
+}
+
+// Bind returns the value converted to the type of the variable, to be passed to the database driver
+func (a QueryArg) Bind() (interface{}, error) {
+	switch strings.ToLower(a.Type) {
+	case "int":
+		return strconv.ParseInt(a.Value, 10, 64)
+	case "bool":
+		return strconv.ParseBool(a.Value)
+	case "date":
+		return time.Parse(dateFormat, a.Value)
+	default:
+		return a.Value, nil
+	}
+}
+
+// BoundArgs returns the values to bind to the query parameters in order, i.e. the first value for $1 and so on
+func (q *Query) BoundArgs() ([]interface{}, error) {
+	args := make([]interface{}, 0, len(q.Args))
+	for _, arg := range q.Args {
+		value, err := arg.Bind()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("!!! I cannot bind the value of '%s' as %s: %v\n", arg.Name, arg.Type, err))
+		}
+		args = append(args, value)
+	}
+	return args, nil
+}
+
+// Check returns an error if the value is not valid for the type of the variable
+func (v *Var) Check(value string) error {
+	var valid bool
+	switch strings.ToLower(v.Type) {
+	case "", "text":
+		return nil
+	case "int":
+		_, err := strconv.ParseInt(value, 10, 64)
+		valid = err == nil
+	case "bool":
+		_, err := strconv.ParseBool(value)
+		valid = err == nil
+	case "date":
+		_, err := time.Parse(dateFormat, value)
+		valid = err == nil
+	case "uuid":
+		valid = uuidRegex.MatchString(value)
+	case "enum":
+		for _, allowed := range v.Values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return errors.New(fmt.Sprintf("the value '%s' of '%s' is not one of %s", value, v.Name, strings.Join(v.Values, ", ")))
+	case "regex":
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", v.Pattern))
+		if err != nil {
+			return errors.New(fmt.Sprintf("the pattern of '%s' is not a valid regular expression: %v", v.Name, err))
+		}
+		if !re.MatchString(value) {
+			return errors.New(fmt.Sprintf("the value '%s' of '%s' does not match the pattern %s", value, v.Name, v.Pattern))
+		}
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("'%s' is not a valid type for '%s', valid types are int, bool, date, uuid, text, enum and regex", v.Type, v.Name))
+	}
+	if !valid {
+		return errors.New(fmt.Sprintf("the value '%s' of '%s' is not a valid %s", value, v.Name, strings.ToLower(v.Type)))
+	}
+	return nil
+}
+
+// RewriteParams replaces the query parameters ($1, $2, ...) outside quoted strings, identifiers and comments (-- and /* */),
+// for databases using a different parameter syntax
+// param: returns the parameter to use in place of the parameter with the passed-in number
+// backslashEscapes: true if a backslash escapes the next character in quoted strings (e.g. MySQL 'it\'s')
+func RewriteParams(content string, param func(n int) string, backslashEscapes bool) string {
+	var (
+		b     strings.Builder
+		quote rune
+		// the end of the comment being read, if any: a new line or */
+		comment string
+	)
+	runes := []rune(content)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case comment == "\n":
+			// the end of the line comment
+			if r == '\n' {
+				comment = ""
+			}
+		case comment == "*/":
+			// the end of the block comment
+			if r == '*' && i+1 < len(runes) && runes[i+1] == '/' {
+				b.WriteString("*/")
+				i++
+				comment = ""
+				continue
+			}
+		case quote != 0:
+			// the escaped character is written as is
+			if backslashEscapes && r == '\\' && quote != '`' && i+1 < len(runes) {
+				b.WriteRune(r)
+				i++
+				r = runes[i]
+				break
+			}
+			// the end of the quoted string or identifier, doubled quotes are escaped quotes
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			comment = "\n"
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// the comment cannot end in its opening /*
+			b.WriteString("/*")
+			i++
+			comment = "*/"
+			continue
+		case r == '$' && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '9':
+			j := i + 1
+			for j < len(runes) && runes[j] >= '0' && runes[j] <= '9' {
+				j++
+			}
+			n, _ := strconv.Atoi(string(runes[i+1 : j]))
+			b.WriteString(param(n))
+			i = j - 1
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+/*
+   DbMan - © 2018-Present - SouthWinds Tech Ltd - www.southwinds.io
+   Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+   Contributors to this project, hereby assign copyright in this code to the project,
+   to be licensed under the same terms as the rest of the code.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRewriteParams(t *testing.T) {
+	cases := []struct {
+		name             string
+		query            string
+		backslashEscapes bool
+		want             string
+	}{
+		{"params", `SELECT name FROM t WHERE id = $1 AND n > $12`, false, `SELECT name FROM t WHERE id = ?1 AND n > ?12`},
+		{"quoted", `SELECT '$1', "a$2", ` + "`$3`" + ` FROM t WHERE id = $1`, false, `SELECT '$1', "a$2", ` + "`$3`" + ` FROM t WHERE id = ?1`},
+		{"doubled quotes", `SELECT 'it''s $1' FROM t WHERE id = $1`, false, `SELECT 'it''s $1' FROM t WHERE id = ?1`},
+		{"line comment", "SELECT 1 -- where id = $1\nFROM t WHERE id = $2", false, "SELECT 1 -- where id = $1\nFROM t WHERE id = ?2"},
+		{"line comment at end", "SELECT 1 FROM t WHERE id = $1 -- $2", false, "SELECT 1 FROM t WHERE id = ?1 -- $2"},
+		{"block comment", "SELECT /* $1 \n $2 */ name FROM t WHERE id = $3", false, "SELECT /* $1 \n $2 */ name FROM t WHERE id = ?3"},
+		{"empty block comment", "SELECT /**/ $1", false, "SELECT /**/ ?1"},
+		{"comment in string", `SELECT '-- $1', '/* $2' FROM t WHERE id = $3`, false, `SELECT '-- $1', '/* $2' FROM t WHERE id = ?3`},
+		{"quote in comment", "SELECT 1 -- it's $1\nFROM t WHERE id = $2", false, "SELECT 1 -- it's $1\nFROM t WHERE id = ?2"},
+		{"backslash escape", `SELECT 'it\'s $1' FROM t WHERE id = $2`, true, `SELECT 'it\'s $1' FROM t WHERE id = ?2`},
+		{"escaped backslash", `SELECT 'c:\\', $1`, true, `SELECT 'c:\\', ?1`},
+		{"backslash without escapes", `SELECT 'c:\', $1`, false, `SELECT 'c:\', ?1`},
+		{"negative number", `SELECT 1 - $1`, false, `SELECT 1 - ?1`},
+		{"not a param", `SELECT $a, $`, false, `SELECT $a, $`},
+	}
+	for _, c := range cases {
+		got := RewriteParams(c.query, func(n int) string { return fmt.Sprintf("?%d", n) }, c.backslashEscapes)
+		if got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}